@@ -4,11 +4,14 @@ package cfn
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil" //nolint: staticcheck
 	"log"
 	"net/http"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
 )
 
 // StatusType represents a CloudFormation response status
@@ -51,12 +54,16 @@ type httpClient interface {
 }
 
 func (r *Response) sendWith(client httpClient) error {
+	return r.sendWithContext(context.Background(), client)
+}
+
+func (r *Response) sendWithContext(ctx context.Context, client httpClient) error {
 	body, err := json.Marshal(r)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPut, r.url, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, r.url, bytes.NewBuffer(body))
 	if err != nil {
 		return err
 	}
@@ -87,3 +94,22 @@ func (r *Response) sendWith(client httpClient) error {
 func (r *Response) Send() error {
 	return r.sendWith(http.DefaultClient)
 }
+
+// SendResponse builds the Response for the given Event and sends it to the event's pre-signed ResponseURL
+// using the default HTTP client. The request is bound to ctx, so it is abandoned if the invoke times out.
+// The event's PhysicalResourceID is copied to the response, falling back to the Log Stream name when it is empty.
+// Use LambdaWrap instead to have the response sent automatically from the values returned by a CustomResourceFunction.
+func SendResponse(ctx context.Context, event *Event, status StatusType, data map[string]interface{}) error {
+	return sendResponseWith(ctx, http.DefaultClient, event, status, data)
+}
+
+func sendResponseWith(ctx context.Context, client httpClient, event *Event, status StatusType, data map[string]interface{}) error {
+	r := NewResponse(event)
+	r.Status = status
+	r.Data = data
+	r.PhysicalResourceID = event.PhysicalResourceID
+	if r.PhysicalResourceID == "" {
+		r.PhysicalResourceID = lambdacontext.LogStreamName
+	}
+	return r.sendWithContext(ctx, client)
+}
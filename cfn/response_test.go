@@ -4,12 +4,16 @@ package cfn
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil" //nolint: staticcheck
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDataCopiedFromRequest(t *testing.T) {
@@ -85,3 +89,50 @@ func TestRequestForbidden(t *testing.T) {
 		assert.Equal(t, fmt.Errorf("invalid status code. got: %d", sc), s)
 	}
 }
+
+func TestSendResponse(t *testing.T) {
+	var method, path string
+	var header http.Header
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, header = r.Method, r.URL.RequestURI(), r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	e := &Event{
+		RequestType:        RequestUpdate,
+		RequestID:          "unique id for this update request",
+		ResponseURL:        ts.URL + "/pre-signed?X-Amz-Signature=abc",
+		LogicalResourceID:  "MyTestResource",
+		PhysicalResourceID: "my-resource",
+		StackID:            "arn:aws:cloudformation:us-west-2:EXAMPLE/stack-name/guid",
+	}
+	require.NoError(t, SendResponse(context.Background(), e, StatusSuccess, map[string]interface{}{"Echo": "hi"}))
+
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/pre-signed?X-Amz-Signature=abc", path)
+	assert.Empty(t, header.Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"Status": "SUCCESS",
+		"RequestId": "unique id for this update request",
+		"LogicalResourceId": "MyTestResource",
+		"StackId": "arn:aws:cloudformation:us-west-2:EXAMPLE/stack-name/guid",
+		"PhysicalResourceId": "my-resource",
+		"Data": {"Echo": "hi"}
+	}`, string(body))
+}
+
+func TestSendResponseHonorsContext(t *testing.T) {
+	e := &Event{ResponseURL: "http://pre-signed-S3-url-for-response"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client := &mockClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return nil, req.Context().Err()
+		},
+	}
+	assert.Equal(t, context.Canceled, sendResponseWith(ctx, client, e, StatusFailed, nil))
+}
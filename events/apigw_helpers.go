@@ -0,0 +1,192 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil" //nolint: staticcheck
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+)
+
+// StageVariablesInto decodes the request's stage variables into the struct pointed to by out.
+// Field names are matched using the `stage` struct tag, defaulting to the field name.
+// Values are coerced into string, bool, integer, and floating point fields.
+// Fields without a matching stage variable are left unchanged.
+//
+// Example:
+//
+//	var config struct {
+//		TableName string `stage:"tableName"`
+//		Verbose   bool   `stage:"verbose"`
+//	}
+//	err := request.StageVariablesInto(&config)
+func (r APIGatewayProxyRequest) StageVariablesInto(out interface{}) error {
+	return decodeStringMap(r.StageVariables, out, "stage")
+}
+
+// StageVariablesInto decodes the request's stage variables into the struct pointed to by out.
+// See APIGatewayProxyRequest.StageVariablesInto for the decoding rules.
+func (r APIGatewayV2HTTPRequest) StageVariablesInto(out interface{}) error {
+	return decodeStringMap(r.StageVariables, out, "stage")
+}
+
+// PathParam returns the URL-decoded value of the path parameter name, and whether the request has it.
+// An encoded slash, "%2F", is decoded to "/", so a value may contain slashes even if the parameter is not greedy.
+// A value that is not valid percent-encoding is returned as is.
+func (r APIGatewayProxyRequest) PathParam(name string) (string, bool) {
+	return pathParam(r.PathParameters, name)
+}
+
+// PathParamsInto decodes the request's URL-decoded path parameters into the struct pointed to by out.
+// Field names are matched using the `path` struct tag, defaulting to the field name.
+// Values are coerced into string, bool, integer, and floating point fields.
+// Fields without a matching path parameter are left unchanged.
+//
+// Example:
+//
+//	var params struct {
+//		UserID int64  `path:"userId"`
+//		Path   string `path:"proxy"`
+//	}
+//	err := request.PathParamsInto(&params)
+func (r APIGatewayProxyRequest) PathParamsInto(out interface{}) error {
+	return pathParamsInto(r.PathParameters, out)
+}
+
+// PathParam returns the URL-decoded value of the path parameter name, and whether the request has it.
+// See APIGatewayProxyRequest.PathParam for the decoding rules.
+func (r APIGatewayV2HTTPRequest) PathParam(name string) (string, bool) {
+	return pathParam(r.PathParameters, name)
+}
+
+// PathParamsInto decodes the request's URL-decoded path parameters into the struct pointed to by out.
+// See APIGatewayProxyRequest.PathParamsInto for the decoding rules.
+func (r APIGatewayV2HTTPRequest) PathParamsInto(out interface{}) error {
+	return pathParamsInto(r.PathParameters, out)
+}
+
+func pathParam(params map[string]string, name string) (string, bool) {
+	value, ok := params[name]
+	if !ok {
+		return "", false
+	}
+	if decoded, err := url.PathUnescape(value); err == nil {
+		return decoded, true
+	}
+	return value, true
+}
+
+func pathParamsInto(params map[string]string, out interface{}) error {
+	decoded := make(map[string]string, len(params))
+	for name, value := range params {
+		unescaped, err := url.PathUnescape(value)
+		if err != nil {
+			return fmt.Errorf("cannot decode path parameter %q: %v", name, err)
+		}
+		decoded[name] = unescaped
+	}
+	return decodeStringMap(decoded, out, "path")
+}
+
+// QueryValues returns the request's query string parameters as url.Values.
+// Values from MultiValueQueryStringParameters are preferred, so repeated parameters keep all of their values.
+// Parameters only present in QueryStringParameters are included with their single value.
+func (r APIGatewayProxyRequest) QueryValues() url.Values {
+	values := make(url.Values, len(r.MultiValueQueryStringParameters))
+	for key, multi := range r.MultiValueQueryStringParameters {
+		values[key] = append([]string(nil), multi...)
+	}
+	for key, single := range r.QueryStringParameters {
+		if _, ok := values[key]; !ok {
+			values[key] = []string{single}
+		}
+	}
+	return values
+}
+
+// ToHTTPResponse converts an API Gateway proxy response to an *http.Response, decoding the body when IsBase64Encoded is set.
+// Values in MultiValueHeaders replace the value of a header with the same name in Headers.
+// If the body is not valid base64, reading the returned response's body fails.
+func ToHTTPResponse(resp APIGatewayProxyResponse) *http.Response {
+	header := make(http.Header, len(resp.Headers)+len(resp.MultiValueHeaders))
+	for key, value := range resp.Headers {
+		header.Set(key, value)
+	}
+	for key, values := range resp.MultiValueHeaders {
+		header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+	}
+	body := []byte(resp.Body)
+	var bodyReader io.Reader = bytes.NewReader(body)
+	if resp.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(resp.Body)
+		if err != nil {
+			body, bodyReader = nil, &errorReader{fmt.Errorf("failed to decode the base64 encoded body: %w", err)}
+		} else {
+			body, bodyReader = decoded, bytes.NewReader(decoded)
+		}
+	}
+	contentLength := int64(len(body))
+	if body == nil {
+		contentLength = -1
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+		StatusCode:    resp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bodyReader),
+		ContentLength: contentLength,
+	}
+}
+
+// FromHTTPResponse converts an *http.Response to an API Gateway proxy response, reading and closing its body.
+// Headers with a single value are set in Headers, and headers with several values in MultiValueHeaders.
+// Bodies that are not valid UTF-8 are base64 encoded.
+func FromHTTPResponse(resp *http.Response) (APIGatewayProxyResponse, error) {
+	proxyResponse := APIGatewayProxyResponse{StatusCode: resp.StatusCode}
+	for key, values := range resp.Header {
+		switch len(values) {
+		case 0:
+		case 1:
+			if proxyResponse.Headers == nil {
+				proxyResponse.Headers = map[string]string{}
+			}
+			proxyResponse.Headers[key] = values[0]
+		default:
+			if proxyResponse.MultiValueHeaders == nil {
+				proxyResponse.MultiValueHeaders = map[string][]string{}
+			}
+			proxyResponse.MultiValueHeaders[key] = append([]string(nil), values...)
+		}
+	}
+	if resp.Body == nil {
+		return proxyResponse, nil
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return APIGatewayProxyResponse{}, fmt.Errorf("failed to read the response body: %w", err)
+	}
+	if utf8.Valid(body) {
+		proxyResponse.Body = string(body)
+	} else {
+		proxyResponse.Body = base64.StdEncoding.EncodeToString(body)
+		proxyResponse.IsBase64Encoded = true
+	}
+	return proxyResponse, nil
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,252 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"encoding/base64"
+	"io/ioutil" //nolint: staticcheck
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stageConfig struct {
+	TableName string `stage:"tableName"`
+	MaxItems  int    `stage:"maxItems"`
+	Verbose   bool   `stage:"verbose"`
+	Ratio     float64
+	Ignored   string `stage:"-"`
+	Missing   string `stage:"missing"`
+}
+
+func TestStageVariablesInto(t *testing.T) {
+	request := APIGatewayProxyRequest{
+		StageVariables: map[string]string{
+			"tableName": "orders",
+			"maxItems":  "25",
+			"verbose":   "true",
+			"Ratio":     "0.5",
+			"-":         "nope",
+			"Ignored":   "nope",
+		},
+	}
+	config := stageConfig{Missing: "default"}
+	require.NoError(t, request.StageVariablesInto(&config))
+	assert.Equal(t, stageConfig{
+		TableName: "orders",
+		MaxItems:  25,
+		Verbose:   true,
+		Ratio:     0.5,
+		Missing:   "default",
+	}, config)
+
+	v2Request := APIGatewayV2HTTPRequest{StageVariables: map[string]string{"tableName": "users"}}
+	var v2Config stageConfig
+	require.NoError(t, v2Request.StageVariablesInto(&v2Config))
+	assert.Equal(t, "users", v2Config.TableName)
+}
+
+func TestStageVariablesIntoErrors(t *testing.T) {
+	request := APIGatewayProxyRequest{StageVariables: map[string]string{"maxItems": "lots"}}
+	var config stageConfig
+	assert.EqualError(t, request.StageVariablesInto(&config), `cannot decode "maxItems" into field MaxItems: strconv.ParseInt: parsing "lots": invalid syntax`)
+
+	request = APIGatewayProxyRequest{StageVariables: map[string]string{"verbose": "sometimes"}}
+	assert.EqualError(t, request.StageVariablesInto(&config), `cannot decode "verbose" into field Verbose: strconv.ParseBool: parsing "sometimes": invalid syntax`)
+
+	assert.EqualError(t, request.StageVariablesInto(config), "out must be a non-nil pointer to a struct")
+
+	var unsupported struct {
+		Values []string `stage:"verbose"`
+	}
+	assert.EqualError(t, request.StageVariablesInto(&unsupported), `cannot decode "verbose" into field Values: unsupported field type []string`)
+}
+
+func TestStageVariablesIntoNilMap(t *testing.T) {
+	var config stageConfig
+	require.NoError(t, APIGatewayProxyRequest{}.StageVariablesInto(&config))
+	assert.Equal(t, stageConfig{}, config)
+}
+
+func TestPathParam(t *testing.T) {
+	request := APIGatewayProxyRequest{
+		PathParameters: map[string]string{
+			"name":  "hello%20world",
+			"key":   "a%2Fb%2Fc",
+			"proxy": "docs/2023/caf%C3%A9.txt",
+			"bad":   "100%",
+			"plus":  "a+b",
+		},
+	}
+	testCases := []struct {
+		name     string
+		expected string
+		found    bool
+	}{
+		{"name", "hello world", true},
+		{"key", "a/b/c", true},
+		{"proxy", "docs/2023/café.txt", true},
+		{"bad", "100%", true},
+		{"plus", "a+b", true},
+		{"missing", "", false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			value, found := request.PathParam(testCase.name)
+			assert.Equal(t, testCase.found, found)
+			assert.Equal(t, testCase.expected, value)
+		})
+	}
+
+	v2Request := APIGatewayV2HTTPRequest{PathParameters: map[string]string{"key": "a%2Fb"}}
+	value, found := v2Request.PathParam("key")
+	assert.True(t, found)
+	assert.Equal(t, "a/b", value)
+	_, found = APIGatewayV2HTTPRequest{}.PathParam("key")
+	assert.False(t, found)
+}
+
+func TestPathParamsInto(t *testing.T) {
+	var params struct {
+		UserID  int64  `path:"userId"`
+		Key     string `path:"key"`
+		Missing string `path:"missing"`
+	}
+	params.Missing = "default"
+	request := APIGatewayProxyRequest{PathParameters: map[string]string{"userId": "%34%32", "key": "reports%2F2023"}}
+	require.NoError(t, request.PathParamsInto(&params))
+	assert.Equal(t, int64(42), params.UserID)
+	assert.Equal(t, "reports/2023", params.Key)
+	assert.Equal(t, "default", params.Missing)
+
+	v2Request := APIGatewayV2HTTPRequest{PathParameters: map[string]string{"key": "a%20b"}}
+	require.NoError(t, v2Request.PathParamsInto(&params))
+	assert.Equal(t, "a b", params.Key)
+}
+
+func TestPathParamsIntoErrors(t *testing.T) {
+	var params struct {
+		UserID int64 `path:"userId"`
+	}
+	request := APIGatewayProxyRequest{PathParameters: map[string]string{"userId": "12%zz"}}
+	assert.EqualError(t, request.PathParamsInto(&params), `cannot decode path parameter "userId": invalid URL escape "%zz"`)
+
+	request = APIGatewayProxyRequest{PathParameters: map[string]string{"userId": "me"}}
+	assert.EqualError(t, request.PathParamsInto(&params), `cannot decode "userId" into field UserID: strconv.ParseInt: parsing "me": invalid syntax`)
+	assert.EqualError(t, request.PathParamsInto(params), "out must be a non-nil pointer to a struct")
+}
+
+func TestQueryValues(t *testing.T) {
+	request := APIGatewayProxyRequest{
+		QueryStringParameters: map[string]string{
+			"tag":    "b",
+			"limit":  "10",
+			"single": "only",
+		},
+		MultiValueQueryStringParameters: map[string][]string{
+			"tag":   {"a", "b"},
+			"limit": {"10"},
+		},
+	}
+	values := request.QueryValues()
+	assert.Equal(t, url.Values{
+		"tag":    {"a", "b"},
+		"limit":  {"10"},
+		"single": {"only"},
+	}, values)
+	assert.Equal(t, "a", values.Get("tag"))
+
+	values.Add("tag", "c")
+	assert.Equal(t, []string{"a", "b"}, request.MultiValueQueryStringParameters["tag"])
+}
+
+func TestQueryValuesSingleValueOnly(t *testing.T) {
+	request := APIGatewayProxyRequest{QueryStringParameters: map[string]string{"q": "gopher"}}
+	assert.Equal(t, url.Values{"q": {"gopher"}}, request.QueryValues())
+	assert.Equal(t, url.Values{}, APIGatewayProxyRequest{}.QueryValues())
+}
+
+func TestHTTPResponseRoundTrip(t *testing.T) {
+	testCases := []struct {
+		name     string
+		response APIGatewayProxyResponse
+		body     []byte
+	}{
+		{
+			name: "text body with single and multi-value headers",
+			response: APIGatewayProxyResponse{
+				StatusCode:        http.StatusCreated,
+				Headers:           map[string]string{"Content-Type": "application/json"},
+				MultiValueHeaders: map[string][]string{"Set-Cookie": {"a=1", "b=2"}},
+				Body:              `{"hello":"world"}`,
+			},
+			body: []byte(`{"hello":"world"}`),
+		},
+		{
+			name: "base64 encoded binary body",
+			response: APIGatewayProxyResponse{
+				StatusCode:      http.StatusOK,
+				Headers:         map[string]string{"Content-Type": "image/png"},
+				Body:            base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G', 0xff}),
+				IsBase64Encoded: true,
+			},
+			body: []byte{0x89, 'P', 'N', 'G', 0xff},
+		},
+		{
+			name:     "empty body without headers",
+			response: APIGatewayProxyResponse{StatusCode: http.StatusNoContent},
+			body:     []byte{},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			httpResponse := ToHTTPResponse(testCase.response)
+			assert.Equal(t, testCase.response.StatusCode, httpResponse.StatusCode)
+			assert.Equal(t, int64(len(testCase.body)), httpResponse.ContentLength)
+			body, err := ioutil.ReadAll(httpResponse.Body)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.body, body)
+
+			httpResponse = ToHTTPResponse(testCase.response)
+			roundTripped, err := FromHTTPResponse(httpResponse)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.response, roundTripped)
+		})
+	}
+}
+
+func TestToHTTPResponseHeaders(t *testing.T) {
+	httpResponse := ToHTTPResponse(APIGatewayProxyResponse{
+		StatusCode:        http.StatusFound,
+		Headers:           map[string]string{"location": "/login", "x-single": "kept", "Vary": "Origin"},
+		MultiValueHeaders: map[string][]string{"vary": {"Accept", "Accept-Encoding"}},
+	})
+	assert.Equal(t, "302 Found", httpResponse.Status)
+	assert.Equal(t, "/login", httpResponse.Header.Get("Location"))
+	assert.Equal(t, "kept", httpResponse.Header.Get("X-Single"))
+	assert.Equal(t, []string{"Accept", "Accept-Encoding"}, httpResponse.Header["Vary"])
+}
+
+func TestToHTTPResponseInvalidBase64(t *testing.T) {
+	httpResponse := ToHTTPResponse(APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: "not base64!", IsBase64Encoded: true})
+	assert.Equal(t, int64(-1), httpResponse.ContentLength)
+	_, err := ioutil.ReadAll(httpResponse.Body)
+	assert.EqualError(t, err, "failed to decode the base64 encoded body: illegal base64 data at input byte 3")
+}
+
+func TestFromHTTPResponseBinaryBody(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("Content-Type", "application/octet-stream")
+	recorder.WriteHeader(http.StatusOK)
+	_, _ = recorder.Write([]byte{0, 1, 0xfe, 0xff})
+
+	response, err := FromHTTPResponse(recorder.Result())
+	require.NoError(t, err)
+	assert.True(t, response.IsBase64Encoded)
+	assert.Equal(t, "AAH+/w==", response.Body)
+	assert.Equal(t, map[string]string{"Content-Type": "application/octet-stream"}, response.Headers)
+}
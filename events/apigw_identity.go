@@ -0,0 +1,48 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"strings"
+)
+
+// IsIAMAuthorized reports whether the request was signed with AWS Signature Version 4, for a method using AWS_IAM authorization.
+// API Gateway only sets the access key and ARN of the caller for such requests.
+func (i APIGatewayRequestIdentity) IsIAMAuthorized() bool {
+	return i.AccessKey != "" && i.UserArn != ""
+}
+
+// CallerARN returns the ARN of the IAM user or role that signed the request, such as "arn:aws:sts::123456789012:assumed-role/role/session".
+// The second return value is false when the request was not IAM authorized.
+func (i APIGatewayRequestIdentity) CallerARN() (string, bool) {
+	if !i.IsIAMAuthorized() {
+		return "", false
+	}
+	return i.UserArn, true
+}
+
+// AWSAccountID returns the id of the AWS account of the caller that signed the request, read from the caller's ARN when API Gateway left it out.
+// The second return value is false when the request was not IAM authorized.
+func (i APIGatewayRequestIdentity) AWSAccountID() (string, bool) {
+	if !i.IsIAMAuthorized() {
+		return "", false
+	}
+	if i.AccountID != "" {
+		return i.AccountID, true
+	}
+	// arn:partition:service:region:account-id:resource
+	fields := strings.SplitN(i.UserArn, ":", 6)
+	if len(fields) < 6 || fields[4] == "" {
+		return "", false
+	}
+	return fields[4], true
+}
+
+// AccessKeyID returns the id of the access key the request was signed with.
+// The second return value is false when the request was not IAM authorized.
+func (i APIGatewayRequestIdentity) AccessKeyID() (string, bool) {
+	if !i.IsIAMAuthorized() {
+		return "", false
+	}
+	return i.AccessKey, true
+}
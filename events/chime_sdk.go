@@ -0,0 +1,41 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+// ChimeSDKEventType is the type of a meeting or attendee lifecycle event sent by the Amazon Chime SDK.
+type ChimeSDKEventType string
+
+const (
+	ChimeSDKMeetingStarted              ChimeSDKEventType = "chime:MeetingStarted"
+	ChimeSDKMeetingEnded                ChimeSDKEventType = "chime:MeetingEnded"
+	ChimeSDKAttendeeAdded               ChimeSDKEventType = "chime:AttendeeAdded"
+	ChimeSDKAttendeeDeleted             ChimeSDKEventType = "chime:AttendeeDeleted"
+	ChimeSDKAttendeeAuthorized          ChimeSDKEventType = "chime:AttendeeAuthorized"
+	ChimeSDKAttendeeJoined              ChimeSDKEventType = "chime:AttendeeJoined"
+	ChimeSDKAttendeeLeft                ChimeSDKEventType = "chime:AttendeeLeft"
+	ChimeSDKAttendeeDropped             ChimeSDKEventType = "chime:AttendeeDropped"
+	ChimeSDKAttendeeVideoStarted        ChimeSDKEventType = "chime:AttendeeVideoStarted"
+	ChimeSDKAttendeeVideoStopped        ChimeSDKEventType = "chime:AttendeeVideoStopped"
+	ChimeSDKAttendeeContentJoined       ChimeSDKEventType = "chime:AttendeeContentJoined"
+	ChimeSDKAttendeeContentLeft         ChimeSDKEventType = "chime:AttendeeContentLeft"
+	ChimeSDKAttendeeContentDropped      ChimeSDKEventType = "chime:AttendeeContentDropped"
+	ChimeSDKAttendeeContentVideoStarted ChimeSDKEventType = "chime:AttendeeContentVideoStarted"
+	ChimeSDKAttendeeContentVideoStopped ChimeSDKEventType = "chime:AttendeeContentVideoStopped"
+)
+
+// ChimeSDKMeetingStateChangeDetailType is the detail-type of the EventBridge events carrying a ChimeSDKMeetingEventDetail.
+const ChimeSDKMeetingStateChangeDetailType = "Chime Meeting State Change"
+
+// ChimeSDKMeetingEventDetail is the detail of an Amazon Chime SDK meeting or attendee lifecycle event delivered by EventBridge.
+// Use CloudWatchEvent.DetailInto to decode it from an EventBridge event with the detail-type "Chime Meeting State Change".
+type ChimeSDKMeetingEventDetail struct {
+	Version           string                `json:"version"`
+	EventType         ChimeSDKEventType     `json:"eventType"`
+	Timestamp         MilliSecondsEpochTime `json:"timestamp"`
+	MeetingID         string                `json:"meetingId"`
+	ExternalMeetingID string                `json:"externalMeetingId,omitempty"`
+	AttendeeID        string                `json:"attendeeId,omitempty"`
+	ExternalUserID    string                `json:"externalUserId,omitempty"`
+	NetworkType       string                `json:"networkType,omitempty"`
+	MediaRegion       string                `json:"mediaRegion"`
+}
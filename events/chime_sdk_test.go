@@ -0,0 +1,48 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChimeSDKMeetingEventMarshaling(t *testing.T) {
+	// 1. read JSON from file
+	inputJSON := test.ReadJSONFromFile(t, "./testdata/chime-sdk-meeting-event.json")
+
+	// 2. de-serialize into Go object
+	var inputEvent CloudWatchEvent
+	require.NoError(t, json.Unmarshal(inputJSON, &inputEvent))
+	assert.Equal(t, "aws.chime", inputEvent.Source)
+	assert.Equal(t, ChimeSDKMeetingStateChangeDetailType, inputEvent.DetailType)
+
+	var detail ChimeSDKMeetingEventDetail
+	require.NoError(t, inputEvent.DetailInto(&detail))
+	assert.Equal(t, ChimeSDKAttendeeJoined, detail.EventType)
+	assert.Equal(t, time.Date(2023, 3, 1, 18, 43, 48, 229000000, time.UTC), detail.Timestamp.UTC())
+	assert.Equal(t, "87654321-4321-4321-1234-111122223333", detail.MeetingID)
+	assert.Equal(t, "my-board-meeting", detail.ExternalMeetingID)
+	assert.Equal(t, "12345678-1234-1234-1234-111122223333", detail.AttendeeID)
+	assert.Equal(t, "user-123", detail.ExternalUserID)
+	assert.Equal(t, "Voip", detail.NetworkType)
+	assert.Equal(t, "us-east-1", detail.MediaRegion)
+
+	// 3. serialize to JSON
+	detailJSON, err := json.Marshal(detail)
+	require.NoError(t, err)
+	inputEvent.Detail = detailJSON
+	outputJSON, err := json.Marshal(inputEvent)
+	require.NoError(t, err)
+
+	// 4. check result
+	assert.JSONEq(t, string(inputJSON), string(outputJSON))
+}
+
+func TestChimeSDKMeetingEventMarshalingMalformedJson(t *testing.T) {
+	test.TestMalformedJson(t, ChimeSDKMeetingEventDetail{})
+}
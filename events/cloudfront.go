@@ -0,0 +1,187 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"strings"
+)
+
+// CloudFrontEventType is the CloudFront trigger that invoked a Lambda@Edge function.
+type CloudFrontEventType string
+
+const (
+	CloudFrontViewerRequest  CloudFrontEventType = "viewer-request"
+	CloudFrontOriginRequest  CloudFrontEventType = "origin-request"
+	CloudFrontOriginResponse CloudFrontEventType = "origin-response"
+	CloudFrontViewerResponse CloudFrontEventType = "viewer-response"
+)
+
+// CloudFrontEvent is the event sent by CloudFront to a Lambda@Edge function.
+type CloudFrontEvent struct {
+	Records []CloudFrontEventRecord `json:"Records"`
+}
+
+// CloudFrontEventRecord holds the CloudFront request, and for origin-response and viewer-response events, the response.
+type CloudFrontEventRecord struct {
+	CF CloudFrontEventRecordCF `json:"cf"`
+}
+
+type CloudFrontEventRecordCF struct {
+	Config   CloudFrontConfig    `json:"config"`
+	Request  *CloudFrontRequest  `json:"request,omitempty"`
+	Response *CloudFrontResponse `json:"response,omitempty"`
+}
+
+// CloudFrontConfig contains information about the distribution and the trigger that invoked the function.
+type CloudFrontConfig struct {
+	DistributionDomainName string              `json:"distributionDomainName"`
+	DistributionID         string              `json:"distributionId"`
+	EventType              CloudFrontEventType `json:"eventType"`
+	RequestID              string              `json:"requestId"`
+}
+
+// CloudFrontHeaders is the CloudFront representation of HTTP headers.
+// Map keys are lowercase header names, and each value holds the header's original casing in Key.
+type CloudFrontHeaders map[string][]CloudFrontHeader
+
+type CloudFrontHeader struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value"`
+}
+
+// Get returns the first value of the header named key, compared case-insensitively.
+func (h CloudFrontHeaders) Get(key string) (string, bool) {
+	values := h[strings.ToLower(key)]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0].Value, true
+}
+
+// Add appends a value to the header named key, keeping key's casing for the header sent by CloudFront.
+func (h CloudFrontHeaders) Add(key, value string) {
+	name := strings.ToLower(key)
+	h[name] = append(h[name], CloudFrontHeader{Key: key, Value: value})
+}
+
+// Del removes all values of the header named key, compared case-insensitively.
+func (h CloudFrontHeaders) Del(key string) {
+	delete(h, strings.ToLower(key))
+}
+
+type CloudFrontRequest struct {
+	ClientIP    string                 `json:"clientIp"`
+	Headers     CloudFrontHeaders      `json:"headers"`
+	Method      string                 `json:"method"`
+	QueryString string                 `json:"querystring"`
+	URI         string                 `json:"uri"`
+	Body        *CloudFrontRequestBody `json:"body,omitempty"`
+	Origin      *CloudFrontOrigin      `json:"origin,omitempty"` // Origin is only sent for origin-request events
+}
+
+// AddHeader appends a value to the request header named key.
+func (r *CloudFrontRequest) AddHeader(key, value string) {
+	if r.Headers == nil {
+		r.Headers = CloudFrontHeaders{}
+	}
+	r.Headers.Add(key, value)
+}
+
+// RemoveHeader removes all values of the request header named key.
+func (r *CloudFrontRequest) RemoveHeader(key string) {
+	r.Headers.Del(key)
+}
+
+// CloudFrontRequestBody is sent when the trigger is configured to include the request body.
+type CloudFrontRequestBody struct {
+	InputTruncated bool   `json:"inputTruncated"`
+	Action         string `json:"action"`   // Action is one of "read-only" or "replace"
+	Encoding       string `json:"encoding"` // Encoding is one of "base64" or "text"
+	Data           string `json:"data"`
+}
+
+type CloudFrontOrigin struct {
+	Custom *CloudFrontCustomOrigin `json:"custom,omitempty"`
+	S3     *CloudFrontS3Origin     `json:"s3,omitempty"`
+}
+
+type CloudFrontCustomOrigin struct {
+	CustomHeaders    CloudFrontHeaders `json:"customHeaders"`
+	DomainName       string            `json:"domainName"`
+	KeepaliveTimeout int               `json:"keepaliveTimeout"`
+	Path             string            `json:"path"`
+	Port             int               `json:"port"`
+	Protocol         string            `json:"protocol"`
+	ReadTimeout      int               `json:"readTimeout"`
+	SSLProtocols     []string          `json:"sslProtocols"`
+}
+
+type CloudFrontS3Origin struct {
+	AuthMethod    string            `json:"authMethod"`
+	CustomHeaders CloudFrontHeaders `json:"customHeaders"`
+	DomainName    string            `json:"domainName"`
+	Path          string            `json:"path"`
+	Region        string            `json:"region"`
+}
+
+// CloudFrontResponse is received in origin-response and viewer-response events,
+// and may be returned from viewer-request and origin-request events to generate a response.
+type CloudFrontResponse struct {
+	Status            string            `json:"status"`
+	StatusDescription string            `json:"statusDescription,omitempty"`
+	Headers           CloudFrontHeaders `json:"headers,omitempty"`
+	Body              string            `json:"body,omitempty"`
+	BodyEncoding      string            `json:"bodyEncoding,omitempty"` // BodyEncoding is one of "base64" or "text"
+}
+
+// AddHeader appends a value to the response header named key.
+func (r *CloudFrontResponse) AddHeader(key, value string) {
+	if r.Headers == nil {
+		r.Headers = CloudFrontHeaders{}
+	}
+	r.Headers.Add(key, value)
+}
+
+// RemoveHeader removes all values of the response header named key.
+func (r *CloudFrontResponse) RemoveHeader(key string) {
+	r.Headers.Del(key)
+}
+
+// EventType returns the CloudFront trigger that invoked the function.
+func (r CloudFrontEventRecord) EventType() CloudFrontEventType {
+	return r.CF.Config.EventType
+}
+
+// ViewerRequest returns the request when the record is from a viewer-request event.
+func (r CloudFrontEventRecord) ViewerRequest() (*CloudFrontRequest, bool) {
+	return r.request(CloudFrontViewerRequest)
+}
+
+// OriginRequest returns the request when the record is from an origin-request event.
+func (r CloudFrontEventRecord) OriginRequest() (*CloudFrontRequest, bool) {
+	return r.request(CloudFrontOriginRequest)
+}
+
+// OriginResponse returns the response when the record is from an origin-response event.
+func (r CloudFrontEventRecord) OriginResponse() (*CloudFrontResponse, bool) {
+	return r.response(CloudFrontOriginResponse)
+}
+
+// ViewerResponse returns the response when the record is from a viewer-response event.
+func (r CloudFrontEventRecord) ViewerResponse() (*CloudFrontResponse, bool) {
+	return r.response(CloudFrontViewerResponse)
+}
+
+func (r CloudFrontEventRecord) request(eventType CloudFrontEventType) (*CloudFrontRequest, bool) {
+	if r.EventType() != eventType || r.CF.Request == nil {
+		return nil, false
+	}
+	return r.CF.Request, true
+}
+
+func (r CloudFrontEventRecord) response(eventType CloudFrontEventType) (*CloudFrontResponse, bool) {
+	if r.EventType() != eventType || r.CF.Response == nil {
+		return nil, false
+	}
+	return r.CF.Response, true
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+package events
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudFrontEventMarshaling(t *testing.T) {
+	for _, file := range []string{
+		"./testdata/cloudfront-viewer-request.json",
+		"./testdata/cloudfront-origin-request.json",
+		"./testdata/cloudfront-origin-response.json",
+		"./testdata/cloudfront-viewer-response.json",
+	} {
+		t.Run(file, func(t *testing.T) {
+			inputJSON := test.ReadJSONFromFile(t, file)
+
+			var inputEvent CloudFrontEvent
+			require.NoError(t, json.Unmarshal(inputJSON, &inputEvent))
+
+			outputJSON, err := json.Marshal(inputEvent)
+			require.NoError(t, err)
+
+			assert.JSONEq(t, string(inputJSON), string(outputJSON))
+		})
+	}
+}
+
+func TestCloudFrontEventMarshalingMalformedJson(t *testing.T) {
+	test.TestMalformedJson(t, CloudFrontEvent{})
+}
+
+func readCloudFrontRecord(t *testing.T, file string) CloudFrontEventRecord {
+	var event CloudFrontEvent
+	require.NoError(t, json.Unmarshal(test.ReadJSONFromFile(t, file), &event))
+	require.Len(t, event.Records, 1)
+	return event.Records[0]
+}
+
+func TestCloudFrontViewerRequest(t *testing.T) {
+	record := readCloudFrontRecord(t, "./testdata/cloudfront-viewer-request.json")
+	assert.Equal(t, CloudFrontViewerRequest, record.EventType())
+
+	request, ok := record.ViewerRequest()
+	require.True(t, ok)
+	assert.Equal(t, "/picture.jpg", request.URI)
+	assert.Equal(t, "size=large", request.QueryString)
+	assert.Nil(t, request.Origin)
+
+	_, ok = record.OriginRequest()
+	assert.False(t, ok)
+	_, ok = record.OriginResponse()
+	assert.False(t, ok)
+	_, ok = record.ViewerResponse()
+	assert.False(t, ok)
+}
+
+func TestCloudFrontOriginRequest(t *testing.T) {
+	record := readCloudFrontRecord(t, "./testdata/cloudfront-origin-request.json")
+	assert.Equal(t, CloudFrontOriginRequest, record.EventType())
+
+	request, ok := record.OriginRequest()
+	require.True(t, ok)
+	require.NotNil(t, request.Origin)
+	require.NotNil(t, request.Origin.Custom)
+	assert.Nil(t, request.Origin.S3)
+	assert.Equal(t, 443, request.Origin.Custom.Port)
+	secret, ok := request.Origin.Custom.CustomHeaders.Get("X-Origin-Secret")
+	assert.True(t, ok)
+	assert.Equal(t, "abc123", secret)
+	require.NotNil(t, request.Body)
+	assert.Equal(t, "base64", request.Body.Encoding)
+
+	_, ok = record.ViewerRequest()
+	assert.False(t, ok)
+}
+
+func TestCloudFrontOriginResponse(t *testing.T) {
+	record := readCloudFrontRecord(t, "./testdata/cloudfront-origin-response.json")
+	assert.Equal(t, CloudFrontOriginResponse, record.EventType())
+
+	response, ok := record.OriginResponse()
+	require.True(t, ok)
+	assert.Equal(t, "200", response.Status)
+	server, ok := response.Headers.Get("server")
+	assert.True(t, ok)
+	assert.Equal(t, "AmazonS3", server)
+	require.NotNil(t, record.CF.Request.Origin.S3)
+	assert.Equal(t, "us-east-1", record.CF.Request.Origin.S3.Region)
+
+	_, ok = record.ViewerResponse()
+	assert.False(t, ok)
+	_, ok = record.OriginRequest()
+	assert.False(t, ok)
+}
+
+func TestCloudFrontViewerResponse(t *testing.T) {
+	record := readCloudFrontRecord(t, "./testdata/cloudfront-viewer-response.json")
+	assert.Equal(t, CloudFrontViewerResponse, record.EventType())
+
+	response, ok := record.ViewerResponse()
+	require.True(t, ok)
+	assert.Len(t, response.Headers["set-cookie"], 2)
+
+	_, ok = record.OriginResponse()
+	assert.False(t, ok)
+	_, ok = record.ViewerRequest()
+	assert.False(t, ok)
+}
+
+func TestCloudFrontHeaderHelpers(t *testing.T) {
+	record := readCloudFrontRecord(t, "./testdata/cloudfront-viewer-response.json")
+	response, ok := record.ViewerResponse()
+	require.True(t, ok)
+
+	response.AddHeader("Strict-Transport-Security", "max-age=63072000")
+	response.AddHeader("Set-Cookie", "c=3")
+	response.RemoveHeader("X-Cache")
+
+	assert.Equal(t, []CloudFrontHeader{{Key: "Strict-Transport-Security", Value: "max-age=63072000"}}, response.Headers["strict-transport-security"])
+	assert.Len(t, response.Headers["set-cookie"], 3)
+	assert.NotContains(t, response.Headers, "x-cache")
+	_, ok = response.Headers.Get("x-cache")
+	assert.False(t, ok)
+
+	var request CloudFrontRequest
+	request.RemoveHeader("Host")
+	request.AddHeader("X-Experiment", "b")
+	value, ok := request.Headers.Get("x-experiment")
+	assert.True(t, ok)
+	assert.Equal(t, "b", value)
+
+	generated := CloudFrontResponse{Status: "302"}
+	generated.AddHeader("Location", "https://example.com/")
+	outputJSON, err := json.Marshal(generated)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"status":"302","headers":{"location":[{"key":"Location","value":"https://example.com/"}]}}`, string(outputJSON))
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CloudTrailAPICallDetailType is the detail type of the EventBridge events sent for API calls recorded by AWS CloudTrail.
+const CloudTrailAPICallDetailType = "AWS API Call via CloudTrail"
+
+// CloudTrailDetail is the detail of an EventBridge event for an API call recorded by AWS CloudTrail.
+// Use CloudWatchEvent.DetailInto to decode it from an EventBridge event with the detail-type "AWS API Call via CloudTrail".
+// The request parameters and response elements differ for each API, and are kept as raw JSON to be decoded by the caller.
+type CloudTrailDetail struct {
+	EventVersion        string                 `json:"eventVersion"`
+	UserIdentity        CloudTrailUserIdentity `json:"userIdentity"`
+	EventTime           time.Time              `json:"eventTime"`
+	EventSource         string                 `json:"eventSource"`
+	EventName           string                 `json:"eventName"`
+	AWSRegion           string                 `json:"awsRegion"`
+	SourceIPAddress     string                 `json:"sourceIPAddress"`
+	UserAgent           string                 `json:"userAgent"`
+	ErrorCode           string                 `json:"errorCode,omitempty"`
+	ErrorMessage        string                 `json:"errorMessage,omitempty"`
+	RequestParameters   json.RawMessage        `json:"requestParameters,omitempty"`
+	ResponseElements    json.RawMessage        `json:"responseElements,omitempty"`
+	AdditionalEventData json.RawMessage        `json:"additionalEventData,omitempty"`
+	RequestID           string                 `json:"requestID,omitempty"`
+	EventID             string                 `json:"eventID"`
+	ReadOnly            *bool                  `json:"readOnly,omitempty"`
+	Resources           []CloudTrailResource   `json:"resources,omitempty"`
+	EventType           string                 `json:"eventType"`
+	ManagementEvent     *bool                  `json:"managementEvent,omitempty"`
+	RecipientAccountID  string                 `json:"recipientAccountId,omitempty"`
+	SharedEventID       string                 `json:"sharedEventID,omitempty"`
+	VPCEndpointID       string                 `json:"vpcEndpointId,omitempty"`
+	EventCategory       string                 `json:"eventCategory,omitempty"`
+	TLSDetails          *CloudTrailTLSDetails  `json:"tlsDetails,omitempty"`
+}
+
+// CloudTrailUserIdentity identifies the principal that made an API call recorded by CloudTrail.
+type CloudTrailUserIdentity struct {
+	Type           string                    `json:"type"` // Type is one of Root, IAMUser, AssumedRole, FederatedUser, AWSAccount, AWSService, and others
+	PrincipalID    string                    `json:"principalId,omitempty"`
+	ARN            string                    `json:"arn,omitempty"`
+	AccountID      string                    `json:"accountId,omitempty"`
+	AccessKeyID    string                    `json:"accessKeyId,omitempty"`
+	UserName       string                    `json:"userName,omitempty"`
+	InvokedBy      string                    `json:"invokedBy,omitempty"`
+	SessionContext *CloudTrailSessionContext `json:"sessionContext,omitempty"`
+}
+
+// CloudTrailSessionContext describes the temporary security credentials used for an API call.
+type CloudTrailSessionContext struct {
+	SessionIssuer       *CloudTrailSessionIssuer    `json:"sessionIssuer,omitempty"`
+	WebIDFederationData json.RawMessage             `json:"webIdFederationData,omitempty"`
+	Attributes          CloudTrailSessionAttributes `json:"attributes"`
+	SourceIdentity      string                      `json:"sourceIdentity,omitempty"`
+}
+
+// CloudTrailSessionIssuer identifies the role or user that issued the temporary security credentials.
+type CloudTrailSessionIssuer struct {
+	Type        string `json:"type"`
+	PrincipalID string `json:"principalId"`
+	ARN         string `json:"arn"`
+	AccountID   string `json:"accountId"`
+	UserName    string `json:"userName"`
+}
+
+// CloudTrailSessionAttributes holds when the temporary security credentials were issued, and whether MFA was used.
+type CloudTrailSessionAttributes struct {
+	CreationDate     time.Time `json:"creationDate"`
+	MFAAuthenticated string    `json:"mfaAuthenticated"` // MFAAuthenticated is "true" or "false"
+}
+
+// CloudTrailResource identifies a resource accessed by an API call.
+type CloudTrailResource struct {
+	ARN       string `json:"ARN"`
+	AccountID string `json:"accountId"`
+	Type      string `json:"type,omitempty"`
+}
+
+// CloudTrailTLSDetails describes the TLS connection of an API call.
+type CloudTrailTLSDetails struct {
+	TLSVersion               string `json:"tlsVersion"`
+	CipherSuite              string `json:"cipherSuite"`
+	ClientProvidedHostHeader string `json:"clientProvidedHostHeader"`
+}
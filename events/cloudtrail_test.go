@@ -0,0 +1,55 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudTrailAPICallMarshaling(t *testing.T) {
+	// 1. read JSON from file
+	inputJSON := test.ReadJSONFromFile(t, "./testdata/cloudtrail-api-call-event.json")
+
+	// 2. de-serialize into Go object
+	var inputEvent CloudWatchEvent
+	require.NoError(t, json.Unmarshal(inputJSON, &inputEvent))
+	assert.Equal(t, CloudTrailAPICallDetailType, inputEvent.DetailType)
+
+	var detail CloudTrailDetail
+	require.NoError(t, inputEvent.DetailInto(&detail))
+	assert.Equal(t, "CreateBucket", detail.EventName)
+	assert.Equal(t, "s3.amazonaws.com", detail.EventSource)
+	assert.Equal(t, "AssumedRole", detail.UserIdentity.Type)
+	require.NotNil(t, detail.UserIdentity.SessionContext)
+	assert.Equal(t, "arn:aws:iam::123456789012:role/Admin", detail.UserIdentity.SessionContext.SessionIssuer.ARN)
+	assert.Equal(t, time.Date(2023, 3, 14, 17, 1, 45, 0, time.UTC), detail.UserIdentity.SessionContext.Attributes.CreationDate)
+	assert.Equal(t, time.Date(2023, 3, 14, 17, 24, 6, 0, time.UTC), detail.EventTime)
+	require.NotNil(t, detail.ReadOnly)
+	assert.False(t, *detail.ReadOnly)
+	assert.Equal(t, "AWS::S3::Bucket", detail.Resources[0].Type)
+
+	var requestParameters struct {
+		BucketName string `json:"bucketName"`
+	}
+	require.NoError(t, json.Unmarshal(detail.RequestParameters, &requestParameters))
+	assert.Equal(t, "example-bucket", requestParameters.BucketName)
+
+	// 3. serialize to JSON
+	detailJSON, err := json.Marshal(detail)
+	require.NoError(t, err)
+	inputEvent.Detail = detailJSON
+	outputJSON, err := json.Marshal(inputEvent)
+	require.NoError(t, err)
+
+	// 4. check result
+	assert.JSONEq(t, string(inputJSON), string(outputJSON))
+}
+
+func TestCloudTrailAPICallMarshalingMalformedJson(t *testing.T) {
+	test.TestMalformedJson(t, CloudTrailDetail{})
+}
@@ -19,3 +19,15 @@ type CloudWatchEvent struct {
 }
 
 type EventBridgeEvent = CloudWatchEvent
+
+// DetailInto unmarshals the event's detail into the value pointed to by out.
+//
+// Example:
+//
+//	var detail events.ChimeSDKMeetingEventDetail
+//	if err := event.DetailInto(&detail); err != nil {
+//		return err
+//	}
+func (e CloudWatchEvent) DetailInto(out interface{}) error {
+	return json.Unmarshal(e.Detail, out)
+}
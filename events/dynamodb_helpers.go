@@ -0,0 +1,75 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DynamoDBReplicationMetadata holds the attributes global tables (version 2017.11.29) add to every replicated item.
+type DynamoDBReplicationMetadata struct {
+	UpdateRegion string    // UpdateRegion is the region where the item was last written, from aws:rep:updateregion
+	UpdateTime   time.Time // UpdateTime is when the item was last written, from aws:rep:updatetime
+	Deleting     bool      // Deleting is set while a delete is being replicated, from aws:rep:deleting
+}
+
+// ReplicationMetadata returns the global table replication attributes of the record's item.
+// They are read from the new image, or from the old image for REMOVE records.
+// The second return value is false when the item carries no aws:rep:updateregion attribute, as for items of regular tables.
+func (r DynamoDBEventRecord) ReplicationMetadata() (DynamoDBReplicationMetadata, bool) {
+	image := r.Change.NewImage
+	if _, ok := image["aws:rep:updateregion"]; !ok {
+		image = r.Change.OldImage
+	}
+	region, ok := image["aws:rep:updateregion"]
+	if !ok || region.DataType() != DataTypeString {
+		return DynamoDBReplicationMetadata{}, false
+	}
+	metadata := DynamoDBReplicationMetadata{UpdateRegion: region.String()}
+	if updateTime, ok := image["aws:rep:updatetime"]; ok && updateTime.DataType() == DataTypeNumber {
+		metadata.UpdateTime, _ = parseEpochSeconds(updateTime.Number())
+	}
+	if deleting, ok := image["aws:rep:deleting"]; ok && deleting.DataType() == DataTypeBoolean {
+		metadata.Deleting = deleting.Boolean()
+	}
+	return metadata, true
+}
+
+// IsReplica reports whether the record's change was replicated from another region of a global table,
+// rather than written in the region of the stream. Consumers that write back to the table can skip replicas to avoid loops.
+func (r DynamoDBEventRecord) IsReplica() bool {
+	metadata, ok := r.ReplicationMetadata()
+	return ok && metadata.UpdateRegion != r.AWSRegion
+}
+
+// SequenceNumber returns the sequence number of the record's change within its shard.
+// Consumers checkpoint it to resume reading the stream after the record, with a DynamoDBShardIteratorTypeAfterSequenceNumber iterator.
+// The record's id is its EventID field.
+func (r DynamoDBEventRecord) SequenceNumber() string {
+	return r.Change.SequenceNumber
+}
+
+// parseEpochSeconds parses a decimal number of seconds since the epoch, such as "1515017002.585001", without rounding through a float.
+func parseEpochSeconds(value string) (time.Time, error) {
+	secondsPart, fractionPart := value, ""
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		secondsPart, fractionPart = value[:i], value[i+1:]
+	}
+	seconds, err := strconv.ParseInt(secondsPart, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var nanoseconds int64
+	if fractionPart != "" {
+		if len(fractionPart) > 9 {
+			fractionPart = fractionPart[:9]
+		}
+		fractionPart += strings.Repeat("0", 9-len(fractionPart))
+		if nanoseconds, err = strconv.ParseInt(fractionPart, 10, 64); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(seconds, nanoseconds).UTC(), nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamoDBGlobalTableEventMarshaling(t *testing.T) {
+	inputJSON := test.ReadJSONFromFile(t, "./testdata/dynamodb-global-table-event.json")
+	var event DynamoDBEvent
+	require.NoError(t, json.Unmarshal(inputJSON, &event))
+	outputJSON, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(inputJSON), string(outputJSON))
+}
+
+func TestDynamoDBReplicationMetadata(t *testing.T) {
+	var event DynamoDBEvent
+	require.NoError(t, json.Unmarshal(test.ReadJSONFromFile(t, "./testdata/dynamodb-global-table-event.json"), &event))
+
+	replicated := event.Records[0]
+	metadata, ok := replicated.ReplicationMetadata()
+	require.True(t, ok)
+	assert.Equal(t, DynamoDBReplicationMetadata{
+		UpdateRegion: "eu-west-1",
+		UpdateTime:   time.Date(2018, 1, 3, 22, 3, 22, 585001000, time.UTC),
+	}, metadata)
+	assert.True(t, replicated.IsReplica())
+
+	removed := event.Records[1]
+	metadata, ok = removed.ReplicationMetadata()
+	require.True(t, ok)
+	assert.Equal(t, DynamoDBReplicationMetadata{
+		UpdateRegion: "us-east-1",
+		UpdateTime:   time.Unix(1515017009, 0).UTC(),
+		Deleting:     true,
+	}, metadata)
+	assert.False(t, removed.IsReplica())
+}
+
+func TestDynamoDBReplicationMetadataRegularTable(t *testing.T) {
+	var event DynamoDBEvent
+	require.NoError(t, json.Unmarshal(test.ReadJSONFromFile(t, "./testdata/dynamodb-event.json"), &event))
+	for _, record := range event.Records {
+		_, ok := record.ReplicationMetadata()
+		assert.False(t, ok)
+		assert.False(t, record.IsReplica())
+	}
+}
+
+func TestDynamoDBEventRecordSequenceNumber(t *testing.T) {
+	inputJSON := test.ReadJSONFromFile(t, "./testdata/dynamodb-event.json")
+	var event DynamoDBEvent
+	require.NoError(t, json.Unmarshal(inputJSON, &event))
+	require.Len(t, event.Records, 2)
+	assert.Equal(t, "1405400000000002063282832", event.Records[0].SequenceNumber())
+	assert.Equal(t, "f07f8ca4b0b26cb9c4e5e77e69f274ee", event.Records[0].EventID)
+	assert.Equal(t, "", DynamoDBEventRecord{}.SequenceNumber())
+}
@@ -0,0 +1,252 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var dynamoDBDataTypeNames = map[DynamoDBDataType]string{
+	DataTypeBinary:    "B",
+	DataTypeBoolean:   "BOOL",
+	DataTypeBinarySet: "BS",
+	DataTypeList:      "L",
+	DataTypeMap:       "M",
+	DataTypeNumber:    "N",
+	DataTypeNumberSet: "NS",
+	DataTypeNull:      "NULL",
+	DataTypeString:    "S",
+	DataTypeStringSet: "SS",
+}
+
+// UnmarshalDynamoDBStreamRecord decodes image, such as the NewImage or OldImage of a stream record, into the struct or map pointed to by out,
+// without depending on the attributevalue package of the AWS SDK.
+//
+// Struct fields are matched using the `dynamodbav` struct tag, defaulting to the field name. Fields tagged "-", unexported fields, and fields without a matching attribute are left unchanged.
+// S attributes are decoded into strings, N into integer, floating point, and string fields, BOOL into bools, B into []byte, and NULL into the zero value of the field.
+// L attributes are decoded into slices and arrays, M into structs and maps with string keys, and SS, NS, and BS into slices of the types accepted for S, N, and B.
+// Pointers are allocated as needed. Into an empty interface, attributes are decoded as string, float64, bool, []byte, nil, []interface{}, map[string]interface{},
+// []string, []float64, and [][]byte.
+//
+// Example:
+//
+//	var order struct {
+//		ID    string   `dynamodbav:"id"`
+//		Total float64  `dynamodbav:"total"`
+//		Tags  []string `dynamodbav:"tags"`
+//	}
+//	err := events.UnmarshalDynamoDBStreamRecord(record.Change.NewImage, &order)
+func UnmarshalDynamoDBStreamRecord(image map[string]DynamoDBAttributeValue, out interface{}) error {
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return errors.New("out must be a non-nil pointer")
+	}
+	return decodeDynamoDBAttribute(NewMapAttribute(image), target.Elem(), "")
+}
+
+func decodeDynamoDBAttribute(av DynamoDBAttributeValue, v reflect.Value, path string) error {
+	dataType := av.DataType()
+	if dataType == DataTypeNull {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	switch {
+	case v.Kind() == reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeDynamoDBAttribute(av, v.Elem(), path)
+	case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+		value, err := dynamoDBAttributeInterface(av)
+		if err != nil {
+			return fmt.Errorf("cannot decode attribute %q: %v", path, err)
+		}
+		v.Set(reflect.ValueOf(&value).Elem())
+		return nil
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("cannot decode %s attribute %q into %s", dynamoDBDataTypeNames[dataType], path, v.Type())
+	}
+	switch dataType {
+	case DataTypeString:
+		if v.Kind() != reflect.String {
+			return mismatch()
+		}
+		v.SetString(av.String())
+	case DataTypeNumber:
+		switch v.Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			if err := setFieldFromString(v, av.Number()); err != nil {
+				return fmt.Errorf("cannot decode N attribute %q into %s: %v", path, v.Type(), err)
+			}
+		default:
+			return mismatch()
+		}
+	case DataTypeBoolean:
+		if v.Kind() != reflect.Bool {
+			return mismatch()
+		}
+		v.SetBool(av.Boolean())
+	case DataTypeBinary:
+		if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Uint8 {
+			return mismatch()
+		}
+		v.SetBytes(append([]byte(nil), av.Binary()...))
+	case DataTypeList:
+		return decodeDynamoDBList(av.List(), v, path, mismatch)
+	case DataTypeStringSet:
+		return decodeDynamoDBList(setAttributes(av.StringSet(), NewStringAttribute), v, path, mismatch)
+	case DataTypeNumberSet:
+		return decodeDynamoDBList(setAttributes(av.NumberSet(), NewNumberAttribute), v, path, mismatch)
+	case DataTypeBinarySet:
+		return decodeDynamoDBList(binarySetAttributes(av.BinarySet()), v, path, mismatch)
+	case DataTypeMap:
+		switch v.Kind() {
+		case reflect.Struct:
+			return decodeDynamoDBStruct(av.Map(), v, path)
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return mismatch()
+			}
+			if v.IsNil() {
+				v.Set(reflect.MakeMapWithSize(v.Type(), len(av.Map())))
+			}
+			for key, value := range av.Map() {
+				elem := reflect.New(v.Type().Elem()).Elem()
+				if err := decodeDynamoDBAttribute(value, elem, joinAttributePath(path, key)); err != nil {
+					return err
+				}
+				v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+			}
+		default:
+			return mismatch()
+		}
+	default:
+		return mismatch()
+	}
+	return nil
+}
+
+func decodeDynamoDBList(list []DynamoDBAttributeValue, v reflect.Value, path string, mismatch func() error) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), len(list), len(list)))
+	case reflect.Array:
+		if len(list) > v.Len() {
+			return fmt.Errorf("cannot decode %d elements of attribute %q into %s", len(list), path, v.Type())
+		}
+	default:
+		return mismatch()
+	}
+	for i, value := range list {
+		if err := decodeDynamoDBAttribute(value, v.Index(i), path+"["+strconv.Itoa(i)+"]"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeDynamoDBStruct(attributes map[string]DynamoDBAttributeValue, v reflect.Value, path string) error {
+	structType := v.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		key := field.Name
+		if tag, ok := field.Tag.Lookup("dynamodbav"); ok {
+			if tag = strings.Split(tag, ",")[0]; tag == "-" {
+				continue
+			} else if tag != "" {
+				key = tag
+			}
+		}
+		value, ok := attributes[key]
+		if !ok {
+			continue
+		}
+		if err := decodeDynamoDBAttribute(value, v.Field(i), joinAttributePath(path, key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dynamoDBAttributeInterface(av DynamoDBAttributeValue) (interface{}, error) {
+	switch av.DataType() {
+	case DataTypeString:
+		return av.String(), nil
+	case DataTypeNumber:
+		return av.Float()
+	case DataTypeBoolean:
+		return av.Boolean(), nil
+	case DataTypeBinary:
+		return av.Binary(), nil
+	case DataTypeList:
+		list := make([]interface{}, 0, len(av.List()))
+		for _, value := range av.List() {
+			elem, err := dynamoDBAttributeInterface(value)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, elem)
+		}
+		return list, nil
+	case DataTypeMap:
+		m := make(map[string]interface{}, len(av.Map()))
+		for key, value := range av.Map() {
+			elem, err := dynamoDBAttributeInterface(value)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = elem
+		}
+		return m, nil
+	case DataTypeStringSet:
+		return av.StringSet(), nil
+	case DataTypeNumberSet:
+		numbers := make([]float64, 0, len(av.NumberSet()))
+		for _, number := range av.NumberSet() {
+			f, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return nil, err
+			}
+			numbers = append(numbers, f)
+		}
+		return numbers, nil
+	case DataTypeBinarySet:
+		return av.BinarySet(), nil
+	}
+	return nil, nil
+}
+
+// setAttributes returns the members of an SS or NS set as S or N attributes, as returned by newAttribute
+func setAttributes(values []string, newAttribute func(string) DynamoDBAttributeValue) []DynamoDBAttributeValue {
+	attributes := make([]DynamoDBAttributeValue, 0, len(values))
+	for _, value := range values {
+		attributes = append(attributes, newAttribute(value))
+	}
+	return attributes
+}
+
+// binarySetAttributes returns the members of a BS set as B attributes
+func binarySetAttributes(values [][]byte) []DynamoDBAttributeValue {
+	attributes := make([]DynamoDBAttributeValue, 0, len(values))
+	for _, value := range values {
+		attributes = append(attributes, NewBinaryAttribute(value))
+	}
+	return attributes
+}
+
+func joinAttributePath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamAddress struct {
+	City string `dynamodbav:"city"`
+	Zip  *int   `dynamodbav:"zip"`
+}
+
+type streamOrder struct {
+	ID        string                   `dynamodbav:"id"`
+	Total     float64                  `dynamodbav:"total"`
+	Quantity  uint8                    `dynamodbav:"quantity"`
+	Paid      bool                     `dynamodbav:"paid"`
+	Note      *string                  `dynamodbav:"note"`
+	Tags      []string                 `dynamodbav:"tags"`
+	Scores    []int64                  `dynamodbav:"scores"`
+	Blobs     [][]byte                 `dynamodbav:"blobs"`
+	Payload   []byte                   `dynamodbav:"payload"`
+	Address   streamAddress            `dynamodbav:"address"`
+	Items     []map[string]interface{} `dynamodbav:"items"`
+	Counts    map[string]int           `dynamodbav:"counts"`
+	Extra     interface{}              `dynamodbav:"extra"`
+	Version   string                   `dynamodbav:"version"`
+	Ignored   string                   `dynamodbav:"-"`
+	Untouched string
+}
+
+func TestUnmarshalDynamoDBStreamRecord(t *testing.T) {
+	image := map[string]DynamoDBAttributeValue{
+		"id":       NewStringAttribute("order-1"),
+		"total":    NewNumberAttribute("12.5"),
+		"quantity": NewNumberAttribute("3"),
+		"paid":     NewBooleanAttribute(true),
+		"note":     NewNullAttribute(),
+		"tags":     NewStringSetAttribute([]string{"a", "b"}),
+		"scores":   NewNumberSetAttribute([]string{"1", "-2"}),
+		"blobs":    NewBinarySetAttribute([][]byte{{1}, {2, 3}}),
+		"payload":  NewBinaryAttribute([]byte("raw")),
+		"address": NewMapAttribute(map[string]DynamoDBAttributeValue{
+			"city": NewStringAttribute("Seattle"),
+			"zip":  NewNumberAttribute("98101"),
+		}),
+		"items": NewListAttribute([]DynamoDBAttributeValue{
+			NewMapAttribute(map[string]DynamoDBAttributeValue{
+				"sku":     NewStringAttribute("widget"),
+				"options": NewListAttribute([]DynamoDBAttributeValue{NewNumberAttribute("1"), NewNullAttribute(), NewBooleanAttribute(false)}),
+			}),
+		}),
+		"counts":  NewMapAttribute(map[string]DynamoDBAttributeValue{"x": NewNumberAttribute("7")}),
+		"extra":   NewNumberSetAttribute([]string{"1.5"}),
+		"version": NewNumberAttribute("42"),
+		"Ignored": NewStringAttribute("nope"),
+		"-":       NewStringAttribute("nope"),
+	}
+	order := streamOrder{Untouched: "kept", Note: new(string)}
+	require.NoError(t, UnmarshalDynamoDBStreamRecord(image, &order))
+
+	zip := 98101
+	assert.Equal(t, streamOrder{
+		ID:       "order-1",
+		Total:    12.5,
+		Quantity: 3,
+		Paid:     true,
+		Tags:     []string{"a", "b"},
+		Scores:   []int64{1, -2},
+		Blobs:    [][]byte{{1}, {2, 3}},
+		Payload:  []byte("raw"),
+		Address:  streamAddress{City: "Seattle", Zip: &zip},
+		Items: []map[string]interface{}{
+			{"sku": "widget", "options": []interface{}{1.0, nil, false}},
+		},
+		Counts:    map[string]int{"x": 7},
+		Extra:     []float64{1.5},
+		Version:   "42",
+		Untouched: "kept",
+	}, order)
+}
+
+func TestUnmarshalDynamoDBStreamRecordFromEvent(t *testing.T) {
+	var event DynamoDBEvent
+	require.NoError(t, json.Unmarshal(test.ReadJSONFromFile(t, "./testdata/dynamodb-event.json"), &event))
+	var item map[string]interface{}
+	require.NoError(t, UnmarshalDynamoDBStreamRecord(event.Records[0].Change.NewImage, &item))
+	assert.NotEmpty(t, item)
+	for key, value := range event.Records[0].Change.NewImage {
+		assert.Contains(t, item, key)
+		if value.DataType() == DataTypeString {
+			assert.Equal(t, value.String(), item[key])
+		}
+	}
+}
+
+func TestUnmarshalDynamoDBStreamRecordErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		image    map[string]DynamoDBAttributeValue
+		expected string
+	}{
+		{
+			name:     "string into a number",
+			image:    map[string]DynamoDBAttributeValue{"total": NewStringAttribute("lots")},
+			expected: `cannot decode S attribute "total" into float64`,
+		},
+		{
+			name:     "number out of range",
+			image:    map[string]DynamoDBAttributeValue{"quantity": NewNumberAttribute("300")},
+			expected: `cannot decode N attribute "quantity" into uint8: strconv.ParseUint: parsing "300": value out of range`,
+		},
+		{
+			name: "nested mismatch",
+			image: map[string]DynamoDBAttributeValue{"items": NewListAttribute([]DynamoDBAttributeValue{
+				NewStringAttribute("widget"),
+			})},
+			expected: `cannot decode S attribute "items[0]" into map[string]interface {}`,
+		},
+		{
+			name: "nested struct field",
+			image: map[string]DynamoDBAttributeValue{"address": NewMapAttribute(map[string]DynamoDBAttributeValue{
+				"zip": NewBooleanAttribute(true),
+			})},
+			expected: `cannot decode BOOL attribute "address.zip" into int`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var order streamOrder
+			assert.EqualError(t, UnmarshalDynamoDBStreamRecord(testCase.image, &order), testCase.expected)
+		})
+	}
+
+	var order streamOrder
+	assert.EqualError(t, UnmarshalDynamoDBStreamRecord(nil, order), "out must be a non-nil pointer")
+	var count int
+	assert.EqualError(t, UnmarshalDynamoDBStreamRecord(nil, &count), `cannot decode M attribute "" into int`)
+}
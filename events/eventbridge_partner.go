@@ -0,0 +1,65 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PartnerEventSourcePrefix is the prefix of the source of events sent to a partner event bus by a SaaS integration,
+// such as "aws.partner/example.com/123456789012/orders".
+const PartnerEventSourcePrefix = "aws.partner/"
+
+// IsPartnerEvent reports whether the event was sent by a SaaS partner integration, rather than by an AWS service or a custom application.
+func (e CloudWatchEvent) IsPartnerEvent() bool {
+	return strings.HasPrefix(e.Source, PartnerEventSourcePrefix)
+}
+
+// PartnerName returns the partner name of an event sent by a SaaS partner integration, such as "example.com" for the source
+// "aws.partner/example.com/123456789012/orders". The second return value is false for other events.
+func (e CloudWatchEvent) PartnerName() (string, bool) {
+	if !e.IsPartnerEvent() {
+		return "", false
+	}
+	name := strings.Split(strings.TrimPrefix(e.Source, PartnerEventSourcePrefix), "/")[0]
+	return name, name != ""
+}
+
+// EventSourceRouter calls the handler registered for the longest prefix of an EventBridge event's source,
+// so that a single function can handle the events of several partner integrations or services.
+//
+// Example:
+//
+//	router := &events.EventSourceRouter{}
+//	router.Handle("aws.partner/example.com/", handleExampleEvent)
+//	router.Handle("aws.s3", handleS3Event)
+//	lambda.Start(router.Route)
+type EventSourceRouter struct {
+	routes map[string]func(context.Context, CloudWatchEvent) error
+}
+
+// Handle registers the handler for events whose source starts with prefix, replacing any handler registered for the same prefix.
+// The empty prefix matches every event, so its handler receives the events no other handler matches.
+func (r *EventSourceRouter) Handle(prefix string, handler func(ctx context.Context, event CloudWatchEvent) error) {
+	if r.routes == nil {
+		r.routes = map[string]func(context.Context, CloudWatchEvent) error{}
+	}
+	r.routes[prefix] = handler
+}
+
+// Route calls the handler registered for the longest prefix of the event's source, returning its error.
+// It returns an error when no handler matches the event's source.
+func (r *EventSourceRouter) Route(ctx context.Context, event CloudWatchEvent) error {
+	matched, found := "", false
+	for prefix := range r.routes {
+		if strings.HasPrefix(event.Source, prefix) && (!found || len(prefix) > len(matched)) {
+			matched, found = prefix, true
+		}
+	}
+	if !found {
+		return fmt.Errorf("no handler is registered for the event source %q", event.Source)
+	}
+	return r.routes[matched](ctx, event)
+}
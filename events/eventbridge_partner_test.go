@@ -0,0 +1,85 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartnerEventMarshaling(t *testing.T) {
+	inputJSON := test.ReadJSONFromFile(t, "./testdata/eventbridge-partner-event.json")
+
+	var inputEvent EventBridgeEvent
+	require.NoError(t, json.Unmarshal(inputJSON, &inputEvent))
+	assert.Equal(t, "aws.partner/example.com/123456789012/orders", inputEvent.Source)
+	assert.True(t, inputEvent.IsPartnerEvent())
+	name, ok := inputEvent.PartnerName()
+	assert.True(t, ok)
+	assert.Equal(t, "example.com", name)
+
+	var detail struct {
+		OrderID  string `json:"orderId"`
+		Customer struct {
+			Tier string `json:"tier"`
+		} `json:"customer"`
+	}
+	require.NoError(t, inputEvent.DetailInto(&detail))
+	assert.Equal(t, "1234", detail.OrderID)
+	assert.Equal(t, "gold", detail.Customer.Tier)
+
+	outputJSON, err := json.Marshal(inputEvent)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(inputJSON), string(outputJSON))
+}
+
+func TestPartnerName(t *testing.T) {
+	for _, source := range []string{"aws.s3", "com.example.orders", "aws.partner/", "aws.partnerexample.com"} {
+		event := CloudWatchEvent{Source: source}
+		name, ok := event.PartnerName()
+		assert.False(t, ok, source)
+		assert.Empty(t, name, source)
+	}
+	assert.False(t, CloudWatchEvent{Source: "aws.events"}.IsPartnerEvent())
+}
+
+func TestEventSourceRouter(t *testing.T) {
+	var routed []string
+	route := func(name string) func(context.Context, CloudWatchEvent) error {
+		return func(_ context.Context, event CloudWatchEvent) error {
+			routed = append(routed, name+" "+event.Source)
+			return nil
+		}
+	}
+	router := &EventSourceRouter{}
+	router.Handle(PartnerEventSourcePrefix, route("partner"))
+	router.Handle("aws.partner/example.com/", route("example"))
+	router.Handle("aws.s3", route("s3"))
+	router.Handle("aws.s3", route("s3 replaced"))
+	router.Handle("com.example.", func(context.Context, CloudWatchEvent) error {
+		return errors.New("cannot handle")
+	})
+
+	for _, source := range []string{"aws.partner/example.com/123456789012/orders", "aws.partner/other.io/123456789012/default", "aws.s3"} {
+		require.NoError(t, router.Route(context.Background(), CloudWatchEvent{Source: source}))
+	}
+	assert.Equal(t, []string{
+		"example aws.partner/example.com/123456789012/orders",
+		"partner aws.partner/other.io/123456789012/default",
+		"s3 replaced aws.s3",
+	}, routed)
+
+	assert.EqualError(t, router.Route(context.Background(), CloudWatchEvent{Source: "com.example.orders"}), "cannot handle")
+	assert.EqualError(t, router.Route(context.Background(), CloudWatchEvent{Source: "aws.ec2"}), `no handler is registered for the event source "aws.ec2"`)
+	assert.EqualError(t, (&EventSourceRouter{}).Route(context.Background(), CloudWatchEvent{}), `no handler is registered for the event source ""`)
+
+	router.Handle("", route("default"))
+	require.NoError(t, router.Route(context.Background(), CloudWatchEvent{Source: "aws.ec2"}))
+	assert.Equal(t, "default aws.ec2", routed[len(routed)-1])
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"time"
+)
+
+// ArrivalTime returns the time the record was accepted by the delivery stream.
+func (r KinesisFirehoseEventRecord) ArrivalTime() time.Time {
+	return r.ApproximateArrivalTimestamp.Time
+}
+
+// KinesisMetadata returns the metadata of the Kinesis data stream record the Firehose record was read from.
+// The second return value is false for delivery streams that do not use a Kinesis data stream as their source.
+func (r KinesisFirehoseEventRecord) KinesisMetadata() (KinesisFirehoseRecordMetadata, bool) {
+	metadata := r.KinesisFirehoseRecordMetadata
+	return metadata, metadata.ShardID != ""
+}
+
+// PartitionKey returns the partition key of the source Kinesis data stream record.
+// The second return value is false for delivery streams that do not use a Kinesis data stream as their source.
+func (r KinesisFirehoseEventRecord) PartitionKey() (string, bool) {
+	metadata, ok := r.KinesisMetadata()
+	return metadata.PartitionKey, ok
+}
+
+// ShardID returns the id of the shard of the source Kinesis data stream record.
+// The second return value is false for delivery streams that do not use a Kinesis data stream as their source.
+func (r KinesisFirehoseEventRecord) ShardID() (string, bool) {
+	metadata, ok := r.KinesisMetadata()
+	return metadata.ShardID, ok
+}
+
+// ArrivalTime returns the time the record was added to the source Kinesis data stream.
+func (m KinesisFirehoseRecordMetadata) ArrivalTime() time.Time {
+	return m.ApproximateArrivalTimestamp.Time
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFirehoseRecordKinesisMetadata(t *testing.T) {
+	var event KinesisFirehoseEvent
+	require.NoError(t, json.Unmarshal(test.ReadJSONFromFile(t, "./testdata/kinesis-firehose-event.json"), &event))
+	record := event.Records[1]
+
+	assert.Equal(t, time.Date(2017, 10, 5, 15, 33, 44, 302000000, time.UTC), record.ArrivalTime().UTC())
+
+	metadata, ok := record.KinesisMetadata()
+	require.True(t, ok)
+	assert.Equal(t, "49546986683135544286507457936321625675700192471156785155", metadata.SequenceNumber)
+	assert.Equal(t, int64(123457), metadata.SubsequenceNumber)
+	assert.Equal(t, time.Date(2017, 10, 5, 15, 33, 44, 302000000, time.UTC), metadata.ArrivalTime().UTC())
+
+	partitionKey, ok := record.PartitionKey()
+	assert.True(t, ok)
+	assert.Equal(t, "4d1ad2b9-24f8-4b9d-a088-76e9947c318a", partitionKey)
+
+	shardID, ok := record.ShardID()
+	assert.True(t, ok)
+	assert.Equal(t, "shardId-000000000001", shardID)
+}
+
+func TestFirehoseRecordDirectPutMetadata(t *testing.T) {
+	var event KinesisFirehoseEvent
+	require.NoError(t, json.Unmarshal(test.ReadJSONFromFile(t, "./testdata/kinesis-firehose-direct-put-event.json"), &event))
+	record := event.Records[0]
+
+	assert.Equal(t, time.Date(2017, 5, 18, 2, 2, 29, 453000000, time.UTC), record.ArrivalTime().UTC())
+	assert.Equal(t, "Hello World", string(record.Data))
+
+	_, ok := record.KinesisMetadata()
+	assert.False(t, ok)
+	_, ok = record.PartitionKey()
+	assert.False(t, ok)
+	_, ok = record.ShardID()
+	assert.False(t, ok)
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// HTTPRequest is implemented by APIGatewayProxyRequest, APIGatewayV2HTTPRequest, LambdaFunctionURLRequest, and ALBTargetGroupRequest,
+// so that helpers like BearerToken, Method, and BodyInto work the same way for each HTTP integration.
+type HTTPRequest interface {
+	header(name string) (string, bool)
+	method() string
+	body() (string, bool)
+}
+
+var (
+	_ HTTPRequest = APIGatewayProxyRequest{}
+	_ HTTPRequest = APIGatewayV2HTTPRequest{}
+	_ HTTPRequest = LambdaFunctionURLRequest{}
+	_ HTTPRequest = ALBTargetGroupRequest{}
+)
+
+func (r APIGatewayProxyRequest) header(name string) (string, bool) {
+	return lookupMultiValueHeader(r.Headers, r.MultiValueHeaders, name)
+}
+
+func (r APIGatewayProxyRequest) method() string {
+	if r.HTTPMethod != "" {
+		return r.HTTPMethod
+	}
+	return r.RequestContext.HTTPMethod
+}
+
+func (r APIGatewayProxyRequest) body() (string, bool) {
+	return r.Body, r.IsBase64Encoded
+}
+
+func (r APIGatewayV2HTTPRequest) header(name string) (string, bool) {
+	return lookupHeader(r.Headers, name)
+}
+
+func (r APIGatewayV2HTTPRequest) method() string {
+	return r.RequestContext.HTTP.Method
+}
+
+func (r APIGatewayV2HTTPRequest) body() (string, bool) {
+	return r.Body, r.IsBase64Encoded
+}
+
+func (r LambdaFunctionURLRequest) header(name string) (string, bool) {
+	return lookupHeader(r.Headers, name)
+}
+
+func (r LambdaFunctionURLRequest) method() string {
+	return r.RequestContext.HTTP.Method
+}
+
+func (r LambdaFunctionURLRequest) body() (string, bool) {
+	return r.Body, r.IsBase64Encoded
+}
+
+func (r ALBTargetGroupRequest) header(name string) (string, bool) {
+	return lookupMultiValueHeader(r.Headers, r.MultiValueHeaders, name)
+}
+
+func (r ALBTargetGroupRequest) method() string {
+	return r.HTTPMethod
+}
+
+func (r ALBTargetGroupRequest) body() (string, bool) {
+	return r.Body, r.IsBase64Encoded
+}
+
+// lookupMultiValueHeader finds a header in the single-value headers, then in the multi-value headers, sent by the integrations
+// with a multiValueHeaders field
+func lookupMultiValueHeader(headers map[string]string, multiValueHeaders map[string][]string, name string) (string, bool) {
+	if value, ok := lookupHeader(headers, name); ok {
+		return value, true
+	}
+	for key, values := range multiValueHeaders {
+		if strings.EqualFold(key, name) && len(values) > 0 {
+			return values[0], true
+		}
+	}
+	return "", false
+}
+
+// lookupHeader finds a header by case-insensitive name, as the casing of header names differs between integrations
+func lookupHeader(headers map[string]string, name string) (string, bool) {
+	if value, ok := headers[name]; ok {
+		return value, true
+	}
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// BearerToken returns the token of a request's "Authorization: Bearer <token>" header.
+// The "Bearer" scheme is matched case-insensitively.
+// The second return value is false when the header is missing, uses another scheme, or has an empty token.
+//
+// Example:
+//
+//	token, ok := events.BearerToken(request)
+//	if !ok {
+//		return events.LambdaFunctionURLResponse{StatusCode: http.StatusUnauthorized}, nil
+//	}
+func BearerToken(req HTTPRequest) (string, bool) {
+	authorization, ok := req.header("Authorization")
+	if !ok {
+		return "", false
+	}
+	authorization = strings.TrimSpace(authorization)
+	i := strings.IndexByte(authorization, ' ')
+	if i < 0 || !strings.EqualFold(authorization[:i], "Bearer") {
+		return "", false
+	}
+	token := strings.TrimSpace(authorization[i+1:])
+	if token == "" || strings.ContainsAny(token, " \t") {
+		return "", false
+	}
+	return token, true
+}
+
+// Method returns the HTTP method of a request, such as "GET", as sent by the integration.
+// The second return value is false when the request carries no method, as for requests built by hand in tests.
+//
+// Example:
+//
+//	switch method, _ := events.Method(request); method {
+//	case http.MethodGet:
+//		return getItem(ctx, request)
+//	case http.MethodPut:
+//		return putItem(ctx, request)
+//	}
+func Method(req HTTPRequest) (string, bool) {
+	method := req.method()
+	return method, method != ""
+}
+
+// BodyInto decodes the JSON body of a request into the value pointed to by out, decoding the body first when IsBase64Encoded is set.
+// Requests with a Content-Type header other than application/json, or another JSON media type such as application/merge-patch+json, are rejected.
+// Requests without a Content-Type header are decoded as JSON.
+//
+// Example:
+//
+//	var order Order
+//	if err := events.BodyInto(request, &order); err != nil {
+//		return events.LambdaFunctionURLResponse{StatusCode: http.StatusBadRequest}, nil
+//	}
+func BodyInto(req HTTPRequest, out interface{}) error {
+	if contentType, ok := req.header("Content-Type"); ok {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return fmt.Errorf("cannot parse content type %q: %v", contentType, err)
+		}
+		if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+			return fmt.Errorf("cannot decode a body with content type %q as JSON", mediaType)
+		}
+	}
+	body, isBase64Encoded := req.body()
+	data := []byte(body)
+	if isBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return fmt.Errorf("failed to decode the base64 encoded body: %w", err)
+		}
+		data = decoded
+	}
+	if len(data) == 0 {
+		return errors.New("request body is empty")
+	}
+	return json.Unmarshal(data, out)
+}
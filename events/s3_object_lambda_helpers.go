@@ -0,0 +1,80 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// S3ObjectLambdaObjectResponse describes the transformed object sent back to the caller of an S3 Object Lambda access point with WriteGetObjectResponse.
+type S3ObjectLambdaObjectResponse struct {
+	StatusCode    int    // StatusCode is the HTTP status sent to the caller, and is only sent when non-zero
+	ContentType   string // ContentType is the Content-Type of the object, and is only sent when non-empty
+	ContentLength int64  // ContentLength is the length of the body, and must be set for the body to be sent with a Content-Length
+	ErrorCode     string // ErrorCode is the S3 error code sent to the caller, such as "NoSuchKey", for a failed request
+	ErrorMessage  string // ErrorMessage is the error message sent to the caller, for a failed request
+}
+
+// NewWriteGetObjectResponseRequest returns the WriteGetObjectResponse request sending body, the transformed object, back to the caller of the access point,
+// using the output route and token of the event's GetObjectContext. The region of the request is read from the access point ARN.
+// The request still has to be signed with Signature Version 4 for the "s3" service, with an unsigned payload,
+// such as with the v4.Signer of the AWS SDK for Go, before it is sent.
+//
+// Usage:
+//
+//	req, err := event.NewWriteGetObjectResponseRequest(ctx, bytes.NewReader(transformed), events.S3ObjectLambdaObjectResponse{
+//		StatusCode:    http.StatusOK,
+//		ContentType:   "text/plain",
+//		ContentLength: int64(len(transformed)),
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+//	err = signer.SignHTTP(ctx, credentials, req, "UNSIGNED-PAYLOAD", "s3", region, time.Now())
+func (e S3ObjectLambdaEvent) NewWriteGetObjectResponseRequest(ctx context.Context, body io.Reader, response S3ObjectLambdaObjectResponse) (*http.Request, error) {
+	if e.GetObjectContext == nil {
+		return nil, errors.New("the event has no getObjectContext, WriteGetObjectResponse only answers GetObject requests")
+	}
+	if e.GetObjectContext.OutputRoute == "" || e.GetObjectContext.OutputToken == "" {
+		return nil, errors.New("the event's getObjectContext has no outputRoute or outputToken")
+	}
+	// arn:partition:service:region:account-id:resource
+	fields := strings.SplitN(e.Configuration.AccessPointARN, ":", 6)
+	if len(fields) != 6 || fields[0] != "arn" || fields[3] == "" {
+		return nil, fmt.Errorf("cannot read the region of access point ARN %q", e.Configuration.AccessPointARN)
+	}
+	region, dnsSuffix := fields[3], "amazonaws.com"
+	if fields[1] == "aws-cn" {
+		dnsSuffix = "amazonaws.com.cn"
+	}
+	url := "https://" + e.GetObjectContext.OutputRoute + ".s3-object-lambda." + region + "." + dnsSuffix + "/WriteGetObjectResponse"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Amz-Request-Route", e.GetObjectContext.OutputRoute)
+	req.Header.Set("X-Amz-Request-Token", e.GetObjectContext.OutputToken)
+	if response.StatusCode != 0 {
+		req.Header.Set("X-Amz-Fwd-Status", strconv.Itoa(response.StatusCode))
+	}
+	if response.ContentType != "" {
+		req.Header.Set("X-Amz-Fwd-Header-Content-Type", response.ContentType)
+	}
+	if response.ErrorCode != "" {
+		req.Header.Set("X-Amz-Fwd-Error-Code", response.ErrorCode)
+	}
+	if response.ErrorMessage != "" {
+		req.Header.Set("X-Amz-Fwd-Error-Message", response.ErrorMessage)
+	}
+	if response.ContentLength > 0 {
+		req.ContentLength = response.ContentLength
+	}
+	return req, nil
+}
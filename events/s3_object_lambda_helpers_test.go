@@ -0,0 +1,72 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil" //nolint: staticcheck
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWriteGetObjectResponseRequest(t *testing.T) {
+	var event S3ObjectLambdaEvent
+	require.NoError(t, json.Unmarshal(test.ReadJSONFromFile(t, "./testdata/s3-object-lambda-event-get-object-iam.json"), &event))
+
+	req, err := event.NewWriteGetObjectResponseRequest(context.Background(), strings.NewReader("HELLO"), S3ObjectLambdaObjectResponse{
+		StatusCode:    http.StatusOK,
+		ContentType:   "text/plain",
+		ContentLength: 5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "https://"+event.GetObjectContext.OutputRoute+".s3-object-lambda.us-east-1.amazonaws.com/WriteGetObjectResponse", req.URL.String())
+	assert.Equal(t, event.GetObjectContext.OutputRoute, req.Header.Get("X-Amz-Request-Route"))
+	assert.Equal(t, event.GetObjectContext.OutputToken, req.Header.Get("X-Amz-Request-Token"))
+	assert.Equal(t, "200", req.Header.Get("X-Amz-Fwd-Status"))
+	assert.Equal(t, "text/plain", req.Header.Get("X-Amz-Fwd-Header-Content-Type"))
+	assert.Empty(t, req.Header.Get("X-Amz-Fwd-Error-Code"))
+	assert.Equal(t, int64(5), req.ContentLength)
+	body, err := ioutil.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", string(body))
+}
+
+func TestNewWriteGetObjectResponseRequestError(t *testing.T) {
+	event := S3ObjectLambdaEvent{
+		GetObjectContext: &S3ObjectLambdaGetObjectContext{OutputRoute: "io-use1-001", OutputToken: "OutputToken"},
+		Configuration:    S3ObjectLambdaConfiguration{AccessPointARN: "arn:aws-cn:s3-object-lambda:cn-north-1:111122223333:accesspoint/example"},
+	}
+	req, err := event.NewWriteGetObjectResponseRequest(context.Background(), http.NoBody, S3ObjectLambdaObjectResponse{
+		StatusCode:   http.StatusNotFound,
+		ErrorCode:    "NoSuchKey",
+		ErrorMessage: "The specified key does not exist.",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "io-use1-001.s3-object-lambda.cn-north-1.amazonaws.com.cn", req.URL.Host)
+	assert.Equal(t, "404", req.Header.Get("X-Amz-Fwd-Status"))
+	assert.Equal(t, "NoSuchKey", req.Header.Get("X-Amz-Fwd-Error-Code"))
+	assert.Equal(t, "The specified key does not exist.", req.Header.Get("X-Amz-Fwd-Error-Message"))
+	assert.Empty(t, req.Header.Get("X-Amz-Fwd-Header-Content-Type"))
+}
+
+func TestNewWriteGetObjectResponseRequestInvalidEvent(t *testing.T) {
+	var headEvent S3ObjectLambdaEvent
+	require.NoError(t, json.Unmarshal(test.ReadJSONFromFile(t, "./testdata/s3-object-lambda-event-head-object-iam.json"), &headEvent))
+	_, err := headEvent.NewWriteGetObjectResponseRequest(context.Background(), http.NoBody, S3ObjectLambdaObjectResponse{})
+	assert.EqualError(t, err, "the event has no getObjectContext, WriteGetObjectResponse only answers GetObject requests")
+
+	event := S3ObjectLambdaEvent{GetObjectContext: &S3ObjectLambdaGetObjectContext{OutputRoute: "route", OutputToken: "token"}}
+	_, err = event.NewWriteGetObjectResponseRequest(context.Background(), http.NoBody, S3ObjectLambdaObjectResponse{})
+	assert.EqualError(t, err, `cannot read the region of access point ARN ""`)
+
+	event.GetObjectContext.OutputToken = ""
+	_, err = event.NewWriteGetObjectResponseRequest(context.Background(), http.NoBody, S3ObjectLambdaObjectResponse{})
+	assert.EqualError(t, err, "the event's getObjectContext has no outputRoute or outputToken")
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"strings"
+	"time"
+)
+
+// Detail types of the EventBridge events sent by AWS Security Hub.
+const (
+	SecurityHubFindingsImportedDetailType     = "Security Hub Findings - Imported"
+	SecurityHubFindingsCustomActionDetailType = "Security Hub Findings - Custom Action"
+)
+
+// SecurityHubFindingsDetail is the detail of an AWS Security Hub findings event delivered by EventBridge.
+// Use CloudWatchEvent.DetailInto to decode it from an EventBridge event with a Security Hub findings detail-type.
+type SecurityHubFindingsDetail struct {
+	ActionName        string               `json:"actionName,omitempty"`        // ActionName is only sent for custom action events
+	ActionDescription string               `json:"actionDescription,omitempty"` // ActionDescription is only sent for custom action events
+	Findings          []SecurityHubFinding `json:"findings"`
+}
+
+// SecurityHubFinding is a finding in the AWS Security Finding Format (ASFF).
+// Findings from Amazon Inspector, Amazon GuardDuty, and other integrated products use this format.
+type SecurityHubFinding struct {
+	SchemaVersion         string                            `json:"SchemaVersion"`
+	ID                    string                            `json:"Id"`
+	ProductARN            string                            `json:"ProductArn"`
+	ProductName           string                            `json:"ProductName,omitempty"`
+	CompanyName           string                            `json:"CompanyName,omitempty"`
+	Region                string                            `json:"Region,omitempty"`
+	GeneratorID           string                            `json:"GeneratorId"`
+	AWSAccountID          string                            `json:"AwsAccountId"`
+	Types                 []string                          `json:"Types,omitempty"`
+	FirstObservedAt       *time.Time                        `json:"FirstObservedAt,omitempty"`
+	LastObservedAt        *time.Time                        `json:"LastObservedAt,omitempty"`
+	CreatedAt             time.Time                         `json:"CreatedAt"`
+	UpdatedAt             time.Time                         `json:"UpdatedAt"`
+	Severity              SecurityHubSeverity               `json:"Severity"`
+	Title                 string                            `json:"Title"`
+	Description           string                            `json:"Description"`
+	Remediation           *SecurityHubRemediation           `json:"Remediation,omitempty"`
+	ProductFields         map[string]string                 `json:"ProductFields,omitempty"`
+	Resources             []SecurityHubResource             `json:"Resources"`
+	Compliance            *SecurityHubCompliance            `json:"Compliance,omitempty"`
+	WorkflowState         string                            `json:"WorkflowState,omitempty"`
+	Workflow              *SecurityHubWorkflow              `json:"Workflow,omitempty"`
+	RecordState           string                            `json:"RecordState,omitempty"`
+	FindingProviderFields *SecurityHubFindingProviderFields `json:"FindingProviderFields,omitempty"`
+}
+
+type SecurityHubSeverity struct {
+	Label      string  `json:"Label"` // Label is one of INFORMATIONAL, LOW, MEDIUM, HIGH, or CRITICAL
+	Normalized int     `json:"Normalized,omitempty"`
+	Original   string  `json:"Original,omitempty"`
+	Product    float64 `json:"Product,omitempty"`
+}
+
+type SecurityHubRemediation struct {
+	Recommendation SecurityHubRecommendation `json:"Recommendation"`
+}
+
+type SecurityHubRecommendation struct {
+	Text string `json:"Text,omitempty"`
+	URL  string `json:"Url,omitempty"`
+}
+
+type SecurityHubResource struct {
+	Type      string                 `json:"Type"`
+	ID        string                 `json:"Id"`
+	Partition string                 `json:"Partition,omitempty"`
+	Region    string                 `json:"Region,omitempty"`
+	Tags      map[string]string      `json:"Tags,omitempty"`
+	Details   map[string]interface{} `json:"Details,omitempty"`
+}
+
+type SecurityHubCompliance struct {
+	Status              string                        `json:"Status"` // Status is one of PASSED, WARNING, FAILED, or NOT_AVAILABLE
+	RelatedRequirements []string                      `json:"RelatedRequirements,omitempty"`
+	StatusReasons       []SecurityHubComplianceReason `json:"StatusReasons,omitempty"`
+}
+
+type SecurityHubComplianceReason struct {
+	ReasonCode  string `json:"ReasonCode"`
+	Description string `json:"Description,omitempty"`
+}
+
+type SecurityHubWorkflow struct {
+	Status string `json:"Status"` // Status is one of NEW, NOTIFIED, SUPPRESSED, or RESOLVED
+}
+
+type SecurityHubFindingProviderFields struct {
+	Severity *SecurityHubSeverity `json:"Severity,omitempty"`
+	Types    []string             `json:"Types,omitempty"`
+}
+
+// SeverityLabel returns the finding's severity label, such as HIGH or CRITICAL.
+func (f SecurityHubFinding) SeverityLabel() string {
+	return f.Severity.Label
+}
+
+// ResourceARNs returns the ids of the finding's resources that are ARNs.
+func (f SecurityHubFinding) ResourceARNs() []string {
+	var arns []string
+	for _, resource := range f.Resources {
+		if strings.HasPrefix(resource.ID, "arn:") {
+			arns = append(arns, resource.ID)
+		}
+	}
+	return arns
+}
+
+// ComplianceStatus returns the status of the compliance check that generated the finding,
+// or an empty string for findings that are not from a compliance check.
+func (f SecurityHubFinding) ComplianceStatus() string {
+	if f.Compliance == nil {
+		return ""
+	}
+	return f.Compliance.Status
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityHubFindingsMarshaling(t *testing.T) {
+	// 1. read JSON from file
+	inputJSON := test.ReadJSONFromFile(t, "./testdata/security-hub-findings-imported.json")
+
+	// 2. de-serialize into Go object
+	var inputEvent CloudWatchEvent
+	require.NoError(t, json.Unmarshal(inputJSON, &inputEvent))
+	assert.Equal(t, "aws.securityhub", inputEvent.Source)
+	assert.Equal(t, SecurityHubFindingsImportedDetailType, inputEvent.DetailType)
+
+	var detail SecurityHubFindingsDetail
+	require.NoError(t, inputEvent.DetailInto(&detail))
+	require.Len(t, detail.Findings, 1)
+	finding := detail.Findings[0]
+	assert.Equal(t, "MEDIUM", finding.SeverityLabel())
+	assert.Equal(t, 40, finding.Severity.Normalized)
+	assert.Equal(t, []string{"arn:aws:s3:::example-bucket"}, finding.ResourceARNs())
+	assert.Equal(t, "FAILED", finding.ComplianceStatus())
+	assert.Equal(t, "123456789012", finding.AWSAccountID)
+	assert.Equal(t, time.Date(2022, 4, 11, 21, 52, 13, 321000000, time.UTC), finding.UpdatedAt)
+	assert.Equal(t, "S3.1", finding.ProductFields["ControlId"])
+
+	// 3. serialize to JSON
+	detailJSON, err := json.Marshal(detail)
+	require.NoError(t, err)
+	inputEvent.Detail = detailJSON
+	outputJSON, err := json.Marshal(inputEvent)
+	require.NoError(t, err)
+
+	// 4. check result
+	assert.JSONEq(t, string(inputJSON), string(outputJSON))
+}
+
+func TestSecurityHubFindingsMarshalingMalformedJson(t *testing.T) {
+	test.TestMalformedJson(t, SecurityHubFindingsDetail{})
+}
+
+func TestSecurityHubFindingAccessorsWithoutCompliance(t *testing.T) {
+	finding := SecurityHubFinding{
+		Severity:  SecurityHubSeverity{Label: "HIGH"},
+		Resources: []SecurityHubResource{{Type: "Other", ID: "not-an-arn"}},
+	}
+	assert.Equal(t, "HIGH", finding.SeverityLabel())
+	assert.Empty(t, finding.ResourceARNs())
+	assert.Equal(t, "", finding.ComplianceStatus())
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqsNow is replaced in tests
+var sqsNow = time.Now
+
+// MessageGroupID returns the MessageGroupId attribute of a message from a FIFO queue.
+// The second return value is false for messages from standard queues.
+func (m SQSMessage) MessageGroupID() (string, bool) {
+	return m.attribute("MessageGroupId")
+}
+
+// MessageDeduplicationID returns the MessageDeduplicationId attribute of a message from a FIFO queue.
+// The second return value is false for messages from standard queues.
+func (m SQSMessage) MessageDeduplicationID() (string, bool) {
+	return m.attribute("MessageDeduplicationId")
+}
+
+// SequenceNumber returns the SequenceNumber attribute assigned by a FIFO queue, which orders the messages within a group.
+// The second return value is false for messages from standard queues.
+func (m SQSMessage) SequenceNumber() (string, bool) {
+	return m.attribute("SequenceNumber")
+}
+
+// SentTimestamp returns the time the message was sent to the queue, read from the SentTimestamp attribute in epoch milliseconds.
+// The second return value is false when the attribute is missing or not a number.
+func (m SQSMessage) SentTimestamp() (time.Time, bool) {
+	value, ok := m.attribute("SentTimestamp")
+	if !ok {
+		return time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)).UTC(), true
+}
+
+// Age returns the time elapsed since the message was sent to the queue, such as to drop stale messages.
+// The second return value is false when the message has no valid SentTimestamp attribute.
+//
+// Usage:
+//
+//	if age, ok := message.Age(); ok && age > 10*time.Minute {
+//		return nil // stale, drop it
+//	}
+func (m SQSMessage) Age() (time.Duration, bool) {
+	sent, ok := m.SentTimestamp()
+	if !ok {
+		return 0, false
+	}
+	return sqsNow().Sub(sent), true
+}
+
+// SQSContentEncodingAttribute is the message attribute naming the encoding of the message body, a String or Binary attribute set to "base64" for base64 encoded bodies.
+const SQSContentEncodingAttribute = "ContentEncoding"
+
+// DecodedBody returns the body of the message, base64 decoded when the message has a SQSContentEncodingAttribute attribute set to "base64".
+// The body is returned unchanged for messages without the attribute, and an error for any other encoding.
+// Use Base64Body for producers that always base64 encode bodies without setting the attribute.
+//
+// Usage:
+//
+//	body, err := message.DecodedBody()
+//	if err != nil {
+//		return err
+//	}
+//	return json.Unmarshal(body, &order)
+func (m SQSMessage) DecodedBody() ([]byte, error) {
+	attribute, ok := m.MessageAttributes[SQSContentEncodingAttribute]
+	if !ok {
+		return []byte(m.Body), nil
+	}
+	var encoding string
+	if attribute.StringValue != nil {
+		encoding = *attribute.StringValue
+	} else {
+		encoding = string(attribute.BinaryValue)
+	}
+	if !strings.EqualFold(strings.TrimSpace(encoding), "base64") {
+		return nil, fmt.Errorf("unsupported %s %q of message %s", SQSContentEncodingAttribute, encoding, m.MessageId)
+	}
+	return m.Base64Body()
+}
+
+// Base64Body returns the base64 decoded body of the message, whatever its attributes. Bodies with or without padding are accepted.
+func (m SQSMessage) Base64Body() ([]byte, error) {
+	body := strings.TrimSpace(m.Body)
+	encoding := base64.StdEncoding
+	if !strings.HasSuffix(body, "=") {
+		encoding = base64.RawStdEncoding
+	}
+	decoded, err := encoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot base64 decode the body of message %s: %v", m.MessageId, err)
+	}
+	return decoded, nil
+}
+
+func (m SQSMessage) attribute(name string) (string, bool) {
+	value, ok := m.Attributes[name]
+	return value, ok
+}
+
+// SQSDefaultGroupID is the key under which GroupSQSByGroupID places messages without a MessageGroupId attribute.
+const SQSDefaultGroupID = ""
+
+// GroupSQSByGroupID groups the event's messages by their MessageGroupId attribute, so that each group of a FIFO queue can be processed independently.
+// Messages keep their order within each group. Messages from standard queues are grouped under SQSDefaultGroupID.
+func GroupSQSByGroupID(event SQSEvent) map[string][]SQSMessage {
+	groups := make(map[string][]SQSMessage)
+	for _, message := range event.Records {
+		groupID, ok := message.MessageGroupID()
+		if !ok {
+			groupID = SQSDefaultGroupID
+		}
+		groups[groupID] = append(groups[groupID], message)
+	}
+	return groups
+}
+
+// SQSBatchResponse builds the SQSEventResponse reporting the messages of a batch that failed processing,
+// for event source mappings with ReportBatchItemFailures enabled. Only the reported messages are retried.
+//
+// Usage:
+//
+//	lambda.Start(func(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+//		batch := events.NewSQSBatchResponse()
+//		for _, message := range event.Records {
+//			if err := process(ctx, message); err != nil {
+//				batch.AddFailure(message.MessageId)
+//			}
+//		}
+//		return batch.Response(), nil
+//	})
+type SQSBatchResponse struct {
+	failures []SQSBatchItemFailure
+	added    map[string]struct{}
+}
+
+// NewSQSBatchResponse returns an SQSBatchResponse without failures. The zero value is also ready to use.
+func NewSQSBatchResponse() *SQSBatchResponse {
+	return &SQSBatchResponse{}
+}
+
+// AddFailure reports the message with the id messageID as failed. Reporting a message more than once has no further effect.
+func (b *SQSBatchResponse) AddFailure(messageID string) {
+	if _, ok := b.added[messageID]; ok {
+		return
+	}
+	if b.added == nil {
+		b.added = map[string]struct{}{}
+	}
+	b.added[messageID] = struct{}{}
+	b.failures = append(b.failures, SQSBatchItemFailure{ItemIdentifier: messageID})
+}
+
+// Response returns the SQSEventResponse to return from the handler, listing the failures in the order they were added.
+// Without failures, BatchItemFailures is an empty slice, so that it is sent as [] rather than null.
+func (b *SQSBatchResponse) Response() SQSEventResponse {
+	return SQSEventResponse{BatchItemFailures: append([]SQSBatchItemFailure{}, b.failures...)}
+}
@@ -0,0 +1,214 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQSFIFOAttributes(t *testing.T) {
+	var event SQSEvent
+	require.NoError(t, json.Unmarshal(test.ReadJSONFromFile(t, "./testdata/sqs-fifo-event.json"), &event))
+	message := event.Records[0]
+
+	groupID, ok := message.MessageGroupID()
+	assert.True(t, ok)
+	assert.Equal(t, "1", groupID)
+
+	deduplicationID, ok := message.MessageDeduplicationID()
+	assert.True(t, ok)
+	assert.Equal(t, "1", deduplicationID)
+
+	sequenceNumber, ok := message.SequenceNumber()
+	assert.True(t, ok)
+	assert.Equal(t, "18849496460467696128", sequenceNumber)
+}
+
+func TestSQSStandardQueueAttributes(t *testing.T) {
+	var event SQSEvent
+	require.NoError(t, json.Unmarshal(test.ReadJSONFromFile(t, "./testdata/sqs-event.json"), &event))
+	message := event.Records[0]
+
+	_, ok := message.MessageGroupID()
+	assert.False(t, ok)
+	_, ok = message.MessageDeduplicationID()
+	assert.False(t, ok)
+	_, ok = message.SequenceNumber()
+	assert.False(t, ok)
+	_, ok = SQSMessage{}.SequenceNumber()
+	assert.False(t, ok)
+}
+
+func TestSQSSentTimestamp(t *testing.T) {
+	var event SQSEvent
+	require.NoError(t, json.Unmarshal(test.ReadJSONFromFile(t, "./testdata/sqs-event.json"), &event))
+	message := event.Records[0]
+
+	sent, ok := message.SentTimestamp()
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2018, time.March, 9, 18, 53, 45, 29000000, time.UTC), sent)
+
+	sqsNow = func() time.Time { return sent.Add(90 * time.Second) }
+	defer func() { sqsNow = time.Now }()
+	age, ok := message.Age()
+	assert.True(t, ok)
+	assert.Equal(t, 90*time.Second, age)
+}
+
+func TestSQSSentTimestampMissing(t *testing.T) {
+	for _, message := range []SQSMessage{
+		{},
+		{Attributes: map[string]string{"SentTimestamp": "yesterday"}},
+	} {
+		sent, ok := message.SentTimestamp()
+		assert.False(t, ok)
+		assert.True(t, sent.IsZero())
+		age, ok := message.Age()
+		assert.False(t, ok)
+		assert.Zero(t, age)
+	}
+}
+
+func TestGroupSQSByGroupID(t *testing.T) {
+	message := func(id, groupID string) SQSMessage {
+		m := SQSMessage{MessageId: id}
+		if groupID != "" {
+			m.Attributes = map[string]string{"MessageGroupId": groupID}
+		}
+		return m
+	}
+	event := SQSEvent{Records: []SQSMessage{
+		message("1", "a"),
+		message("2", "b"),
+		message("3", ""),
+		message("4", "a"),
+		message("5", "b"),
+		message("6", "a"),
+	}}
+
+	groups := GroupSQSByGroupID(event)
+	ids := map[string][]string{}
+	for groupID, messages := range groups {
+		for _, m := range messages {
+			ids[groupID] = append(ids[groupID], m.MessageId)
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"a":               {"1", "4", "6"},
+		"b":               {"2", "5"},
+		SQSDefaultGroupID: {"3"},
+	}, ids)
+}
+
+func TestGroupSQSByGroupIDEmptyEvent(t *testing.T) {
+	assert.Empty(t, GroupSQSByGroupID(SQSEvent{}))
+
+	var event SQSEvent
+	require.NoError(t, json.Unmarshal(test.ReadJSONFromFile(t, "./testdata/sqs-fifo-event.json"), &event))
+	assert.Len(t, GroupSQSByGroupID(event)["1"], len(event.Records))
+}
+
+func TestSQSBatchResponse(t *testing.T) {
+	batch := NewSQSBatchResponse()
+	batch.AddFailure("059f36b4-87a3-44ab-83d2-661975830a7d")
+	batch.AddFailure("2e1424d4-f796-459a-8184-9c92662be6da")
+	batch.AddFailure("059f36b4-87a3-44ab-83d2-661975830a7d")
+	outputJSON, err := json.Marshal(batch.Response())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"batchItemFailures": [
+			{"itemIdentifier": "059f36b4-87a3-44ab-83d2-661975830a7d"},
+			{"itemIdentifier": "2e1424d4-f796-459a-8184-9c92662be6da"}
+		]
+	}`, string(outputJSON))
+
+	// the response is a copy, unaffected by later failures
+	response := batch.Response()
+	batch.AddFailure("another")
+	assert.Len(t, response.BatchItemFailures, 2)
+}
+
+func TestSQSBatchResponseWithoutFailures(t *testing.T) {
+	for _, batch := range []*SQSBatchResponse{NewSQSBatchResponse(), {}} {
+		outputJSON, err := json.Marshal(batch.Response())
+		require.NoError(t, err)
+		assert.Equal(t, `{"batchItemFailures":[]}`, string(outputJSON))
+	}
+}
+
+func TestSQSDecodedBody(t *testing.T) {
+	base64Encoding := "base64"
+	testCases := []struct {
+		name       string
+		message    SQSMessage
+		expected   string
+		errMessage string
+	}{
+		{
+			name:     "plain body",
+			message:  SQSMessage{Body: "aGVsbG8="},
+			expected: "aGVsbG8=",
+		},
+		{
+			name: "string attribute",
+			message: SQSMessage{Body: "aGVsbG8=", MessageAttributes: map[string]SQSMessageAttribute{
+				SQSContentEncodingAttribute: {StringValue: &base64Encoding, DataType: "String"},
+			}},
+			expected: "hello",
+		},
+		{
+			name: "binary attribute",
+			message: SQSMessage{Body: "aGVsbG8", MessageAttributes: map[string]SQSMessageAttribute{
+				SQSContentEncodingAttribute: {BinaryValue: []byte("BASE64"), DataType: "Binary"},
+			}},
+			expected: "hello",
+		},
+		{
+			name: "invalid base64",
+			message: SQSMessage{MessageId: "m1", Body: "not base64!", MessageAttributes: map[string]SQSMessageAttribute{
+				SQSContentEncodingAttribute: {StringValue: &base64Encoding, DataType: "String"},
+			}},
+			errMessage: "cannot base64 decode the body of message m1: illegal base64 data at input byte 3",
+		},
+		{
+			name: "unsupported encoding",
+			message: SQSMessage{MessageId: "m1", Body: "hello", MessageAttributes: map[string]SQSMessageAttribute{
+				SQSContentEncodingAttribute: {BinaryValue: []byte("gzip"), DataType: "Binary"},
+			}},
+			errMessage: `unsupported ContentEncoding "gzip" of message m1`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			body, err := testCase.message.DecodedBody()
+			if testCase.errMessage != "" {
+				assert.EqualError(t, err, testCase.errMessage)
+				assert.Nil(t, body)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, string(body))
+		})
+	}
+}
+
+func TestSQSBase64Body(t *testing.T) {
+	var event SQSEvent
+	require.NoError(t, json.Unmarshal(test.ReadJSONFromFile(t, "./testdata/sqs-event.json"), &event))
+	body, err := event.Records[0].DecodedBody()
+	require.NoError(t, err)
+	assert.Equal(t, event.Records[0].Body, string(body))
+
+	body, err = SQSMessage{Body: "eyJpZCI6MX0=\n"}.Base64Body()
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":1}`, string(body))
+
+	_, err = SQSMessage{MessageId: "m1", Body: `{"id":1}`}.Base64Body()
+	assert.Error(t, err)
+}
@@ -0,0 +1,138 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// TestingT is the subset of *testing.T used by AssertValidEvent.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// AssertValidEvent checks that eventBytes unmarshals into the event type given by eventType,
+// and that the fields AWS always sends for that type are present. It is meant for checking the fixtures used by tests.
+// eventType is a value, or a pointer, of the event type. When it is a non-nil pointer it holds the decoded event on return.
+// Required fields are checked for the SQS, SNS, S3, DynamoDB Streams, Kinesis, Kinesis Firehose, CloudWatch Events,
+// API Gateway, ALB, and Function URL event types. For other types, only the decoding is checked.
+// Failures are reported with t.Errorf, and the return value reports whether the event is valid.
+//
+// Usage:
+//
+//	func TestFixtures(t *testing.T) {
+//		eventBytes, _ := os.ReadFile("testdata/sqs-event.json")
+//		events.AssertValidEvent(t, eventBytes, events.SQSEvent{})
+//	}
+func AssertValidEvent(t TestingT, eventBytes []byte, eventType interface{}) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	event := reflect.ValueOf(eventType)
+	if !event.IsValid() {
+		t.Errorf("eventType must not be nil")
+		return false
+	}
+	if event.Kind() != reflect.Ptr || event.IsNil() {
+		event = reflect.New(event.Type())
+		if event.Type().Elem().Kind() == reflect.Ptr {
+			event = reflect.New(event.Type().Elem().Elem())
+		}
+	}
+	if err := json.Unmarshal(eventBytes, event.Interface()); err != nil {
+		t.Errorf("event does not unmarshal into %s: %v", event.Type().Elem(), err)
+		return false
+	}
+	if missing := missingEventFields(event.Interface()); len(missing) > 0 {
+		t.Errorf("%s is missing required fields: %s", event.Type().Elem(), strings.Join(missing, ", "))
+		return false
+	}
+	return true
+}
+
+type requiredFields []string
+
+func (r *requiredFields) check(present bool, path string, args ...interface{}) {
+	if !present {
+		*r = append(*r, fmt.Sprintf(path, args...))
+	}
+}
+
+// missingEventFields returns the JSON paths of the required fields absent from event, a pointer to an event type
+func missingEventFields(event interface{}) []string {
+	var r requiredFields
+	switch e := event.(type) {
+	case *SQSEvent:
+		r.check(len(e.Records) > 0, "Records")
+		for i, m := range e.Records {
+			r.check(m.MessageId != "", "Records[%d].messageId", i)
+			r.check(m.ReceiptHandle != "", "Records[%d].receiptHandle", i)
+			r.check(m.EventSource != "", "Records[%d].eventSource", i)
+			r.check(m.EventSourceARN != "", "Records[%d].eventSourceARN", i)
+		}
+	case *SNSEvent:
+		r.check(len(e.Records) > 0, "Records")
+		for i, record := range e.Records {
+			r.check(record.EventSource != "", "Records[%d].EventSource", i)
+			r.check(record.SNS.MessageID != "", "Records[%d].Sns.MessageId", i)
+			r.check(record.SNS.TopicArn != "", "Records[%d].Sns.TopicArn", i)
+		}
+	case *S3Event:
+		r.check(len(e.Records) > 0, "Records")
+		for i, record := range e.Records {
+			r.check(record.EventSource != "", "Records[%d].eventSource", i)
+			r.check(record.EventName != "", "Records[%d].eventName", i)
+			r.check(record.S3.Bucket.Name != "", "Records[%d].s3.bucket.name", i)
+			r.check(record.S3.Object.Key != "", "Records[%d].s3.object.key", i)
+		}
+	case *DynamoDBEvent:
+		r.check(len(e.Records) > 0, "Records")
+		for i, record := range e.Records {
+			r.check(record.EventID != "", "Records[%d].eventID", i)
+			r.check(record.EventName != "", "Records[%d].eventName", i)
+			r.check(record.EventSourceArn != "", "Records[%d].eventSourceARN", i)
+			r.check(len(record.Change.Keys) > 0, "Records[%d].dynamodb.Keys", i)
+		}
+	case *KinesisEvent:
+		r.check(len(e.Records) > 0, "Records")
+		for i, record := range e.Records {
+			r.check(record.EventID != "", "Records[%d].eventID", i)
+			r.check(record.EventSourceArn != "", "Records[%d].eventSourceARN", i)
+			r.check(record.Kinesis.PartitionKey != "", "Records[%d].kinesis.partitionKey", i)
+			r.check(record.Kinesis.SequenceNumber != "", "Records[%d].kinesis.sequenceNumber", i)
+		}
+	case *KinesisFirehoseEvent:
+		r.check(e.InvocationID != "", "invocationId")
+		r.check(e.DeliveryStreamArn != "", "deliveryStreamArn")
+		r.check(len(e.Records) > 0, "records")
+		for i, record := range e.Records {
+			r.check(record.RecordID != "", "records[%d].recordId", i)
+		}
+	case *CloudWatchEvent:
+		r.check(e.ID != "", "id")
+		r.check(e.DetailType != "", "detail-type")
+		r.check(e.Source != "", "source")
+		r.check(len(e.Detail) > 0, "detail")
+	case *APIGatewayProxyRequest:
+		r.check(e.HTTPMethod != "", "httpMethod")
+		r.check(e.Path != "", "path")
+		r.check(e.RequestContext.RequestID != "", "requestContext.requestId")
+	case *APIGatewayV2HTTPRequest:
+		r.check(e.RouteKey != "", "routeKey")
+		r.check(e.RawPath != "", "rawPath")
+		r.check(e.RequestContext.RequestID != "", "requestContext.requestId")
+		r.check(e.RequestContext.HTTP.Method != "", "requestContext.http.method")
+	case *ALBTargetGroupRequest:
+		r.check(e.HTTPMethod != "", "httpMethod")
+		r.check(e.Path != "", "path")
+		r.check(e.RequestContext.ELB.TargetGroupArn != "", "requestContext.elb.targetGroupArn")
+	case *LambdaFunctionURLRequest:
+		r.check(e.RawPath != "", "rawPath")
+		r.check(e.RequestContext.RequestID != "", "requestContext.requestId")
+		r.check(e.RequestContext.HTTP.Method != "", "requestContext.http.method")
+	}
+	return r
+}
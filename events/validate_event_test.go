@@ -0,0 +1,110 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertValidEventFixtures(t *testing.T) {
+	testCases := []struct {
+		file      string
+		eventType interface{}
+	}{
+		{"sqs-event.json", SQSEvent{}},
+		{"sqs-fifo-event.json", SQSEvent{}},
+		{"sns-event.json", SNSEvent{}},
+		{"s3-event.json", S3Event{}},
+		{"dynamodb-event.json", DynamoDBEvent{}},
+		{"dynamodb-global-table-event.json", DynamoDBEvent{}},
+		{"kinesis-event.json", KinesisEvent{}},
+		{"kinesis-firehose-event.json", KinesisFirehoseEvent{}},
+		{"security-hub-findings-imported.json", CloudWatchEvent{}},
+		{"codepipeline-execution-state-change-event.json", CloudWatchEvent{}},
+		{"apigw-request.json", APIGatewayProxyRequest{}},
+		{"apigw-v2-request-no-authorizer.json", APIGatewayV2HTTPRequest{}},
+		{"alb-lambda-target-request-headers-only.json", ALBTargetGroupRequest{}},
+		{"lambda-urls-request.json", LambdaFunctionURLRequest{}},
+		{"cognito-event.json", CognitoEvent{}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.file, func(t *testing.T) {
+			AssertValidEvent(t, test.ReadJSONFromFile(t, "./testdata/"+testCase.file), testCase.eventType)
+		})
+	}
+}
+
+func TestAssertValidEventDecodesIntoPointer(t *testing.T) {
+	var event SQSEvent
+	require.True(t, AssertValidEvent(t, test.ReadJSONFromFile(t, "./testdata/sqs-event.json"), &event))
+	assert.Equal(t, "aws:sqs", event.Records[0].EventSource)
+
+	var nilEvent *SQSEvent
+	assert.True(t, AssertValidEvent(t, test.ReadJSONFromFile(t, "./testdata/sqs-event.json"), nilEvent))
+}
+
+func TestAssertValidEventInvalid(t *testing.T) {
+	testCases := []struct {
+		name      string
+		event     string
+		eventType interface{}
+		expected  string
+	}{
+		{
+			name:      "malformed json",
+			event:     string(test.GetMalformedJson()),
+			eventType: SQSEvent{},
+			expected:  "event does not unmarshal into events.SQSEvent: unexpected end of JSON input",
+		},
+		{
+			name:      "wrong field type",
+			event:     `{"Records": {"messageId": "1"}}`,
+			eventType: SQSEvent{},
+			expected:  "event does not unmarshal into events.SQSEvent: json: cannot unmarshal object into Go struct field SQSEvent.Records of type []events.SQSMessage",
+		},
+		{
+			name:      "no records",
+			event:     `{"Records": []}`,
+			eventType: S3Event{},
+			expected:  "events.S3Event is missing required fields: Records",
+		},
+		{
+			name:      "record fields missing",
+			event:     `{"Records": [{"messageId": "1", "eventSource": "aws:sqs"}, {"receiptHandle": "h", "eventSourceARN": "arn"}]}`,
+			eventType: &SQSEvent{},
+			expected:  "events.SQSEvent is missing required fields: Records[0].receiptHandle, Records[0].eventSourceARN, Records[1].messageId, Records[1].eventSource",
+		},
+		{
+			name:      "wrong event type",
+			event:     string(test.ReadJSONFromFile(t, "./testdata/sqs-event.json")),
+			eventType: APIGatewayProxyRequest{},
+			expected:  "events.APIGatewayProxyRequest is missing required fields: httpMethod, path, requestContext.requestId",
+		},
+		{
+			name:      "nil event type",
+			event:     `{}`,
+			eventType: nil,
+			expected:  "eventType must not be nil",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := &recordingT{}
+			assert.False(t, AssertValidEvent(recorder, []byte(testCase.event), testCase.eventType))
+			assert.Equal(t, []string{testCase.expected}, recorder.errors)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignature is returned when a webhook signature does not match the request body.
+var ErrInvalidSignature = errors.New("webhook signature does not match the request body")
+
+// ErrStaleSignatureTimestamp is returned when a webhook's signed timestamp is outside of the allowed tolerance.
+var ErrStaleSignatureTimestamp = errors.New("webhook signature timestamp is outside of the allowed tolerance")
+
+// signatureNow is replaced in tests
+var signatureNow = time.Now
+
+// VerifyHMACSignature checks that signature is the hex encoded HMAC of rawBody, computed with secret and algo, such as sha256.New.
+// A leading "name=" prefix on signature, as in GitHub's "sha256=..." X-Hub-Signature-256 header, is ignored.
+// The comparison is done in constant time.
+//
+// rawBody must be the body exactly as sent by the provider. For Function URL and API Gateway requests,
+// decode Body with base64 first when IsBase64Encoded is set.
+//
+// Example:
+//
+//	err := events.VerifyHMACSignature([]byte(request.Body), request.Headers["x-hub-signature-256"], secret, sha256.New)
+func VerifyHMACSignature(rawBody []byte, signature string, secret []byte, algo func() hash.Hash) error {
+	if i := strings.IndexByte(signature, '='); i >= 0 {
+		signature = signature[i+1:]
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	mac := hmac.New(algo, secret)
+	mac.Write(rawBody) // nolint:errcheck
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// VerifySlackSignature checks a Slack request signature, given the X-Slack-Request-Timestamp and X-Slack-Signature headers.
+// Requests with a timestamp further than tolerance from the current time are rejected with ErrStaleSignatureTimestamp,
+// Slack recommends a tolerance of 5 minutes.
+func VerifySlackSignature(rawBody []byte, timestamp string, signature string, secret []byte, tolerance time.Duration) error {
+	if err := checkSignatureTimestamp(timestamp, tolerance); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(signature, "v0=") {
+		return ErrInvalidSignature
+	}
+	signed := make([]byte, 0, len(timestamp)+len(rawBody)+4)
+	signed = append(signed, "v0:"+timestamp+":"...)
+	signed = append(signed, rawBody...)
+	return VerifyHMACSignature(signed, signature, secret, sha256.New)
+}
+
+// VerifyStripeSignature checks a Stripe webhook signature, given the Stripe-Signature header.
+// The signature is accepted if any of the header's v1 signatures match.
+// Requests with a timestamp further than tolerance from the current time are rejected with ErrStaleSignatureTimestamp,
+// Stripe's libraries default to a tolerance of 5 minutes.
+func VerifyStripeSignature(rawBody []byte, header string, secret []byte, tolerance time.Duration) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "t":
+			timestamp = fields[1]
+		case "v1":
+			signatures = append(signatures, fields[1])
+		}
+	}
+	if err := checkSignatureTimestamp(timestamp, tolerance); err != nil {
+		return err
+	}
+	signed := make([]byte, 0, len(timestamp)+len(rawBody)+1)
+	signed = append(signed, timestamp+"."...)
+	signed = append(signed, rawBody...)
+	for _, signature := range signatures {
+		if VerifyHMACSignature(signed, signature, secret, sha256.New) == nil {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func checkSignatureTimestamp(timestamp string, tolerance time.Duration) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid webhook signature timestamp %q: %w", timestamp, err)
+	}
+	age := signatureNow().Sub(time.Unix(seconds, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrStaleSignatureTimestamp
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package events
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // nolint:gosec
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sign(algo func() hash.Hash, secret, body string) string {
+	mac := hmac.New(algo, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyHMACSignature(t *testing.T) {
+	body := []byte(`{"action":"opened"}`)
+	secret := []byte("It's a Secret to Everybody")
+	signature := sign(sha256.New, "It's a Secret to Everybody", `{"action":"opened"}`)
+
+	assert.NoError(t, VerifyHMACSignature(body, "sha256="+signature, secret, sha256.New))
+	assert.NoError(t, VerifyHMACSignature(body, signature, secret, sha256.New))
+	assert.NoError(t, VerifyHMACSignature(body, "sha1="+sign(sha1.New, string(secret), string(body)), secret, sha1.New))
+
+	assert.Equal(t, ErrInvalidSignature, VerifyHMACSignature(body, "sha256="+signature, []byte("wrong secret"), sha256.New))
+	assert.Equal(t, ErrInvalidSignature, VerifyHMACSignature([]byte(`{"action":"closed"}`), "sha256="+signature, secret, sha256.New))
+	assert.Equal(t, ErrInvalidSignature, VerifyHMACSignature(body, "sha256=not-hex", secret, sha256.New))
+	assert.Equal(t, ErrInvalidSignature, VerifyHMACSignature(body, "", secret, sha256.New))
+}
+
+func withSignatureNow(now time.Time) func() {
+	signatureNow = func() time.Time { return now }
+	return func() { signatureNow = time.Now }
+}
+
+func TestVerifySlackSignature(t *testing.T) {
+	defer withSignatureNow(time.Unix(1531420618, 0))()
+	body := []byte("token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&command=%2Fweather")
+	signature := "v0=" + sign(sha256.New, "8f742231b10e8888abcd99yyyzzz85a5", "v0:1531420618:"+string(body))
+	secret := []byte("8f742231b10e8888abcd99yyyzzz85a5")
+
+	assert.NoError(t, VerifySlackSignature(body, "1531420618", signature, secret, 5*time.Minute))
+	assert.Equal(t, ErrInvalidSignature, VerifySlackSignature(body, "1531420618", signature, []byte("wrong"), 5*time.Minute))
+	assert.Equal(t, ErrInvalidSignature, VerifySlackSignature(body, "1531420617", signature, secret, 5*time.Minute))
+	assert.Equal(t, ErrInvalidSignature, VerifySlackSignature(body, "1531420618", "v1="+signature[3:], secret, 5*time.Minute))
+
+	defer withSignatureNow(time.Unix(1531420618, 0).Add(10 * time.Minute))()
+	assert.Equal(t, ErrStaleSignatureTimestamp, VerifySlackSignature(body, "1531420618", signature, secret, 5*time.Minute))
+	assert.EqualError(t, VerifySlackSignature(body, "yesterday", signature, secret, 5*time.Minute), `invalid webhook signature timestamp "yesterday": strconv.ParseInt: parsing "yesterday": invalid syntax`)
+}
+
+func TestVerifyStripeSignature(t *testing.T) {
+	defer withSignatureNow(time.Unix(1492774577, 0).Add(time.Minute))()
+	body := []byte(`{"id":"evt_1","object":"event"}`)
+	secret := []byte("whsec_test")
+	valid := sign(sha256.New, "whsec_test", "1492774577."+string(body))
+	invalid := sign(sha256.New, "whsec_other", "1492774577."+string(body))
+
+	assert.NoError(t, VerifyStripeSignature(body, "t=1492774577,v1="+valid+",v0=6ffbb59b2300aae63f27240693", secret, 5*time.Minute))
+	assert.NoError(t, VerifyStripeSignature(body, "t=1492774577,v1="+invalid+",v1="+valid, secret, 5*time.Minute))
+	assert.Equal(t, ErrInvalidSignature, VerifyStripeSignature(body, "t=1492774577,v1="+invalid, secret, 5*time.Minute))
+	assert.Equal(t, ErrInvalidSignature, VerifyStripeSignature(body, "t=1492774577", secret, 5*time.Minute))
+	assert.Equal(t, ErrInvalidSignature, VerifyStripeSignature(body, "t=1492774578,v1="+valid, secret, 5*time.Minute))
+	assert.Error(t, VerifyStripeSignature(body, "v1="+valid, secret, 5*time.Minute))
+
+	defer withSignatureNow(time.Unix(1492774577, 0).Add(-time.Hour))()
+	assert.Equal(t, ErrStaleSignatureTimestamp, VerifyStripeSignature(body, "t=1492774577,v1="+valid, secret, 5*time.Minute))
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// withContentLengthCheck checks the body of response against the length declared by the ContentLength() int64 method of the handler's response, body.
+// A body with a Len() int method, such as a *bytes.Buffer, is checked before anything is sent.
+// Other bodies are checked as they are read, failing the response once the body is longer than declared, or ends before it.
+// response is returned unchanged when body does not declare its length.
+func withContentLengthCheck(body, response io.Reader) (io.Reader, error) {
+	declared, ok := body.(interface{ ContentLength() int64 })
+	if !ok {
+		return response, nil
+	}
+	length := declared.ContentLength()
+	if sized, ok := body.(interface{ Len() int }); ok {
+		if int64(sized.Len()) != length {
+			return nil, fmt.Errorf("response declares a content length of %d bytes, but has %d bytes", length, sized.Len())
+		}
+		return response, nil
+	}
+	// the status code and headers of a streamed response precede its body, so only the body is counted
+	if streaming, ok := response.(*events.LambdaFunctionURLStreamingResponse); ok && response != body {
+		streaming.Body = &contentLengthReader{reader: body, declared: length}
+		return streaming, nil
+	}
+	return &contentLengthReader{reader: response, declared: length}, nil
+}
+
+// contentLengthReader fails when the bytes read from reader exceed, or fall short of, those declared
+type contentLengthReader struct {
+	reader   io.Reader
+	declared int64
+	read     int64
+	err      error // err is the length mismatch found while reading
+}
+
+func (r *contentLengthReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.read > r.declared {
+		r.err = fmt.Errorf("response declares a content length of %d bytes, but has more", r.declared)
+		return 0, r.err
+	}
+	if err == io.EOF && r.read < r.declared {
+		r.err = fmt.Errorf("response declares a content length of %d bytes, but has %d bytes", r.declared, r.read)
+		return n, r.err
+	}
+	return n, err
+}
+
+// contentLengthFailure returns the length mismatch found while sending a response returned by withContentLengthCheck
+func contentLengthFailure(response io.Reader) error {
+	if streaming, ok := response.(*events.LambdaFunctionURLStreamingResponse); ok {
+		response = streaming.Body
+	}
+	if checked, ok := response.(*contentLengthReader); ok {
+		return checked.err
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"bytes"
+	"reflect"
+)
+
+// contentTypeTag is the `lambda` struct tag value marking the string field holding a response's content type
+const contentTypeTag = "contentType"
+
+// taggedContentType returns the value of the string field tagged `lambda:"contentType"` in val, when val is a struct or a pointer to one.
+// The second return value is false when there is no such field, or when its value is empty.
+func taggedContentType(val interface{}) (string, bool) {
+	v := reflect.ValueOf(val)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return "", false
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("lambda") != contentTypeTag || field.Type.Kind() != reflect.String {
+			continue
+		}
+		contentType := v.Field(i).String()
+		return contentType, contentType != ""
+	}
+	return "", false
+}
+
+type contentTypeBuffer struct {
+	*bytes.Buffer
+	contentType string
+}
+
+func (c *contentTypeBuffer) ContentType() string {
+	return c.contentType
+}
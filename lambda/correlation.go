@@ -0,0 +1,44 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+var (
+	headersKey           = []byte(`"headers"`)
+	multiValueHeadersKey = []byte(`"multiValueHeaders"`)
+)
+
+// correlationID reads the header from the invoke, then from the headers of an HTTP integration event.
+// It returns the empty string when neither has it, for lambdacontext.CorrelationID to fall back to the request id.
+func correlationID(invoke *invoke, header string) string {
+	if id := invoke.headers.Get(header); id != "" {
+		return id
+	}
+	// only events with headers are decoded, and only for their headers
+	if !bytes.Contains(invoke.payload, headersKey) && !bytes.Contains(invoke.payload, multiValueHeadersKey) {
+		return ""
+	}
+	var event struct {
+		Headers           map[string]string   `json:"headers"`
+		MultiValueHeaders map[string][]string `json:"multiValueHeaders"`
+	}
+	if err := json.Unmarshal(invoke.payload, &event); err != nil {
+		return ""
+	}
+	for key, value := range event.Headers {
+		if strings.EqualFold(key, header) && value != "" {
+			return value
+		}
+	}
+	for key, values := range event.MultiValueHeaders {
+		if strings.EqualFold(key, header) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
@@ -4,6 +4,7 @@ package lambda
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 )
@@ -15,10 +16,12 @@ import (
 // Rules:
 //
 //   - handler must be a function
-//   - handler may take between 0 and two arguments.
+//   - handler may take between 0 and three arguments.
 //   - if there are two arguments, the first argument must satisfy the "context.Context" interface.
-//   - handler may return between 0 and two values.
+//   - if there are three arguments, the first must satisfy the "context.Context" interface, the second must be []byte or json.RawMessage, and the third must be neither.
+//   - handler may return between 0 and three values.
 //   - if there are two return values, the second return value must be an error.
+//   - if there are three return values, the second return value must be response metadata, and the third an error.
 //   - if there is one return value it must be an error.
 //
 // Valid function signatures:
@@ -35,12 +38,32 @@ import (
 //	func (context.Context, TIn)
 //	func (context.Context, TIn) error
 //	func (context.Context, TIn) (TOut, error)
+//	func (context.Context, TIn) (TOut, TMeta, error)
+//	func (context.Context, []byte, TIn) error
+//	func (context.Context, []byte, TIn) (TOut, error)
 //
 // Where "TIn" and "TOut" are types compatible with the "encoding/json" standard library.
 // See https://golang.org/pkg/encoding/json/#Unmarshal for how deserialization behaves
+// Handlers taking three arguments receive the exact bytes of the payload along with the decoded "TIn", such as to verify the signature of a webhook.
+// If "TOut" implements json.Marshaler with a pointer receiver, the marshaler is also used when a "TOut" value is returned.
+// If "TOut" contains a kind that encoding/json cannot serialize, such as a chan or func, Start fails before serving any invoke.
+// If "TOut" is a struct with a string field tagged `lambda:"contentType"`, a non-empty value of that field is sent as the response content type.
+//
+// Handlers streaming their response to a Function URL may instead take a lambda.ResponseWriter as the last argument:
+//
+//	func (context.Context, lambda.ResponseWriter) error
+//	func (context.Context, TIn, lambda.ResponseWriter) error
 //
 // "TOut" may also implement the io.Reader interface.
 // If "TOut" is both json serializable and implements io.Reader, then the json serialization is used.
+// If an io.Reader "TOut" also implements StatusCode() int or Headers() http.Header, it is streamed as a Function URL response with that status and headers.
+// If an io.Reader "TOut" implements ContentEncoding() string, such as for a body compressed with gzip, it is streamed the same way, with a non-empty encoding as the Content-Encoding header.
+// "TMeta" is http.Header, *http.Header, or a type implementing ContentType() string, Headers() http.Header, StatusCode() int, or any of them.
+// When "TOut" is an events.APIGatewayProxyResponse, events.APIGatewayV2HTTPResponse, events.ALBTargetGroupResponse, events.LambdaFunctionURLResponse,
+// or a pointer to one, the content type, headers, and status code of "TMeta" are merged into its fields, overriding those already set.
+// For any other "TOut", its headers and status code are sent with the encoded "TOut" in the same way as above, streamed as a Function URL response,
+// which only Function URLs with response streaming understand, and its content type as the response content type.
+// A nil "TMeta" leaves the response unchanged.
 func Start(handler interface{}) {
 	StartWithOptions(handler)
 }
@@ -83,6 +106,7 @@ var (
 
 	// This allows end to end testing of the Start functions, by tests overwriting this function to keep the program alive
 	logFatalf = log.Fatalf
+	osExit    = os.Exit
 )
 
 // StartHandlerWithContext is the same as StartHandler except sets the base context for the function.
@@ -97,6 +121,13 @@ func StartHandlerWithContext(ctx context.Context, handler Handler) {
 }
 
 func start(handler *handlerOptions) {
+	if handler.startErr != nil {
+		logFatalf("%v", handler.startErr)
+		return
+	}
+	if handler.autoMaxProcs {
+		setAutoMaxProcs()
+	}
 	var keys []string
 	for _, start := range startFunctions {
 		config := os.Getenv(start.env)
@@ -104,6 +135,16 @@ func start(handler *handlerOptions) {
 			// in normal operation, the start function never returns
 			// if it does, exit!, this triggers a restart of the lambda function
 			err := start.f(config, handler)
+			if err == nil {
+				// the start function only returns nil when a clean exit was requested, see WithExitOnEOF and WithRuntimeResetStatus
+				return
+			}
+			var exitErr *exitError
+			if errors.As(err, &exitErr) {
+				log.Print(err)
+				osExit(exitErr.code)
+				return
+			}
 			logFatalf("%v", err)
 		}
 		keys = append(keys, start.env)
@@ -4,11 +4,13 @@ package lambda
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/aws/aws-lambda-go/lambda/messages"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,3 +33,47 @@ func TestStartRuntimeAPIWithContext(t *testing.T) {
 
 	assert.Equal(t, expected, actual)
 }
+
+func TestStartRejectsUnserializableOutput(t *testing.T) {
+	server, record := runtimeAPIServer("null", 1)
+	defer server.Close()
+
+	os.Setenv("AWS_LAMBDA_RUNTIME_API", strings.Split(server.URL, "://")[1])
+	defer os.Unsetenv("AWS_LAMBDA_RUNTIME_API")
+	var actual string
+	logFatalf = func(format string, v ...interface{}) {
+		actual = fmt.Sprintf(format, v...)
+	}
+	defer func() { logFatalf = log.Fatalf }()
+
+	type output struct {
+		Updates chan string
+	}
+	Start(func() (output, error) {
+		return output{}, nil
+	})
+
+	assert.Equal(t, "handler returns type lambda.output, which cannot be serialized to JSON: Updates has unsupported kind chan", actual)
+	assert.Equal(t, 0, record.nGets)
+}
+
+func TestStartExitsWithExitCode(t *testing.T) {
+	server, _ := runtimeAPIServer("null", 1)
+	defer server.Close()
+
+	os.Setenv("AWS_LAMBDA_RUNTIME_API", strings.Split(server.URL, "://")[1])
+	defer os.Unsetenv("AWS_LAMBDA_RUNTIME_API")
+	fatal := false
+	logFatalf = func(format string, v ...interface{}) { fatal = true }
+	defer func() { logFatalf = log.Fatalf }()
+	code := -1
+	osExit = func(c int) { code = c }
+	defer func() { osExit = os.Exit }()
+
+	StartWithOptions(func() error { panic("oops") }, WithExitCode(func(err *messages.InvokeResponse_Error) int {
+		return 42
+	}))
+
+	assert.Equal(t, 42, code)
+	assert.False(t, fatal)
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// loadEnvConfig copies the environment variables named prefix plus the `env` struct tag of each field, defaulting to the upper-cased field name,
+// into the struct pointed to by out. Fields without a variable in the environment keep their value, unless tagged "required";
+// the variables of missing required fields are listed in the returned error.
+func loadEnvConfig(prefix string, out interface{}) error {
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("env config must be a non-nil pointer to a struct, got %T", out)
+	}
+	target = target.Elem()
+	targetType := target.Type()
+	var missing []string
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, required := strings.ToUpper(field.Name), false
+		if tag, ok := field.Tag.Lookup("env"); ok {
+			options := strings.Split(tag, ",")
+			if options[0] == "-" {
+				continue
+			} else if options[0] != "" {
+				name = options[0]
+			}
+			for _, option := range options[1:] {
+				required = required || option == "required"
+			}
+		}
+		key := prefix + name
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			if required {
+				missing = append(missing, key)
+			}
+			continue
+		}
+		if err := setEnvField(target.Field(i), value); err != nil {
+			return fmt.Errorf("cannot decode environment variable %s into field %s: %v", key, field.Name, err)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.New("missing required environment variables: " + strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// setEnvField sets field from value, coerced to a string, bool, number, time.Duration, or comma separated slice of those
+func setEnvField(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setEnvField(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+	case reflect.Slice:
+		if value == "" {
+			field.Set(reflect.MakeSlice(field.Type(), 0, 0))
+			return nil
+		}
+		elems := strings.Split(value, ",")
+		slice := reflect.MakeSlice(field.Type(), len(elems), len(elems))
+		for i, elem := range elems {
+			if err := setEnvField(slice.Index(i), strings.TrimSpace(elem)); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
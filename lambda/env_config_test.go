@@ -0,0 +1,137 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type envTestConfig struct {
+	TableName string        `env:"TABLE_NAME,required"`
+	Retries   int           `env:"RETRIES"`
+	Ratio     float64       `env:"RATIO"`
+	Debug     bool          `env:"DEBUG"`
+	Timeout   time.Duration `env:"TIMEOUT"`
+	Queues    []string      `env:"QUEUES"`
+	Ports     []uint16      `env:"PORTS"`
+	Limit     *int64        `env:"LIMIT"`
+	Region    string
+	Skipped   string `env:"-"`
+	Default   string `env:"DEFAULT"`
+}
+
+func TestEnvConfig(t *testing.T) {
+	for key, value := range map[string]string{
+		"ENV_TEST_TABLE_NAME": "orders",
+		"ENV_TEST_RETRIES":    "3",
+		"ENV_TEST_RATIO":      "0.5",
+		"ENV_TEST_DEBUG":      "true",
+		"ENV_TEST_TIMEOUT":    "1m30s",
+		"ENV_TEST_QUEUES":     "a, b,c",
+		"ENV_TEST_PORTS":      "80,443",
+		"ENV_TEST_LIMIT":      "-1",
+		"ENV_TEST_REGION":     "us-west-2",
+		"ENV_TEST_-":          "nope",
+		"ENV_TEST_SKIPPED":    "nope",
+	} {
+		os.Setenv(key, value)
+		defer os.Unsetenv(key)
+	}
+	require.NoError(t, os.Unsetenv("ENV_TEST_DEFAULT"))
+	ts, record := runtimeAPIServer(`{}`, 1)
+	defer ts.Close()
+
+	var configs []interface{}
+	cfg := &envTestConfig{Default: "kept"}
+	handler := NewHandlerWithOptions(func(ctx context.Context) error {
+		config, ok := lambdacontext.Config(ctx)
+		assert.True(t, ok)
+		configs = append(configs, config)
+		return nil
+	}, WithEnvConfig("ENV_TEST_", cfg))
+	err := startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.Error(t, err, "the loop should have run until the test server ended it")
+	require.Equal(t, 1, record.nPosts)
+	assert.Equal(t, "/2018-06-01/runtime/invocation/dummyid/response", record.postPaths[0])
+
+	limit := int64(-1)
+	assert.Equal(t, &envTestConfig{
+		TableName: "orders",
+		Retries:   3,
+		Ratio:     0.5,
+		Debug:     true,
+		Timeout:   90 * time.Second,
+		Queues:    []string{"a", "b", "c"},
+		Ports:     []uint16{80, 443},
+		Limit:     &limit,
+		Region:    "us-west-2",
+		Default:   "kept",
+	}, cfg)
+	require.Len(t, configs, 1)
+	assert.Same(t, cfg, configs[0])
+}
+
+func TestEnvConfigInvalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		env      map[string]string
+		out      interface{}
+		expected string
+	}{
+		{
+			name:     "missing required",
+			env:      map[string]string{"ENV_TEST_RETRIES": "3"},
+			out:      &envTestConfig{},
+			expected: "missing required environment variables: ENV_TEST_TABLE_NAME",
+		},
+		{
+			name:     "invalid number",
+			env:      map[string]string{"ENV_TEST_TABLE_NAME": "orders", "ENV_TEST_RETRIES": "many"},
+			out:      &envTestConfig{},
+			expected: `cannot decode environment variable ENV_TEST_RETRIES into field Retries: strconv.ParseInt: parsing "many": invalid syntax`,
+		},
+		{
+			name:     "invalid slice element",
+			env:      map[string]string{"ENV_TEST_TABLE_NAME": "orders", "ENV_TEST_PORTS": "80,http"},
+			out:      &envTestConfig{},
+			expected: `cannot decode environment variable ENV_TEST_PORTS into field Ports: strconv.ParseUint: parsing "http": invalid syntax`,
+		},
+		{
+			name:     "not a pointer to a struct",
+			out:      envTestConfig{},
+			expected: "env config must be a non-nil pointer to a struct, got lambda.envTestConfig",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			for _, key := range []string{"ENV_TEST_TABLE_NAME", "ENV_TEST_RETRIES", "ENV_TEST_PORTS"} {
+				require.NoError(t, os.Unsetenv(key))
+			}
+			for key, value := range testCase.env {
+				os.Setenv(key, value)
+				defer os.Unsetenv(key)
+			}
+			ts, record := runtimeAPIServer(`{}`, 1)
+			defer ts.Close()
+
+			calls := 0
+			handler := NewHandlerWithOptions(func() error {
+				calls++
+				return nil
+			}, WithEnvConfig("ENV_TEST_", testCase.out))
+			err := startRuntimeAPILoop(serverAddress(ts), handler)
+			assert.EqualError(t, err, testCase.expected)
+			assert.Equal(t, 0, calls)
+			require.Equal(t, 1, record.nPosts)
+			assert.Equal(t, "/2018-06-01/runtime/init/error", record.postPaths[0])
+			assert.Contains(t, string(record.responses[0]), `"errorType":"Runtime.InvalidEnvironmentConfig"`)
+		})
+	}
+}
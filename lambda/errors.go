@@ -4,6 +4,7 @@ package lambda
 
 import (
 	"reflect"
+	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/lambda/messages"
 )
@@ -29,9 +30,28 @@ func lambdaErrorResponse(invokeError error) *messages.InvokeResponse_Error {
 	return &messages.InvokeResponse_Error{
 		Message: invokeError.Error(),
 		Type:    errorName,
+		Causes:  errorCauses(invokeError),
 	}
 }
 
+// errorCauses returns the errors wrapped by err, with errors.Unwrap or, as for errors.Join, an Unwrap() []error method
+func errorCauses(err error) []*messages.InvokeResponse_Error {
+	var wrapped []error
+	switch err := err.(type) {
+	case interface{ Unwrap() error }:
+		wrapped = []error{err.Unwrap()}
+	case interface{ Unwrap() []error }:
+		wrapped = err.Unwrap()
+	}
+	var causes []*messages.InvokeResponse_Error
+	for _, cause := range wrapped {
+		if cause != nil {
+			causes = append(causes, lambdaErrorResponse(cause))
+		}
+	}
+	return causes
+}
+
 func lambdaPanicResponse(err interface{}) *messages.InvokeResponse_Error {
 	if ive, ok := err.(messages.InvokeResponse_Error); ok {
 		return &ive
@@ -44,3 +64,28 @@ func lambdaPanicResponse(err interface{}) *messages.InvokeResponse_Error {
 		ShouldExit: true,
 	}
 }
+
+// formatPanicMessage replaces the message of a panic response with the one returned by format, unless the panic value is an error
+func formatPanicMessage(invokeErr *messages.InvokeResponse_Error, value interface{}, format func(interface{}) string) {
+	if format == nil {
+		return
+	}
+	switch value.(type) {
+	case error, messages.InvokeResponse_Error:
+		return
+	}
+	invokeErr.Message = format(value)
+}
+
+const truncatedErrorMessageMarker = "..."
+
+// truncateErrorMessage shortens message to at most n bytes followed by the truncation marker, without splitting a UTF-8 character
+func truncateErrorMessage(message string, n int) string {
+	if len(message) <= n {
+		return message
+	}
+	for n > 0 && !utf8.RuneStart(message[n]) {
+		n--
+	}
+	return message[:n] + truncatedErrorMessageMarker
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"fmt"
+	"time"
+)
+
+// failureCoalescer suppresses the log lines of failures identical to the last one logged, within a window starting when it was logged.
+type failureCoalescer struct {
+	window      time.Duration
+	now         func() time.Time
+	last        string
+	windowStart time.Time
+	repeated    int
+}
+
+func newFailureCoalescer(window time.Duration) *failureCoalescer {
+	return &failureCoalescer{window: window, now: time.Now}
+}
+
+// observe reports whether the failure's log line should be written.
+// When failures were suppressed and the window ended, or a different failure occurred, it also returns a summary line
+// for the suppressed failures, to be written first.
+func (c *failureCoalescer) observe(errorPayload []byte) (logLine bool, summary string) {
+	now := c.now()
+	payload := string(errorPayload)
+	if payload == c.last && now.Sub(c.windowStart) < c.window {
+		c.repeated++
+		return false, ""
+	}
+	if c.repeated > 0 {
+		summary = fmt.Sprintf("%s (%d identical failures were coalesced)", c.last, c.repeated)
+	}
+	c.last, c.windowStart, c.repeated = payload, now, 0
+	return true, summary
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailureCoalescing(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	nInvokes := 5
+	ts, record := runtimeAPIServer(``, nInvokes)
+	defer ts.Close()
+	calls := 0
+	handler := NewHandlerWithOptions(func() error {
+		calls++
+		if calls == nInvokes {
+			return errors.New("table not found")
+		}
+		return errors.New("dependency is down")
+	}, WithFailureCoalescing(time.Hour))
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+
+	assert.Equal(t, nInvokes, record.nPosts)
+	for i := 0; i < nInvokes-1; i++ {
+		assert.JSONEq(t, `{"errorMessage":"dependency is down","errorType":"errorString"}`, string(record.responses[i]))
+	}
+	assert.Equal(t, 2, strings.Count(logs.String(), "dependency is down"))
+	assert.Contains(t, logs.String(), `{"errorMessage":"dependency is down","errorType":"errorString"} (3 identical failures were coalesced)`)
+	assert.Equal(t, 1, strings.Count(logs.String(), "table not found"))
+}
+
+func TestFailureCoalescerWindows(t *testing.T) {
+	now := time.Unix(0, 0)
+	coalescer := newFailureCoalescer(time.Minute)
+	coalescer.now = func() time.Time { return now }
+
+	expect := func(payload string, logLine bool, summary string) {
+		t.Helper()
+		gotLogLine, gotSummary := coalescer.observe([]byte(payload))
+		assert.Equal(t, logLine, gotLogLine)
+		assert.Equal(t, summary, gotSummary)
+	}
+
+	expect("a", true, "")
+	expect("a", false, "")
+	now = now.Add(59 * time.Second)
+	expect("a", false, "")
+
+	now = now.Add(time.Second)
+	expect("a", true, "a (2 identical failures were coalesced)")
+	expect("b", true, "")
+	expect("b", false, "")
+	expect("a", true, "b (1 identical failures were coalesced)")
+	expect("a", false, "")
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+)
+
+// FileResponseBody is a handler response streaming a file from disk, as returned by FileResponse.
+// The file is opened by the first Read, and closed by Close, which Start calls once the response is sent.
+type FileResponseBody struct {
+	path        string
+	contentType string
+	size        int64
+	file        *os.File
+	closed      bool
+}
+
+// FileResponse returns a response streaming the regular file at path, such as a static asset bundled with the function,
+// without reading it into memory. The response reports the size of the file as its ContentLength, and contentType as its ContentType,
+// defaulting to the type of the file extension, or to "application/octet-stream".
+// It returns an error when the file does not exist, or is not a regular file.
+//
+// Usage:
+//
+//	lambda.Start(func() (io.Reader, error) {
+//		return lambda.FileResponse("assets/report.pdf", "application/pdf")
+//	})
+func FileResponse(path string, contentType string) (*FileResponseBody, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(path))
+	}
+	if contentType == "" {
+		contentType = contentTypeBytes
+	}
+	return &FileResponseBody{path: path, contentType: contentType, size: info.Size()}, nil
+}
+
+// Read reads from the file, opening it on the first call.
+func (f *FileResponseBody) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.file == nil {
+		file, err := os.Open(f.path)
+		if err != nil {
+			return 0, err
+		}
+		f.file = file
+	}
+	return f.file.Read(p)
+}
+
+// Close closes the file, if it was opened.
+func (f *FileResponseBody) Close() error {
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Close()
+}
+
+// ContentLength returns the size of the file when FileResponse was called.
+func (f *FileResponseBody) ContentLength() int64 {
+	return f.size
+}
+
+// ContentType returns the content type of the file.
+func (f *FileResponseBody) ContentType() string {
+	return f.contentType
+}
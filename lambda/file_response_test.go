@@ -0,0 +1,84 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil" //nolint: staticcheck
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileResponse(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	dir, err := ioutil.TempDir("", "file-response")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "asset.bin")
+	require.NoError(t, ioutil.WriteFile(path, content, 0600))
+
+	var responses []*FileResponseBody
+	handler := func() (io.Reader, error) {
+		response, err := FileResponse(path, "application/x-asset")
+		responses = append(responses, response)
+		return response, err
+	}
+	for _, options := range [][]Option{nil, {WithStrictContentLength()}} {
+		result := NewInvokeMux(handler, options...).Invoke([]byte(`{}`), nil)
+		require.NoError(t, result.Err)
+		assert.Nil(t, result.Error)
+		assert.Equal(t, content, result.Response)
+		assert.Equal(t, "application/x-asset", result.ContentType)
+	}
+	require.Len(t, responses, 2)
+	for _, response := range responses {
+		assert.Equal(t, int64(len(content)), response.ContentLength())
+		assert.True(t, response.closed, "the file should be closed once the response is sent")
+		_, err := response.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, os.ErrClosed)
+	}
+}
+
+func TestFileResponseOpensLazily(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-response")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index.html")
+	require.NoError(t, ioutil.WriteFile(path, []byte("<html></html>"), 0600))
+
+	response, err := FileResponse(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", response.ContentType())
+	assert.Equal(t, int64(13), response.ContentLength())
+	assert.Nil(t, response.file)
+	require.NoError(t, response.Close(), "closing an unread response should not fail")
+	require.NoError(t, response.Close())
+
+	response, err = FileResponse(filepath.Join(dir, "no-extension"), "")
+	assert.Nil(t, response)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = FileResponse(dir, "")
+	assert.ErrorContains(t, err, "is not a regular file")
+}
+
+func TestFileResponseDefaultContentType(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-response")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "blob")
+	require.NoError(t, ioutil.WriteFile(path, []byte{1, 2, 3}, 0600))
+
+	response, err := FileResponse(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, "application/octet-stream", response.ContentType())
+	b, err := ioutil.ReadAll(response)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, b)
+	assert.NoError(t, response.Close())
+}
@@ -10,16 +10,36 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil" // nolint:staticcheck
+	"net/http"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/lambda/handlertrace"
+	"github.com/aws/aws-lambda-go/lambda/messages"
+	"github.com/aws/aws-lambda-go/lambda/metrics"
+	"github.com/aws/aws-lambda-go/lambdacontext"
 )
 
 type Handler interface {
 	Invoke(ctx context.Context, payload []byte) ([]byte, error)
 }
 
+// BytesHandlerFunc is a Handler receiving the payload and returning the response as bytes, without any reflection or JSON encoding by this package,
+// for handlers controlling their own marshaling. The response is sent with the application/octet-stream content type.
+//
+// Usage:
+//
+//	lambda.Start(lambda.BytesHandlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+//		return proto.Marshal(process(payload))
+//	}))
+type BytesHandlerFunc func(ctx context.Context, payload []byte) ([]byte, error)
+
+// Invoke calls f(ctx, payload).
+func (f BytesHandlerFunc) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	return f(ctx, payload)
+}
+
 type handlerOptions struct {
 	handlerFunc
 	baseContext                      context.Context
@@ -31,6 +51,50 @@ type handlerOptions struct {
 	jsonResponseIndentValue          string
 	enableSIGTERM                    bool
 	sigtermCallbacks                 []func()
+	goroutineLeakCheck               bool
+	goroutineLeakThreshold           int
+	exitPolicy                       func(*messages.InvokeResponse_Error) bool
+	errorLogLimiter                  *logRateLimiter
+	jsonResponseNilSliceAsEmptyArray bool
+	requestIDResponseHeader          string
+	fallbackEncoder                  func(v interface{}) ([]byte, error)
+	invokeRecorder                   *invokeRecorder
+	exitOnEOF                        bool
+	autoMaxProcs                     bool
+	maxErrorMessageLen               int
+	startupSelfTest                  bool
+	loopStats                        *loopStats
+	invokeStartLog                   io.Writer
+	invokesStarted                   int
+	proxyErrorMapper                 ProxyErrorMapper
+	exitCode                         func(*messages.InvokeResponse_Error) int
+	initTimeoutHandler               func()
+	failureCoalescer                 *failureCoalescer
+	heartbeat                        *heartbeat
+	betweenInvokes                   func(context.Context)
+	unmarshaler                      func(data []byte, v interface{}) error
+	marshaler                        func(v interface{}) ([]byte, error)
+	panicValueFormatter              func(v interface{}) string
+	structuredLogging                bool
+	requiredEnv                      []string
+	errorFormatter                   func(err error) *messages.InvokeResponse_Error
+	correlationHeader                string
+	streamingEncode                  bool
+	stackFilter                      func(frame messages.InvokeResponse_Error_StackFrame) bool
+	preInvoke                        func(ctx context.Context, headers http.Header) context.Context
+	skipResponseSerialization        func(ctx context.Context) bool
+	maxPayloadBytes                  int64
+	crashReporter                    func(ctx context.Context, invokeErr *messages.InvokeResponse_Error)
+	nextRetry                        retryPolicy
+	strictContentLength              bool
+	invokeTimeout                    time.Duration
+	runtimeResetStatus               int
+	envConfigPrefix                  string
+	envConfig                        interface{}
+	invokeObserver                   func(InvokeInfo)
+	perInvokeReset                   func()
+	metrics                          *metrics.Buffer
+	startErr                         error // set when the handler is invalid in a way that should stop Start from entering the invoke loop
 }
 
 type Option func(*handlerOptions)
@@ -154,6 +218,821 @@ func WithEnableSIGTERM(callbacks ...func()) Option {
 	})
 }
 
+// WithGoroutineLeakCheck logs a warning, with a dump of all goroutine stacks, when the number of goroutines after an invoke
+// has grown by more than threshold since the start of that invoke. A threshold of 0 warns on any growth.
+// Goroutines that outlive an invoke are frozen along with the execution environment, and are a common source of leaks.
+// Intended as a debugging aid, capturing the stack dump is not free.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return event, nil
+//		},
+//		lambda.WithGoroutineLeakCheck(0)
+//	)
+func WithGoroutineLeakCheck(threshold int) Option {
+	return Option(func(h *handlerOptions) {
+		h.goroutineLeakCheck = true
+		h.goroutineLeakThreshold = threshold
+	})
+}
+
+// WithExitPolicy sets the function that decides whether the process should exit after a failed invoke.
+// The policy is called with the error reported for every failed invoke, and its result replaces the error's ShouldExit field.
+// By default, the process exits only after the handler panics.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return nil, errConfigMissing
+//		},
+//		lambda.WithExitPolicy(func(err *messages.InvokeResponse_Error) bool {
+//			return err.ShouldExit || err.Type == "configError"
+//		})
+//	)
+func WithExitPolicy(policy func(err *messages.InvokeResponse_Error) bool) Option {
+	return Option(func(h *handlerOptions) {
+		h.exitPolicy = policy
+	})
+}
+
+// WithErrorLogRateLimit limits the logging of failed invokes to at most n log lines for each period of the given duration.
+// Every failure is still reported to the Lambda Runtime API, only the log line is dropped.
+// The number of dropped log lines is logged once the next period starts.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return nil, errors.New("dependency is down")
+//		},
+//		lambda.WithErrorLogRateLimit(10, time.Minute)
+//	)
+func WithErrorLogRateLimit(n int, per time.Duration) Option {
+	return Option(func(h *handlerOptions) {
+		h.errorLogLimiter = newLogRateLimiter(n, per)
+	})
+}
+
+// WithNilSliceAsEmptyArray makes nil slices in the handler's response, including those nested in structs and maps, encode as [] instead of null.
+// Values implementing json.Marshaler or encoding.TextMarshaler are encoded as-is, and []byte continues to encode as a base64 string.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func () ([]string, error) {
+//			return nil, nil // encodes as []
+//		},
+//		lambda.WithNilSliceAsEmptyArray()
+//	)
+func WithNilSliceAsEmptyArray() Option {
+	return Option(func(h *handlerOptions) {
+		h.jsonResponseNilSliceAsEmptyArray = true
+	})
+}
+
+// WithRequestIDResponseHeader adds the invoke's AwsRequestID as a header on proxy, ALB, and Function URL responses,
+// so that clients can correlate a response with the function's logs.
+// If name is empty, the header is named X-Amzn-RequestId.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+//			return events.LambdaFunctionURLResponse{StatusCode: 200}, nil
+//		},
+//		lambda.WithRequestIDResponseHeader("")
+//	)
+func WithRequestIDResponseHeader(name string) Option {
+	return Option(func(h *handlerOptions) {
+		if name == "" {
+			name = "X-Amzn-RequestId"
+		}
+		h.requestIDResponseHeader = name
+	})
+}
+
+// WithFallbackEncoder sets an encoder that is tried when the handler's response cannot be encoded to JSON.
+// The fallback is given the handler's response value, and its output is returned as the response.
+// If the fallback also fails, the invoke fails with the original JSON encoding error.
+// Response types the JSON encoder can never serialize are accepted at Start when a fallback is set.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func () (any, error) {
+//			return complex(1, 2), nil
+//		},
+//		lambda.WithFallbackEncoder(func(v any) ([]byte, error) {
+//			return []byte(fmt.Sprintf("%q", fmt.Sprint(v))), nil
+//		})
+//	)
+func WithFallbackEncoder(enc func(v interface{}) ([]byte, error)) Option {
+	return Option(func(h *handlerOptions) {
+		h.fallbackEncoder = enc
+	})
+}
+
+// WithInvokeRecorder writes the payload, response, and error of each invoke to files in dir, for replaying invokes while debugging.
+// Each invoke writes a <timestamp>-<request id>.request file holding the payload, and a .response or .error file with the same prefix.
+// The request file can be replayed by passing its contents to the handler's Invoke method.
+// Recording is disabled unless this option is used, and dir must already exist.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return event, nil
+//		},
+//		lambda.WithInvokeRecorder("/tmp/invokes")
+//	)
+func WithInvokeRecorder(dir string) Option {
+	return Option(func(h *handlerOptions) {
+		h.invokeRecorder = newInvokeRecorder(dir)
+	})
+}
+
+// WithExitOnEOF makes Start return, instead of exiting the process with an error, when the Runtime API closes the connection
+// while waiting for the next invoke. This is useful with local emulators that serve a single invoke and then shut down.
+// Lambda does not close the connection in normal operation, so this should only be used when testing.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return event, nil
+//		},
+//		lambda.WithExitOnEOF()
+//	)
+func WithExitOnEOF() Option {
+	return Option(func(h *handlerOptions) {
+		h.exitOnEOF = true
+	})
+}
+
+// WithAutoMaxProcs sets GOMAXPROCS at Start to the number of vCPUs allocated for the function's memory size,
+// which Lambda scales linearly up to 6 vCPUs at 10,240 MB. GOMAXPROCS is left unchanged if the GOMAXPROCS environment variable is set.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return event, nil
+//		},
+//		lambda.WithAutoMaxProcs()
+//	)
+func WithAutoMaxProcs() Option {
+	return Option(func(h *handlerOptions) {
+		h.autoMaxProcs = true
+	})
+}
+
+// WithMaxErrorMessageLen truncates the message of errors reported to Lambda, and logged, to at most n bytes followed by "...".
+// Stack traces are not affected.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return nil, errors.New(strings.Repeat("very long error ", 1000))
+//		},
+//		lambda.WithMaxErrorMessageLen(1024)
+//	)
+func WithMaxErrorMessageLen(n int) Option {
+	return Option(func(h *handlerOptions) {
+		h.maxErrorMessageLen = n
+	})
+}
+
+// WithStartupSelfTest checks that the Runtime API accepts connections before waiting for the first invoke.
+// If it does not, Start exits with an error naming the unreachable address, instead of blocking while waiting for an invoke.
+// This is useful to diagnose misconfigured local emulators.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return event, nil
+//		},
+//		lambda.WithStartupSelfTest()
+//	)
+func WithStartupSelfTest() Option {
+	return Option(func(h *handlerOptions) {
+		h.startupSelfTest = true
+	})
+}
+
+// withPointerMarshaler returns a pointer to a copy of v when only *T implements json.Marshaler or encoding.TextMarshaler,
+// so that the marshaler is used when the handler returns a T. encoding/json only uses pointer receiver methods on addressable values.
+func withPointerMarshaler(v interface{}) interface{} {
+	value := reflect.ValueOf(v)
+	if !value.IsValid() || value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		return v
+	}
+	valueType := value.Type()
+	if valueType.Implements(jsonMarshalerType) || valueType.Implements(textMarshalerType) {
+		return v
+	}
+	ptrType := reflect.PtrTo(valueType)
+	if !ptrType.Implements(jsonMarshalerType) && !ptrType.Implements(textMarshalerType) {
+		return v
+	}
+	ptr := reflect.New(valueType)
+	ptr.Elem().Set(value)
+	return ptr.Interface()
+}
+
+// WithLoopStats calls callback with updated LoopStats after each invoke, once its response or error has been sent.
+// The callback is called on the invoke loop's goroutine, so the next invoke is not started until it returns.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return event, nil
+//		},
+//		lambda.WithLoopStats(func(stats lambda.LoopStats) {
+//			log.Printf("served %d invokes, %d failed, average duration %s", stats.Invokes, stats.Failures, stats.AverageDuration)
+//		})
+//	)
+func WithLoopStats(callback func(LoopStats)) Option {
+	return Option(func(h *handlerOptions) {
+		h.loopStats = &loopStats{callback: callback}
+	})
+}
+
+// WithInvokeStartLog writes a JSON line to w as each invoke starts, before calling the handler.
+// The line holds the request id, whether the invoke is the first served by the process, and the milliseconds remaining until the deadline:
+//
+//	{"type":"invoke.start","requestId":"8476a536-e9f4-11e8-9739-2dfe598c3fcd","coldStart":true,"remainingTimeMs":2998}
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return event, nil
+//		},
+//		lambda.WithInvokeStartLog(os.Stdout)
+//	)
+func WithInvokeStartLog(w io.Writer) Option {
+	return Option(func(h *handlerOptions) {
+		h.invokeStartLog = w
+	})
+}
+
+// WithProxyErrorMapper makes errors returned by the handler be sent as the API Gateway proxy response returned by mapper,
+// instead of failing the invoke. When mapper is nil, DefaultProxyErrorMapper is used.
+// Panics still fail the invoke.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+//			return events.APIGatewayProxyResponse{}, lambda.NewHTTPError(http.StatusNotFound, "no such user")
+//		},
+//		lambda.WithProxyErrorMapper(nil)
+//	)
+func WithProxyErrorMapper(mapper ProxyErrorMapper) Option {
+	return Option(func(h *handlerOptions) {
+		if mapper == nil {
+			mapper = DefaultProxyErrorMapper
+		}
+		h.proxyErrorMapper = mapper
+	})
+}
+
+// WithExitCode sets the function that chooses the process exit code when a failed invoke requires the process to exit,
+// such as after the handler panics, or when the policy set with WithExitPolicy asks for it.
+// The function is called with the error reported for the invoke, after the failure is sent to the Lambda Runtime API.
+// By default, Start logs the error and exits with status 1.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			panic("oops")
+//		},
+//		lambda.WithExitCode(func(err *messages.InvokeResponse_Error) int {
+//			if err.Type == "configError" {
+//				return 78
+//			}
+//			return 2
+//		})
+//	)
+func WithExitCode(exitCode func(err *messages.InvokeResponse_Error) int) Option {
+	return Option(func(h *handlerOptions) {
+		h.exitCode = exitCode
+	})
+}
+
+// WithInitTimeoutHandler sets a function called when the function's initialization takes longer than the 10 second init timeout,
+// giving it a chance to clean up, such as flushing logs, before Lambda recycles the execution environment.
+// The Runtime API does not report init timeouts, so the init phase is approximated with the time from the start of the process
+// until the runtime first asks for an invoke. When Start is called after the timeout was already exceeded, the function is called right away.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return event, nil
+//		},
+//		lambda.WithInitTimeoutHandler(func() {
+//			log.Print("initialization timed out, the next invoke repeats it")
+//		})
+//	)
+func WithInitTimeoutHandler(handler func()) Option {
+	return Option(func(h *handlerOptions) {
+		h.initTimeoutHandler = handler
+	})
+}
+
+// WithFailureCoalescing logs a failure identical to the previous one only once per window, such as during a dependency outage.
+// The window starts when the failure is logged. The number of identical failures left out of the log is logged with the next failure logged.
+// Every failure is still reported to the Lambda Runtime API, only the log line is dropped.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return nil, errDependencyDown
+//		},
+//		lambda.WithFailureCoalescing(time.Minute)
+//	)
+func WithFailureCoalescing(window time.Duration) Option {
+	return Option(func(h *handlerOptions) {
+		h.failureCoalescer = newFailureCoalescer(window)
+	})
+}
+
+// WithHeartbeat writes a JSON line to w every interval while the handler is running, so that long invokes can be told apart from stuck ones.
+// The line holds the request id, and the milliseconds elapsed since the handler was called and remaining until the deadline:
+//
+//	{"type":"invoke.heartbeat","requestId":"8476a536-e9f4-11e8-9739-2dfe598c3fcd","elapsedMs":60000,"remainingTimeMs":839998}
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return reindex(event)
+//		},
+//		lambda.WithHeartbeat(time.Minute, os.Stdout)
+//	)
+func WithHeartbeat(interval time.Duration, w io.Writer) Option {
+	return Option(func(h *handlerOptions) {
+		h.heartbeat = newHeartbeat(interval, w)
+	})
+}
+
+// WithBetweenInvokes sets a function called after each invoke's response or error is sent, before asking for the next invoke.
+// Lambda may freeze the execution environment as soon as the next invoke is requested, so the function is the last chance
+// to do work while unfrozen, such as closing idle database connections that would otherwise be dropped by the server during the freeze.
+// The function is called with the base context, and is not called after an invoke requiring the process to exit.
+// When the Runtime API signals a reset with the status set by WithRuntimeResetStatus, the function is called one last time with the base context canceled,
+// so that pooled connections can be closed before Start exits.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (ctx context.Context, event any) (any, error) {
+//			return queryUsers(ctx, db)
+//		},
+//		lambda.WithBetweenInvokes(func(ctx context.Context) {
+//			db.SetConnMaxIdleTime(time.Second)
+//		})
+//	)
+func WithBetweenInvokes(callback func(ctx context.Context)) Option {
+	return Option(func(h *handlerOptions) {
+		h.betweenInvokes = callback
+	})
+}
+
+// WithUnmarshaler sets the function used to decode the invoke payload into the handler's event argument, instead of encoding/json.
+// It is called with the payload and a pointer to a new value of the event type. WithUseNumber and WithDisallowUnknownFields have no effect when it is set.
+// Events of type []byte or json.RawMessage are given the payload as is, without calling it.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event Order) (any, error) {
+//			return process(event)
+//		},
+//		lambda.WithUnmarshaler(jsoniter.ConfigFastest.Unmarshal)
+//	)
+func WithUnmarshaler(unmarshaler func(data []byte, v interface{}) error) Option {
+	return Option(func(h *handlerOptions) {
+		h.unmarshaler = unmarshaler
+	})
+}
+
+// WithMarshaler sets the function used to encode the handler's response, instead of encoding/json.
+// The options changing how encoding/json writes the response, such as WithSetIndent, have no effect when it is set.
+// When encoding fails, a response implementing io.Reader is still returned as is, and the encoder set with WithFallbackEncoder is still tried.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (Order, error) {
+//			return loadOrder(event)
+//		},
+//		lambda.WithMarshaler(jsoniter.ConfigFastest.Marshal)
+//	)
+func WithMarshaler(marshaler func(v interface{}) ([]byte, error)) Option {
+	return Option(func(h *handlerOptions) {
+		h.marshaler = marshaler
+	})
+}
+
+// WithPanicValueFormatter sets the function formatting the error message reported when the handler panics with a value that is not an error.
+// By default, the value is formatted with fmt's %v verb. Panics with an error value are reported with the error's message.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			panic(struct{ Code int }{Code: 42})
+//		},
+//		lambda.WithPanicValueFormatter(func(v interface{}) string {
+//			b, err := json.Marshal(v)
+//			if err != nil {
+//				return fmt.Sprintf("%v", v)
+//			}
+//			return string(b)
+//		})
+//	)
+func WithPanicValueFormatter(formatter func(v interface{}) string) Option {
+	return Option(func(h *handlerOptions) {
+		h.panicValueFormatter = formatter
+	})
+}
+
+// WithStructuredLogging logs handler failures as a single line JSON object, for the JSON log format of CloudWatch Logs,
+// instead of the default log.Printf of the error payload. The object holds the timestamp, level, requestId, errorType, errorMessage, and stackTrace.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return nil, errors.New("oops")
+//		},
+//		lambda.WithStructuredLogging(true)
+//	)
+func WithStructuredLogging(enabled bool) Option {
+	return Option(func(h *handlerOptions) {
+		h.structuredLogging = enabled
+	})
+}
+
+// WithRequiredEnv checks that each of the environment variables is set before the first invoke.
+// When some are missing, the function fails to initialize, and the init error sent to the Lambda Runtime API lists them.
+// A variable set to the empty string is not missing.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return dynamo.GetItem(os.Getenv("TABLE_NAME"), event)
+//		},
+//		lambda.WithRequiredEnv("TABLE_NAME", "QUEUE_URL")
+//	)
+func WithRequiredEnv(keys ...string) Option {
+	return Option(func(h *handlerOptions) {
+		h.requiredEnv = append(h.requiredEnv, keys...)
+	})
+}
+
+// WithErrorFormatter replaces the conversion of the errors returned by the handler to the error response sent to the Lambda Runtime API,
+// such as to redact messages, or to add a correlation id to them. Returning nil falls back to the default conversion.
+// Panics are passed to the formatter too, as the panic value when it is an error, and as the default messages.InvokeResponse_Error otherwise.
+// The process still exits after a panic, whatever the formatter returns.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return nil, fmt.Errorf("user %s not found", event)
+//		},
+//		lambda.WithErrorFormatter(func(err error) *messages.InvokeResponse_Error {
+//			return &messages.InvokeResponse_Error{Type: "InternalError", Message: "internal error"}
+//		})
+//	)
+func WithErrorFormatter(formatter func(err error) *messages.InvokeResponse_Error) Option {
+	return Option(func(h *handlerOptions) {
+		h.errorFormatter = formatter
+	})
+}
+
+// WithCorrelationHeader sets the request header the correlation id returned by lambdacontext.CorrelationID is read from.
+// The header is looked up in the headers of the invoke, then in the headers of API Gateway, ALB, and Function URL events.
+// When neither has it, the correlation id is the invoke's AwsRequestID.
+// Without the option, the payload is not read for a correlation id. With it, only the headers of events having them are decoded.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (ctx context.Context, event events.APIGatewayV2HTTPRequest) (any, error) {
+//			log.Printf("correlation id: %s", lambdacontext.CorrelationID(ctx))
+//			return nil, nil
+//		},
+//		lambda.WithCorrelationHeader("X-Request-Id")
+//	)
+func WithCorrelationHeader(name string) Option {
+	return Option(func(h *handlerOptions) {
+		// canonicalized once, rather than by each lookup in the invoke's headers
+		h.correlationHeader = http.CanonicalHeaderKey(name)
+	})
+}
+
+// WithStreamingEncode encodes the handler's response to JSON as it is sent to the Lambda Runtime API, instead of into a buffer first,
+// reducing the peak memory used by large responses. Slices and arrays are written an element at a time, and so are the fields of structs holding them;
+// maps, embedded structs, and values implementing json.Marshaler or encoding.TextMarshaler are encoded whole.
+// Errors from encoding found before the first 32KB are sent are reported as they are without the option, later ones fail the response.
+// The option has no effect on handlers returning an io.Reader, or when WithMarshaler is used.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (*Report, error) {
+//			return buildLargeReport(event)
+//		},
+//		lambda.WithStreamingEncode()
+//	)
+func WithStreamingEncode() Option {
+	return Option(func(h *handlerOptions) {
+		h.streamingEncode = true
+	})
+}
+
+// WithStackFilter sets a filter for the stack frames of panics sent to X-Ray, keeping the frames for which it returns true.
+// Use SkipRuntimeFrames to drop the frames of this package, reflect, and the Go runtime, which clutter traces.
+// The stack trace in the error response sent to the Lambda Runtime API is left unfiltered.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			panic("oops")
+//		},
+//		lambda.WithStackFilter(lambda.SkipRuntimeFrames)
+//	)
+func WithStackFilter(filter func(frame messages.InvokeResponse_Error_StackFrame) bool) Option {
+	return Option(func(h *handlerOptions) {
+		h.stackFilter = filter
+	})
+}
+
+// WithPreInvoke sets a hook deriving the context of each invoke from the headers of the Runtime API's next invocation response,
+// such as to attach a logger scoped to the request id. The hook is called before the handler, with a context already carrying the lambdacontext.LambdaContext.
+// The context it returns is passed to the handler. Returning nil leaves the context unchanged.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (ctx context.Context) (string, error) {
+//			ctx.Value(loggerKey{}).(*slog.Logger).Info("hello")
+//			return "hello", nil
+//		},
+//		lambda.WithPreInvoke(func(ctx context.Context, headers http.Header) context.Context {
+//			logger := slog.Default().With("requestId", headers.Get("Lambda-Runtime-Aws-Request-Id"))
+//			return context.WithValue(ctx, loggerKey{}, logger)
+//		})
+//	)
+func WithPreInvoke(hook func(ctx context.Context, headers http.Header) context.Context) Option {
+	return Option(func(h *handlerOptions) {
+		h.preInvoke = hook
+	})
+}
+
+// WithSkipResponseSerialization sets a predicate for invokes whose response is not serialized, such as asynchronous invokes,
+// for which the return value of the handler is ignored. When the predicate returns true, the handler still runs,
+// and its error is still reported, but its return value is not marshaled and a null response is sent instead.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event events.SQSEvent) (*Summary, error) {
+//			return process(event)
+//		},
+//		lambda.WithSkipResponseSerialization(isAsyncInvoke)
+//	)
+func WithSkipResponseSerialization(predicate func(ctx context.Context) bool) Option {
+	return Option(func(h *handlerOptions) {
+		h.skipResponseSerialization = predicate
+	})
+}
+
+// WithMaxPayloadBytes sets the largest invoke payload passed to the handler, in bytes.
+// Larger payloads fail with a Runtime.PayloadTooLarge error, without being decoded or calling the handler.
+// The default, 0, leaves payloads unlimited.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return event, nil
+//		},
+//		lambda.WithMaxPayloadBytes(256 * 1024)
+//	)
+func WithMaxPayloadBytes(n int64) Option {
+	return Option(func(h *handlerOptions) {
+		h.maxPayloadBytes = n
+	})
+}
+
+// WithCrashReporter sets a function receiving the error of each invoke that requires the process to exit, such as a panic,
+// to report the crash to a monitoring service. It is called after the error is sent to the Lambda Runtime API, and before the process exits.
+// The context is the one passed to the handler.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			panic("oops")
+//		},
+//		lambda.WithCrashReporter(func(ctx context.Context, invokeErr *messages.InvokeResponse_Error) {
+//			monitoring.CaptureMessage(ctx, invokeErr.Type+": "+invokeErr.Message)
+//		})
+//	)
+func WithCrashReporter(reporter func(ctx context.Context, invokeErr *messages.InvokeResponse_Error)) Option {
+	return Option(func(h *handlerOptions) {
+		h.crashReporter = reporter
+	})
+}
+
+// WithNextRetry sets the number of times a failed request for the next invoke to the Lambda Runtime API is retried, instead of stopping the process.
+// The first retry waits for backoff, which doubles for each further retry.
+// Only failures to connect or to read the response are retried, and errors from a canceled request are returned immediately.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return event, nil
+//		},
+//		lambda.WithNextRetry(3, 10 * time.Millisecond)
+//	)
+func WithNextRetry(retries int, backoff time.Duration) Option {
+	return Option(func(h *handlerOptions) {
+		h.nextRetry = retryPolicy{retries: retries, backoff: backoff}
+	})
+}
+
+// WithStrictContentLength fails responses implementing ContentLength() int64 when their length does not match the declared one.
+// Responses with a Len() int method, such as a *bytes.Buffer, are checked before being sent, and the mismatch is reported as the invoke's error.
+// Other responses are checked as they are sent, failing the response through its error trailer once the mismatch is found.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (ctx context.Context) (*ObjectReader, error) {
+//			return openObject(ctx)
+//		},
+//		lambda.WithStrictContentLength()
+//	)
+func WithStrictContentLength() Option {
+	return Option(func(h *handlerOptions) {
+		h.strictContentLength = true
+	})
+}
+
+// WithInvokeTimeout sets a timeout for each invoke, shorter than the function's, so that the handler's context is canceled before Lambda stops the invoke.
+// The context's deadline is the sooner of the invoke's deadline and the timeout after the invoke starts.
+// When the invoke's deadline cannot be parsed, as with some local emulators, the timeout is used instead of failing the invoke.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (ctx context.Context) (any, error) {
+//			return callDownstream(ctx)
+//		},
+//		lambda.WithInvokeTimeout(5 * time.Second)
+//	)
+func WithInvokeTimeout(d time.Duration) Option {
+	return Option(func(h *handlerOptions) {
+		h.invokeTimeout = d
+	})
+}
+
+// WithEnvConfig populates the struct pointed to by out from the environment variables starting with prefix, before the first invoke,
+// and stores out in the context passed to the handler, where lambdacontext.Config returns it.
+// Each field is read from the variable named prefix plus its `env` struct tag, defaulting to the upper-cased field name, and fields tagged "-" are skipped.
+// Values are coerced to the field's type: strings, bools, integers, floats, time.Duration, pointers to those, and comma separated slices of those.
+// Fields whose variable is not set keep their value, so defaults can be set on out beforehand, unless the tag has the "required" option.
+// When a required variable is missing, or a value cannot be coerced, the function fails to initialize, and the init error sent to the Lambda Runtime API says why.
+//
+// Usage:
+//
+//	type Config struct {
+//		TableName string        `env:"TABLE_NAME,required"`
+//		Timeout   time.Duration `env:"TIMEOUT"`
+//	}
+//	lambda.StartWithOptions(
+//		func (ctx context.Context, event any) (any, error) {
+//			cfg, _ := lambdacontext.Config(ctx)
+//			return dynamo.GetItem(cfg.(*Config).TableName, event)
+//		},
+//		lambda.WithEnvConfig("APP_", &Config{Timeout: time.Second})
+//	)
+func WithEnvConfig(prefix string, out interface{}) Option {
+	return Option(func(h *handlerOptions) {
+		h.envConfigPrefix = prefix
+		h.envConfig = out
+	})
+}
+
+// WithInvokeObserver sets a function called once after each invoke, whether it succeeded or failed, with its request id, outcome, and timing,
+// such as to emit CloudWatch embedded metric format metrics without wrapping every handler.
+// The function is called after the response or error is sent, including for invokes that failed before the handler was called, and before the process exits after a panic.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (event any) (any, error) {
+//			return process(event)
+//		},
+//		lambda.WithInvokeObserver(func(info lambda.InvokeInfo) {
+//			emitMetric("Duration", info.Duration, "Failed", info.Failed)
+//		})
+//	)
+func WithInvokeObserver(observer func(InvokeInfo)) Option {
+	return Option(func(h *handlerOptions) {
+		h.invokeObserver = observer
+	})
+}
+
+// WithPerInvokeReset sets a function called at the start of each invoke, before the handler, such as to reset package-level caches that must not outlive an invoke.
+// It runs after the previous invoke's response was sent and its WithBetweenInvokes callback returned,
+// but goroutines started by the previous invoke may still be running, and observe the reset, as nothing waits for them.
+// Work that must see the previous invoke's state should be done by the handler before it returns.
+//
+// Usage:
+//
+//	var seen = map[string]bool{}
+//
+//	lambda.StartWithOptions(
+//		func (event Event) error {
+//			return processOnce(seen, event)
+//		},
+//		lambda.WithPerInvokeReset(func() {
+//			seen = map[string]bool{}
+//		})
+//	)
+func WithPerInvokeReset(reset func()) Option {
+	return Option(func(h *handlerOptions) {
+		h.perInvokeReset = reset
+	})
+}
+
+// WithMetrics stores buffer in the context passed to the handler, where metrics.Collector returns it, and flushes it after each invoke's response or error is sent,
+// before any WithBetweenInvokes callback, so that publishing the data points recorded by the handler does not delay its response.
+// The buffer is also flushed after a handler panic, before the function exits.
+// The context passed to the publisher is canceled at the invoke's deadline, or 2 seconds into the flush when the deadline is sooner.
+// Errors returned by the buffer's publisher are logged.
+//
+// Usage:
+//
+//	buffer := metrics.NewBuffer("MyService", 0, func(ctx context.Context, namespace string, data []metrics.Datum) error {
+//		_, err := cloudwatchClient.PutMetricData(ctx, toPutMetricDataInput(namespace, data))
+//		return err
+//	})
+//	lambda.StartWithOptions(
+//		func (ctx context.Context, event any) (any, error) {
+//			metrics.Collector(ctx).Add("Processed", 1, "Count", nil)
+//			return process(event)
+//		},
+//		lambda.WithMetrics(buffer)
+//	)
+func WithMetrics(buffer *metrics.Buffer) Option {
+	return Option(func(h *handlerOptions) {
+		h.metrics = buffer
+	})
+}
+
+// WithRuntimeResetStatus treats a response to the Runtime API's /next request with the given status as a signal that the execution environment is being reset,
+// rather than as an error. The Runtime API does not define such a status, so this is for custom Runtime API implementations, such as emulators, that do.
+// On a reset, the base context passed to invokes is canceled, WithBetweenInvokes callbacks are called one last time with it, so that pooled connections can be closed,
+// and Start returns without logging an error.
+//
+// Usage:
+//
+//	lambda.StartWithOptions(
+//		func (ctx context.Context, event any) (any, error) {
+//			return queryUsers(ctx, db)
+//		},
+//		lambda.WithRuntimeResetStatus(http.StatusResetContent),
+//		lambda.WithBetweenInvokes(func(ctx context.Context) {
+//			if ctx.Err() != nil {
+//				db.Close()
+//			}
+//		})
+//	)
+func WithRuntimeResetStatus(status int) Option {
+	return Option(func(h *handlerOptions) {
+		h.runtimeResetStatus = status
+	})
+}
+
 // handlerTakesContext returns whether the handler takes a context.Context as its first argument.
 func handlerTakesContext(handler reflect.Type) (bool, error) {
 	switch handler.NumIn() {
@@ -182,16 +1061,37 @@ func handlerTakesContext(handler reflect.Type) (bool, error) {
 			return false, fmt.Errorf("handler takes two arguments, but the first is not Context. got %s", argumentType.Kind())
 		}
 		return true, nil
+	case 3:
+		contextType := reflect.TypeOf((*context.Context)(nil)).Elem()
+		argumentType := handler.In(0)
+		if argumentType.Kind() != reflect.Interface || !contextType.Implements(argumentType) || !argumentType.Implements(contextType) {
+			return false, fmt.Errorf("handler takes three arguments, but the first is not Context. got %s", argumentType.Kind())
+		}
+		if rawType := handler.In(1); rawType != bytesType && rawType != rawMessageType {
+			return false, fmt.Errorf("handler takes three arguments, but the second is not []byte or json.RawMessage. got %s", rawType)
+		}
+		// the raw payload is already passed as the second argument, so a third raw argument is more likely a mistake than a decoded event
+		if eventType := handler.In(2); eventType == bytesType || eventType == rawMessageType {
+			return false, fmt.Errorf("handler takes three arguments, but the third is %s, the same raw payload as the second. use a two argument handler instead", eventType)
+		}
+		return true, nil
 	}
-	return false, fmt.Errorf("handlers may not take more than two arguments, but handler takes %d", handler.NumIn())
+	return false, fmt.Errorf("handlers may not take more than three arguments, but handler takes %d", handler.NumIn())
 }
 
 func validateReturns(handler reflect.Type) error {
 	errorType := reflect.TypeOf((*error)(nil)).Elem()
 
 	switch n := handler.NumOut(); {
-	case n > 2:
-		return fmt.Errorf("handler may not return more than two values")
+	case n > 3:
+		return fmt.Errorf("handler may not return more than three values")
+	case n == 3:
+		if !handler.Out(2).Implements(errorType) {
+			return fmt.Errorf("handler returns three values, but the third does not implement error")
+		}
+		if !isResponseMetadataType(handler.Out(1)) {
+			return fmt.Errorf("handler returns three values, but the second is not http.Header, *http.Header, or a type with a ContentType() string, Headers() http.Header, or StatusCode() int method. got %s", handler.Out(1))
+		}
 	case n > 1:
 		if !handler.Out(1).Implements(errorType) {
 			return fmt.Errorf("handler returns two values, but the second does not implement error")
@@ -240,6 +1140,9 @@ func newHandler(handlerFunc interface{}, options ...Option) *handlerOptions {
 	for k, v := range h.contextValues {
 		h.baseContext = context.WithValue(h.baseContext, k, v)
 	}
+	if h.metrics != nil {
+		h.baseContext = metrics.NewContext(h.baseContext, h.metrics)
+	}
 	if h.enableSIGTERM {
 		enableSIGTERM(h.sigtermCallbacks)
 	}
@@ -253,6 +1156,9 @@ type handlerFunc func(context.Context, []byte) (io.Reader, error)
 func (h handlerFunc) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
 	response, err := h(ctx, payload)
 	if err != nil {
+		if response, ok := response.(io.Closer); ok {
+			_ = response.Close()
+		}
 		return nil, err
 	}
 	// if the response needs to be closed (ex: net.Conn, os.File), ensure it's closed before the next invoke to prevent a resource leak
@@ -273,6 +1179,15 @@ func (h handlerFunc) Invoke(ctx context.Context, payload []byte) ([]byte, error)
 	return b, nil
 }
 
+// partialResponse returns the first value returned by a handler along with an error, when it is a non-nil io.Reader
+func partialResponse(response []reflect.Value) io.Reader {
+	if len(response) < 2 || isNilValue(response[0]) {
+		return nil
+	}
+	reader, _ := response[0].Interface().(io.Reader)
+	return reader
+}
+
 func errorHandler(err error) handlerFunc {
 	return func(_ context.Context, _ []byte) (io.Reader, error) {
 		return nil, err
@@ -287,6 +1202,11 @@ func (j *jsonOutBuffer) ContentType() string {
 	return contentTypeJSON
 }
 
+var (
+	bytesType      = reflect.TypeOf([]byte(nil))
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
 func reflectHandler(f interface{}, h *handlerOptions) handlerFunc {
 	if f == nil {
 		return errorHandler(errors.New("handler is nil"))
@@ -309,6 +1229,10 @@ func reflectHandler(f interface{}, h *handlerOptions) handlerFunc {
 		return errorHandler(fmt.Errorf("handler kind %s is not %s", handlerType.Kind(), reflect.Func))
 	}
 
+	if n := handlerType.NumIn(); n > 1 && handlerType.In(n-1) == responseWriterType {
+		return streamingHandler(handler, handlerType, h)
+	}
+
 	takesContext, err := handlerTakesContext(handlerType)
 	if err != nil {
 		return errorHandler(err)
@@ -318,8 +1242,15 @@ func reflectHandler(f interface{}, h *handlerOptions) handlerFunc {
 		return errorHandler(err)
 	}
 
+	if handlerType.NumOut() > 1 && h.fallbackEncoder == nil && h.marshaler == nil {
+		if err := validateSerializable(handlerType.Out(0)); err != nil {
+			h.startErr = err
+			return errorHandler(err)
+		}
+	}
+
 	out := &jsonOutBuffer{bytes.NewBuffer(nil)}
-	return func(ctx context.Context, payload []byte) (io.Reader, error) {
+	return func(ctx context.Context, payload []byte) (reader io.Reader, err error) {
 		out.Reset()
 		in := bytes.NewBuffer(payload)
 		decoder := json.NewDecoder(in)
@@ -332,6 +1263,17 @@ func reflectHandler(f interface{}, h *handlerOptions) handlerFunc {
 		encoder := json.NewEncoder(out)
 		encoder.SetEscapeHTML(h.jsonResponseEscapeHTML)
 		encoder.SetIndent(h.jsonResponseIndentPrefix, h.jsonResponseIndentValue)
+		encode := func(v interface{}) error {
+			if h.marshaler == nil {
+				return encoder.Encode(v)
+			}
+			b, err := h.marshaler(v)
+			if err != nil {
+				return err
+			}
+			_, _ = out.Write(b)
+			return nil
+		}
 
 		trace := handlertrace.FromContext(ctx)
 
@@ -340,10 +1282,20 @@ func reflectHandler(f interface{}, h *handlerOptions) handlerFunc {
 		if takesContext {
 			args = append(args, reflect.ValueOf(ctx))
 		}
-		if (handlerType.NumIn() == 1 && !takesContext) || handlerType.NumIn() == 2 {
+		// handlers taking three arguments receive the payload as it was sent, before the decoded event
+		if handlerType.NumIn() == 3 {
+			args = append(args, reflect.ValueOf(append([]byte(nil), payload...)).Convert(handlerType.In(1)))
+		}
+		if (handlerType.NumIn() == 1 && !takesContext) || handlerType.NumIn() >= 2 {
 			eventType := handlerType.In(handlerType.NumIn() - 1)
 			event := reflect.New(eventType)
-			if err := decoder.Decode(event.Interface()); err != nil {
+			if h.unmarshaler == nil {
+				if err := decoder.Decode(event.Interface()); err != nil {
+					return nil, err
+				}
+			} else if eventType == bytesType || eventType == rawMessageType {
+				event.Elem().SetBytes(append([]byte(nil), payload...))
+			} else if err := h.unmarshaler(payload, event.Interface()); err != nil {
 				return nil, err
 			}
 			if nil != trace.RequestEvent {
@@ -353,28 +1305,86 @@ func reflectHandler(f interface{}, h *handlerOptions) handlerFunc {
 		}
 
 		response := handler.Call(args)
+		// for handlers returning (TOut, metadata, error), the metadata is applied to the response however it was encoded,
+		// unless it was merged into a proxy response
+		metadataMerged := false
+		if len(response) == 3 {
+			defer func() {
+				if err == nil && !metadataMerged {
+					reader = withResponseMetadata(reader, response[1])
+				}
+			}()
+		}
 
-		// return the error, if any
+		// return the error, if any, unless it is mapped to a proxy response
+		var val interface{}
+		mappedError := false
 		if len(response) > 0 {
 			if errVal, ok := response[len(response)-1].Interface().(error); ok && errVal != nil {
-				return nil, errVal
+				// a response returned along with the error is passed on, so that it can be closed
+				partial := partialResponse(response)
+				if h.proxyErrorMapper == nil {
+					return partial, errVal
+				}
+				if closer, ok := partial.(io.Closer); ok {
+					_ = closer.Close()
+				}
+				val, mappedError = h.proxyErrorMapper(errVal), true
 			}
 		}
 		// set the response value, if any
-		var val interface{}
-		if len(response) > 1 {
-			val = response[0].Interface()
+		if len(response) > 1 || mappedError {
+			if !mappedError {
+				val = response[0].Interface()
+			}
+			if len(response) == 3 {
+				val, metadataMerged = mergeResponseMetadata(val, response[1])
+			}
+			if h.requestIDResponseHeader != "" {
+				if lc, ok := lambdacontext.FromContext(ctx); ok {
+					val = withResponseHeader(val, h.requestIDResponseHeader, lc.AwsRequestID)
+				}
+			}
 			if nil != trace.ResponseEvent {
 				trace.ResponseEvent(ctx, val)
 			}
 		}
 
+		if h.skipResponseSerialization != nil && h.skipResponseSerialization(ctx) {
+			_, _ = out.WriteString("null")
+			return out, nil
+		}
+
 		// encode to JSON
-		if err := encoder.Encode(val); err != nil {
+		encodeVal := val
+		if h.jsonResponseNilSliceAsEmptyArray {
+			encodeVal = nilSlicesAsEmpty(val)
+		}
+		encodeVal = withPointerMarshaler(encodeVal)
+		if h.streamingEncode && h.marshaler == nil {
+			if _, ok := val.(io.Reader); !ok {
+				contentType, ok := taggedContentType(val)
+				if !ok {
+					contentType = contentTypeJSON
+				}
+				// on errors, encode again below, so that they are handled like those of the buffered encoding
+				if reader, err := streamJSON(encodeVal, h, contentType); err == nil {
+					return reader, nil
+				}
+			}
+		}
+		if err := encode(encodeVal); err != nil {
 			// if response is not JSON serializable, but the response type is a reader, return it as-is
 			if reader, ok := val.(io.Reader); ok {
 				return reader, nil
 			}
+			if h.fallbackEncoder != nil {
+				if b, fallbackErr := h.fallbackEncoder(val); fallbackErr == nil {
+					out.Reset()
+					_, _ = out.Write(b)
+					return out, nil
+				}
+			}
 			return nil, err
 		}
 
@@ -387,9 +1397,12 @@ func reflectHandler(f interface{}, h *handlerOptions) handlerFunc {
 		}
 
 		// back-compat, strip the encoder's trailing newline unless WithSetIndent was used
-		if h.jsonResponseIndentValue == "" && h.jsonResponseIndentPrefix == "" {
+		if h.marshaler == nil && h.jsonResponseIndentValue == "" && h.jsonResponseIndentPrefix == "" {
 			out.Truncate(out.Len() - 1)
 		}
+		if contentType, ok := taggedContentType(val); ok {
+			return &contentTypeBuffer{out.Buffer, contentType}, nil
+		}
 		return out, nil
 	}
 }
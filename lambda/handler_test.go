@@ -5,10 +5,12 @@ package lambda
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil" //nolint: staticcheck
+	"net/http"
 	"reflect"
 	"strings"
 	"testing"
@@ -54,11 +56,32 @@ func TestInvalidHandlers(t *testing.T) {
 		},
 		{
 			name:     "handler declares too many arguments",
-			expected: errors.New("handlers may not take more than two arguments, but handler takes 3"),
+			expected: errors.New("handlers may not take more than three arguments, but handler takes 4"),
+			handler: func(n context.Context, raw []byte, x string, y string) error {
+				return nil
+			},
+		},
+		{
+			name:     "three argument handler does not take the raw payload as second argument",
+			expected: errors.New("handler takes three arguments, but the second is not []byte or json.RawMessage. got string"),
 			handler: func(n context.Context, x string, y string) error {
 				return nil
 			},
 		},
+		{
+			name:     "three argument handler does not take context as first argument",
+			expected: errors.New("handler takes three arguments, but the first is not Context. got slice"),
+			handler: func(raw []byte, n context.Context, y string) error {
+				return nil
+			},
+		},
+		{
+			name:     "three argument handler takes the raw payload twice",
+			expected: errors.New("handler takes three arguments, but the third is json.RawMessage, the same raw payload as the second. use a two argument handler instead"),
+			handler: func(n context.Context, raw []byte, y json.RawMessage) error {
+				return nil
+			},
+		},
 		{
 			name:     "two argument handler does not context as first argument",
 			expected: errors.New("handler takes two arguments, but the first is not Context. got string"),
@@ -68,11 +91,32 @@ func TestInvalidHandlers(t *testing.T) {
 		},
 		{
 			name:     "handler returns too many values",
-			expected: errors.New("handler may not return more than two values"),
+			expected: errors.New("handler may not return more than three values"),
+			handler: func() (string, http.Header, error, error) {
+				return "", nil, nil, nil
+			},
+		},
+		{
+			name:     "handler returning three values does not declare response metadata as the second return value",
+			expected: errors.New("handler returns three values, but the second is not http.Header, *http.Header, or a type with a ContentType() string, Headers() http.Header, or StatusCode() int method. got error"),
 			handler: func() (error, error, error) {
 				return nil, nil, nil
 			},
 		},
+		{
+			name:     "handler returning three values does not declare error as the third return value",
+			expected: errors.New("handler returns three values, but the third does not implement error"),
+			handler: func() (string, http.Header, string) {
+				return "", nil, ""
+			},
+		},
+		{
+			name:     "handler returning three values with headers should not result in error",
+			expected: nil,
+			handler: func() (string, *http.Header, error) {
+				return "", nil, nil
+			},
+		},
 		{
 			name:     "handler returning two values does not declare error as the second return value",
 			expected: errors.New("handler returns two values, but the second does not implement error"),
@@ -140,6 +184,82 @@ func TestInvalidHandlers(t *testing.T) {
 			handler: func(ctx customContext, v interface{}) {
 			},
 		},
+		{
+			name:     "the handler returns a struct with a chan field",
+			expected: errors.New("handler returns type struct { Name string; Updates chan string }, which cannot be serialized to JSON: Updates has unsupported kind chan"),
+			handler: func() (struct {
+				Name    string
+				Updates chan string
+			}, error) {
+				return struct {
+					Name    string
+					Updates chan string
+				}{}, nil
+			},
+		},
+		{
+			name:     "the handler returns a func",
+			expected: errors.New("handler returns type func(), which cannot be serialized to JSON: func is not supported"),
+			handler: func() (func(), error) {
+				return nil, nil
+			},
+		},
+		{
+			name:     "the handler returns a nested unsupported field",
+			expected: errors.New("handler returns type *lambda.serializableOuter, which cannot be serialized to JSON: Inner[].Callback has unsupported kind func"),
+			handler: func() (*serializableOuter, error) {
+				return nil, nil
+			},
+		},
+		{
+			name:     "the handler returns a map with unsupported keys",
+			expected: errors.New("handler returns type map[[2]int]string, which cannot be serialized to JSON: key has unsupported kind array"),
+			handler: func() (map[[2]int]string, error) {
+				return nil, nil
+			},
+		},
+		{
+			name:     "streaming handler returns a value",
+			expected: errors.New("streaming handlers must return only an error"),
+			handler: func(context.Context, ResponseWriter) (string, error) {
+				return "", nil
+			},
+		},
+		{
+			name:     "streaming handler does not take a context",
+			expected: errors.New("streaming handlers must take a context.Context as the first argument, got string"),
+			handler: func(string, ResponseWriter) error {
+				return nil
+			},
+		},
+		{
+			name:     "streaming handler takes too many arguments",
+			expected: errors.New("streaming handlers must take a context.Context, an optional event, and a lambda.ResponseWriter, but handler takes 4 arguments"),
+			handler: func(context.Context, string, string, ResponseWriter) error {
+				return nil
+			},
+		},
+		{
+			name:     "the handler returns unsupported fields that are skipped, marshaled, or read",
+			expected: nil,
+			handler: func() (struct {
+				Skipped  chan string `json:"-"`
+				private  chan string
+				Marshals arbitraryJSONWithChan
+				Self     *serializableSelf
+				Reader   io.Reader
+				Any      interface{}
+			}, error) {
+				return struct {
+					Skipped  chan string `json:"-"`
+					private  chan string
+					Marshals arbitraryJSONWithChan
+					Self     *serializableSelf
+					Reader   io.Reader
+					Any      interface{}
+				}{Marshals: arbitraryJSONWithChan{arbitraryJSON: arbitraryJSON{json: []byte(`{}`)}}}, nil
+			},
+		},
 	}
 	for i, testCase := range testCases {
 		testCase := testCase
@@ -151,6 +271,22 @@ func TestInvalidHandlers(t *testing.T) {
 	}
 }
 
+type serializableOuter struct {
+	Inner []struct {
+		Callback func() `json:"callback"`
+	}
+}
+
+type serializableSelf struct {
+	Name string
+	Next *serializableSelf
+}
+
+type arbitraryJSONWithChan struct {
+	arbitraryJSON
+	Ch chan string
+}
+
 type arbitraryJSON struct {
 	json []byte
 	err  error
@@ -358,6 +494,243 @@ func TestInvokes(t *testing.T) {
 			handler:  func(_ struct{}) {},
 			options:  []Option{},
 		},
+		{
+			name:     "nil slices encode as null by default",
+			expected: expected{`null`, nil},
+			handler: func() ([]string, error) {
+				return nil, nil
+			},
+		},
+		{
+			name:     "WithNilSliceAsEmptyArray() encodes nil slices as empty arrays",
+			expected: expected{`[]`, nil},
+			handler: func() ([]string, error) {
+				return nil, nil
+			},
+			options: []Option{WithNilSliceAsEmptyArray()},
+		},
+		{
+			name:     "WithNilSliceAsEmptyArray() leaves empty and populated slices unchanged",
+			expected: expected{`{"Empty":[],"Populated":["a"]}`, nil},
+			handler: func() (interface{}, error) {
+				return struct {
+					Empty     []string
+					Populated []string
+				}{[]string{}, []string{"a"}}, nil
+			},
+			options: []Option{WithNilSliceAsEmptyArray()},
+		},
+		{
+			name:     "WithNilSliceAsEmptyArray() encodes nested nil slices as empty arrays",
+			expected: expected{`{"Items":[],"Nested":{"Tags":[]},"Ptr":{"Tags":[]},"Map":{"k":[]},"List":[[],["x"]],"Bytes":null,"Nil":null}`, nil},
+			handler: func() (interface{}, error) {
+				type nested struct{ Tags []string }
+				return &struct {
+					Items  []int
+					Nested nested
+					Ptr    *nested
+					Map    map[string][]string
+					List   [][]string
+					Bytes  []byte
+					Nil    *nested
+				}{
+					Ptr:  &nested{},
+					Map:  map[string][]string{"k": nil},
+					List: [][]string{nil, {"x"}},
+				}, nil
+			},
+			options: []Option{WithNilSliceAsEmptyArray()},
+		},
+		{
+			name:     "WithNilSliceAsEmptyArray() preserves custom marshalers",
+			expected: expected{`{"Custom":"custom"}`, nil},
+			handler: func() (interface{}, error) {
+				return struct{ Custom customSliceMarshaler }{}, nil
+			},
+			options: []Option{WithNilSliceAsEmptyArray()},
+		},
+		{
+			name:     "WithFallbackEncoder() is used when JSON encoding fails",
+			expected: expected{`(1+2i)`, nil},
+			handler: func() (interface{}, error) {
+				return complex(1, 2), nil
+			},
+			options: []Option{WithFallbackEncoder(func(v interface{}) ([]byte, error) {
+				return []byte(fmt.Sprint(v)), nil
+			})},
+		},
+		{
+			name:     "WithFallbackEncoder() accepts response types JSON cannot encode",
+			expected: expected{`fallback`, nil},
+			handler: func() (chan int, error) {
+				return nil, nil
+			},
+			options: []Option{WithFallbackEncoder(func(v interface{}) ([]byte, error) {
+				return []byte("fallback"), nil
+			})},
+		},
+		{
+			name:     "WithFallbackEncoder() is not used when JSON encoding succeeds",
+			expected: expected{`"hello"`, nil},
+			handler: func() (string, error) {
+				return "hello", nil
+			},
+			options: []Option{WithFallbackEncoder(func(v interface{}) ([]byte, error) {
+				return nil, errors.New("unexpected fallback")
+			})},
+		},
+		{
+			name:     "WithFallbackEncoder() failure returns the JSON encoding error",
+			expected: expected{"", errors.New("json: unsupported type: complex128")},
+			handler: func() (interface{}, error) {
+				return complex(1, 2), nil
+			},
+			options: []Option{WithFallbackEncoder(func(v interface{}) ([]byte, error) {
+				return nil, errors.New("fallback failed too")
+			})},
+		},
+		{
+			name:     "WithUnmarshaler() decodes payloads encoding/json rejects",
+			input:    `not json`,
+			expected: expected{`"NOT JSON"`, nil},
+			handler: func(event string) (string, error) {
+				return event, nil
+			},
+			options: []Option{WithUnmarshaler(func(data []byte, v interface{}) error {
+				*(v.(*string)) = strings.ToUpper(string(data))
+				return nil
+			})},
+		},
+		{
+			name:     "WithUnmarshaler() error is returned",
+			input:    `{}`,
+			expected: expected{"", errors.New("cannot decode")},
+			handler: func(event map[string]interface{}) error {
+				return nil
+			},
+			options: []Option{WithUnmarshaler(func(data []byte, v interface{}) error {
+				return errors.New("cannot decode")
+			})},
+		},
+		{
+			name:     "WithUnmarshaler() is bypassed for json.RawMessage",
+			input:    `{"a": 1}`,
+			expected: expected{`"{\"a\": 1}"`, nil},
+			handler: func(event json.RawMessage) (string, error) {
+				return string(event), nil
+			},
+			options: []Option{WithUnmarshaler(func(data []byte, v interface{}) error {
+				return errors.New("unexpected unmarshaler call")
+			})},
+		},
+		{
+			name:     "WithUnmarshaler() is bypassed for []byte",
+			input:    `raw bytes`,
+			expected: expected{`"raw bytes"`, nil},
+			handler: func(ctx context.Context, event []byte) (string, error) {
+				return string(event), nil
+			},
+			options: []Option{WithUnmarshaler(func(data []byte, v interface{}) error {
+				return errors.New("unexpected unmarshaler call")
+			})},
+		},
+		{
+			name:     "WithMarshaler() encodes the response",
+			expected: expected{`name=gopher`, nil},
+			handler: func() (map[string]string, error) {
+				return map[string]string{"name": "gopher"}, nil
+			},
+			options: []Option{WithSetIndent("", "  "), WithMarshaler(func(v interface{}) ([]byte, error) {
+				return []byte("name=" + v.(map[string]string)["name"]), nil
+			})},
+		},
+		{
+			name:     "WithMarshaler() accepts response types JSON cannot encode",
+			expected: expected{`a channel`, nil},
+			handler: func() (chan int, error) {
+				return nil, nil
+			},
+			options: []Option{WithMarshaler(func(v interface{}) ([]byte, error) {
+				return []byte("a channel"), nil
+			})},
+		},
+		{
+			name:     "WithMarshaler() error is returned",
+			expected: expected{"", errors.New("cannot encode")},
+			handler: func() (string, error) {
+				return "hello", nil
+			},
+			options: []Option{WithMarshaler(func(v interface{}) ([]byte, error) {
+				return nil, errors.New("cannot encode")
+			})},
+		},
+		{
+			name:     "WithStreamingEncode() encodes the response",
+			expected: expected{`{"name":"<gopher>"}`, nil},
+			handler: func() (map[string]string, error) {
+				return map[string]string{"name": "<gopher>"}, nil
+			},
+			options: []Option{WithStreamingEncode()},
+		},
+		{
+			name:     "WithStreamingEncode() keeps WithSetIndent and WithSetEscapeHTML",
+			expected: expected{"{\n  \"name\": \"\\u003cgopher\\u003e\"\n}\n", nil},
+			handler: func() (map[string]string, error) {
+				return map[string]string{"name": "<gopher>"}, nil
+			},
+			options: []Option{WithStreamingEncode(), WithSetIndent("", "  "), WithSetEscapeHTML(true)},
+		},
+		{
+			name:     "WithStreamingEncode() returns encoding errors",
+			expected: expected{"", errors.New("json: unsupported type: chan int")},
+			handler: func() (interface{}, error) {
+				return make(chan int), nil
+			},
+			options: []Option{WithStreamingEncode()},
+		},
+		{
+			name:     "WithStreamingEncode() keeps io.Reader responses passthrough",
+			expected: expected{"raw", nil},
+			handler: func() (io.Reader, error) {
+				return strings.NewReader("raw"), nil
+			},
+			options: []Option{WithStreamingEncode()},
+		},
+		{
+			name:     "pointer receiver MarshalJSON is used for values",
+			expected: expected{`"custom pointer gopher"`, nil},
+			handler: func() (pointerMarshaler, error) {
+				return pointerMarshaler{Name: "gopher"}, nil
+			},
+		},
+		{
+			name:     "pointer receiver MarshalJSON is used for pointers",
+			expected: expected{`"custom pointer gopher"`, nil},
+			handler: func() (*pointerMarshaler, error) {
+				return &pointerMarshaler{Name: "gopher"}, nil
+			},
+		},
+		{
+			name:     "pointer receiver MarshalJSON is used for values returned as interface{}",
+			expected: expected{`"custom pointer gopher"`, nil},
+			handler: func() (interface{}, error) {
+				return pointerMarshaler{Name: "gopher"}, nil
+			},
+		},
+		{
+			name:     "pointer receiver MarshalText is used for values",
+			expected: expected{`"text gopher"`, nil},
+			handler: func() (pointerTextMarshaler, error) {
+				return pointerTextMarshaler{Name: "gopher"}, nil
+			},
+		},
+		{
+			name:     "nested pointer receiver MarshalJSON follows encoding/json",
+			expected: expected{`{"Inner":{"Name":"gopher"}}`, nil},
+			handler: func() (struct{ Inner pointerMarshaler }, error) {
+				return struct{ Inner pointerMarshaler }{pointerMarshaler{Name: "gopher"}}, nil
+			},
+		},
 		{
 			name:     "bytes are base64 encoded strings",
 			input:    `"aGVsbG8="`,
@@ -453,6 +826,40 @@ func TestInvokes(t *testing.T) {
 	}
 }
 
+type pointerMarshaler struct {
+	Name string
+}
+
+func (p *pointerMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`"custom pointer ` + p.Name + `"`), nil
+}
+
+type pointerTextMarshaler struct {
+	Name string
+}
+
+func (p *pointerTextMarshaler) MarshalText() ([]byte, error) {
+	return []byte("text " + p.Name), nil
+}
+
+type customSliceMarshaler []string
+
+func (customSliceMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`"custom"`), nil
+}
+
+func TestNilSlicesAsEmptyPreservesUnexportedFields(t *testing.T) {
+	type withUnexported struct {
+		Items  []string
+		hidden string
+	}
+	original := withUnexported{hidden: "secret"}
+	replaced := nilSlicesAsEmpty(original).(withUnexported)
+	assert.Equal(t, "secret", replaced.hidden)
+	assert.NotNil(t, replaced.Items)
+	assert.Nil(t, original.Items)
+}
+
 func TestInvalidJsonInput(t *testing.T) {
 	lambdaHandler := NewHandler(func(s string) error { return nil })
 	_, err := lambdaHandler.Invoke(context.TODO(), []byte(`{"invalid json`))
@@ -519,3 +926,112 @@ func TestHandlerTrace(t *testing.T) {
 		t.Error("response callbacks not called as expected", responseHistory)
 	}
 }
+
+type countingMarshaler struct {
+	calls *int
+}
+
+func (m countingMarshaler) MarshalJSON() ([]byte, error) {
+	*m.calls++
+	return []byte(`"marshaled"`), nil
+}
+
+func TestSkipResponseSerialization(t *testing.T) {
+	type skipKey struct{}
+	calls, runs := 0, 0
+	handler := NewHandlerWithOptions(
+		func(ctx context.Context, fail bool) (countingMarshaler, error) {
+			runs++
+			if fail {
+				return countingMarshaler{&calls}, errors.New("failed")
+			}
+			return countingMarshaler{&calls}, nil
+		},
+		WithSkipResponseSerialization(func(ctx context.Context) bool {
+			return ctx.Value(skipKey{}) != nil
+		}),
+	)
+	skipped := context.WithValue(context.Background(), skipKey{}, true)
+
+	response, err := handler.Invoke(skipped, []byte(`false`))
+	require.NoError(t, err)
+	assert.Equal(t, "null", string(response))
+	assert.Equal(t, 1, runs)
+	assert.Equal(t, 0, calls)
+
+	_, err = handler.Invoke(skipped, []byte(`true`))
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, 2, runs)
+
+	response, err = handler.Invoke(context.Background(), []byte(`false`))
+	require.NoError(t, err)
+	assert.Equal(t, `"marshaled"`, string(response))
+	assert.Equal(t, 1, calls)
+}
+
+func TestRawPayloadWithEvent(t *testing.T) {
+	type webhook struct {
+		ID     string `json:"id"`
+		Amount int    `json:"amount"`
+	}
+	payload := []byte("{ \"amount\": 42,\n  \"id\": \"evt_1\", \"extra\": [1, 2] }\n")
+	var raws [][]byte
+	handler := func(ctx context.Context, raw json.RawMessage, event webhook) (webhook, error) {
+		raws = append(raws, raw)
+		return event, nil
+	}
+	for _, options := range [][]Option{nil, {WithUnmarshaler(json.Unmarshal)}} {
+		response, err := NewHandlerWithOptions(handler, options...).Invoke(context.Background(), payload)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id": "evt_1", "amount": 42}`, string(response))
+	}
+	require.Len(t, raws, 2)
+	for _, raw := range raws {
+		assert.Equal(t, payload, []byte(raw))
+	}
+
+	raws[0][0] = 'X'
+	assert.Equal(t, byte('{'), payload[0], "the raw payload passed to the handler should be a copy")
+
+	var raw []byte
+	response, err := NewHandler(func(ctx context.Context, b []byte, event *webhook) error {
+		raw = b
+		return nil
+	}).Invoke(context.Background(), payload)
+	require.NoError(t, err)
+	assert.Equal(t, "null", string(response))
+	assert.Equal(t, payload, raw)
+
+	_, err = NewHandler(handler).Invoke(context.Background(), []byte(`{"id": 1}`))
+	assert.Error(t, err, "the event should still be decoded")
+}
+
+func TestBytesHandlerFunc(t *testing.T) {
+	payload := "\x00\xffnot json\n"
+	ts, record := runtimeAPIServer(payload, 1)
+	defer ts.Close()
+
+	var received []byte
+	handler := BytesHandlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+		received = append([]byte(nil), payload...)
+		return payload, nil
+	})
+	var _ Handler = handler
+	err := startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.Error(t, err, "the loop should have run until the test server ended it")
+	assert.Equal(t, []byte(payload), received)
+	require.Equal(t, 1, record.nPosts)
+	assert.Equal(t, []byte(payload), record.responses[0])
+	assert.Equal(t, contentTypeBytes, record.contentTypes[0])
+
+	_, err = BytesHandlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+		return nil, errors.New("failed")
+	}).Invoke(context.Background(), nil)
+	assert.EqualError(t, err, "failed")
+}
+
+func ExampleBytesHandlerFunc() {
+	StartWithOptions(BytesHandlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+		return bytes.ToUpper(payload), nil
+	}))
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// heartbeat writes a JSON line to w every interval while a handler is running.
+type heartbeat struct {
+	interval  time.Duration
+	w         io.Writer
+	now       func() time.Time
+	newTicker func(d time.Duration) (ticks <-chan time.Time, stop func())
+}
+
+type heartbeatLine struct {
+	Type            string `json:"type"`
+	RequestID       string `json:"requestId"`
+	ElapsedMS       int64  `json:"elapsedMs"`
+	RemainingTimeMS int64  `json:"remainingTimeMs"`
+}
+
+func newHeartbeat(interval time.Duration, w io.Writer) *heartbeat {
+	return &heartbeat{
+		interval: interval,
+		w:        w,
+		now:      time.Now,
+		newTicker: func(d time.Duration) (<-chan time.Time, func()) {
+			ticker := time.NewTicker(d)
+			return ticker.C, ticker.Stop
+		},
+	}
+}
+
+// start starts writing heartbeats for the invoke. The returned function stops them, and returns once no more are written.
+func (h *heartbeat) start(requestID string, deadline time.Time) (stop func()) {
+	started := h.now()
+	ticks, stopTicker := h.newTicker(h.interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticks:
+				line, _ := json.Marshal(heartbeatLine{
+					Type:            "invoke.heartbeat",
+					RequestID:       requestID,
+					ElapsedMS:       int64(now.Sub(started) / time.Millisecond),
+					RemainingTimeMS: int64(deadline.Sub(now) / time.Millisecond),
+				})
+				_, _ = h.w.Write(append(line, '\n'))
+			}
+		}
+	}()
+	return func() {
+		stopTicker()
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeatCadence(t *testing.T) {
+	var out bytes.Buffer
+	now := time.Unix(1000, 0)
+	ticks := make(chan time.Time)
+	var tickerInterval time.Duration
+	tickerStopped := false
+	h := newHeartbeat(30*time.Second, &out)
+	h.now = func() time.Time { return now }
+	h.newTicker = func(d time.Duration) (<-chan time.Time, func()) {
+		tickerInterval = d
+		return ticks, func() { tickerStopped = true }
+	}
+
+	stop := h.start("dummyid", now.Add(2*time.Minute))
+	for i := 0; i < 3; i++ {
+		now = now.Add(30 * time.Second)
+		ticks <- now
+	}
+	now = now.Add(10 * time.Second)
+	stop()
+
+	assert.Equal(t, 30*time.Second, tickerInterval)
+	assert.True(t, tickerStopped)
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Len(t, lines, 3)
+	assert.JSONEq(t, `{"type":"invoke.heartbeat","requestId":"dummyid","elapsedMs":30000,"remainingTimeMs":90000}`, lines[0])
+	assert.JSONEq(t, `{"type":"invoke.heartbeat","requestId":"dummyid","elapsedMs":60000,"remainingTimeMs":60000}`, lines[1])
+	assert.JSONEq(t, `{"type":"invoke.heartbeat","requestId":"dummyid","elapsedMs":90000,"remainingTimeMs":30000}`, lines[2])
+}
+
+func TestHeartbeatDuringInvoke(t *testing.T) {
+	var out bytes.Buffer
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	handler := NewHandlerWithOptions(func() error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	}, WithHeartbeat(20*time.Millisecond, &out))
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 1, record.nPosts)
+
+	written := out.String()
+	assert.GreaterOrEqual(t, strings.Count(written, `"type":"invoke.heartbeat","requestId":"dummyid"`), 2)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, written, out.String(), "no heartbeats are written after the handler returns")
+}
@@ -0,0 +1,113 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// IdempotencyStore persists the responses of successful invokes, keyed by an idempotency key.
+// Implementations are provided by the user, and are typically backed by a database such as DynamoDB.
+// Expiring entries, for example using a TTL attribute, is the responsibility of the store.
+type IdempotencyStore interface {
+	// Get returns the response stored for key, and whether one was found.
+	Get(ctx context.Context, key string) (response []byte, found bool, err error)
+	// Put stores the response for key.
+	Put(ctx context.Context, key string, response []byte) error
+}
+
+// Idempotency returns a middleware that short-circuits invokes whose payload maps to an already seen idempotency key,
+// returning the response stored for that key instead of calling the handler again.
+// The key is computed by keyFn, an empty key disables the check for that invoke.
+// Only successful responses are stored, so a failed invoke may be retried.
+//
+// Duplicate invokes running concurrently within the same process wait for the first one to complete.
+// Duplicates running concurrently in different execution environments are not coordinated,
+// stores that need this guarantee should use conditional writes.
+//
+// Usage:
+//
+//	lambda.Start(lambda.Idempotency(store, func(ctx context.Context, payload []byte) string {
+//		var order struct{ OrderID string }
+//		_ = json.Unmarshal(payload, &order)
+//		return order.OrderID
+//	})(lambda.NewHandler(handleOrder)))
+func Idempotency(store IdempotencyStore, keyFn func(ctx context.Context, payload []byte) string) func(Handler) Handler {
+	return func(next Handler) Handler {
+		return &idempotentHandler{
+			next:     next,
+			store:    store,
+			keyFn:    keyFn,
+			inFlight: map[string]chan struct{}{},
+		}
+	}
+}
+
+type idempotentHandler struct {
+	next     Handler
+	store    IdempotencyStore
+	keyFn    func(ctx context.Context, payload []byte) string
+	mutex    sync.Mutex
+	inFlight map[string]chan struct{}
+}
+
+func (h *idempotentHandler) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	key := h.keyFn(ctx, payload)
+	if key == "" {
+		return h.next.Invoke(ctx, payload)
+	}
+
+	done, err := h.acquire(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer h.release(key, done)
+
+	response, found, err := h.store.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the idempotency record for key %q: %v", key, err)
+	}
+	if found {
+		return response, nil
+	}
+
+	response, err = h.next.Invoke(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	// the wrapped handler may reuse its response buffer on the next invoke
+	response = append([]byte(nil), response...)
+	if err := h.store.Put(ctx, key, response); err != nil {
+		return nil, fmt.Errorf("failed to put the idempotency record for key %q: %v", key, err)
+	}
+	return response, nil
+}
+
+// acquire waits until no other invoke with the same key is in flight, and then marks key as in flight.
+func (h *idempotentHandler) acquire(ctx context.Context, key string) (chan struct{}, error) {
+	for {
+		h.mutex.Lock()
+		wait, busy := h.inFlight[key]
+		if !busy {
+			done := make(chan struct{})
+			h.inFlight[key] = done
+			h.mutex.Unlock()
+			return done, nil
+		}
+		h.mutex.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (h *idempotentHandler) release(key string, done chan struct{}) {
+	h.mutex.Lock()
+	delete(h.inFlight, key)
+	h.mutex.Unlock()
+	close(done)
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryIdempotencyStore struct {
+	mutex   sync.Mutex
+	records map[string][]byte
+	puts    int
+}
+
+func (s *memoryIdempotencyStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	response, found := s.records[key]
+	return response, found, nil
+}
+
+func (s *memoryIdempotencyStore) Put(_ context.Context, key string, response []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.records[key] = response
+	s.puts++
+	return nil
+}
+
+func payloadKey(_ context.Context, payload []byte) string {
+	return string(payload)
+}
+
+func TestIdempotencyReturnsCachedResponse(t *testing.T) {
+	store := &memoryIdempotencyStore{records: map[string][]byte{}}
+	calls := 0
+	handler := Idempotency(store, payloadKey)(NewHandler(func(name string) (string, error) {
+		calls++
+		return name + " #" + string(rune('0'+calls)), nil
+	}))
+
+	first, err := handler.Invoke(context.Background(), []byte(`"order"`))
+	require.NoError(t, err)
+	second, err := handler.Invoke(context.Background(), []byte(`"order"`))
+	require.NoError(t, err)
+	other, err := handler.Invoke(context.Background(), []byte(`"other"`))
+	require.NoError(t, err)
+
+	assert.Equal(t, `"order #1"`, string(first))
+	assert.Equal(t, `"order #1"`, string(second))
+	assert.Equal(t, `"other #2"`, string(other))
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 2, store.puts)
+}
+
+func TestIdempotencyDoesNotStoreFailures(t *testing.T) {
+	store := &memoryIdempotencyStore{records: map[string][]byte{}}
+	calls := 0
+	handler := Idempotency(store, payloadKey)(NewHandler(func() (string, error) {
+		calls++
+		if calls == 1 {
+			return "", errors.New("try again")
+		}
+		return "ok", nil
+	}))
+
+	_, err := handler.Invoke(context.Background(), []byte(`{}`))
+	assert.EqualError(t, err, "try again")
+	response, err := handler.Invoke(context.Background(), []byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, `"ok"`, string(response))
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyEmptyKeyBypassesStore(t *testing.T) {
+	store := &memoryIdempotencyStore{records: map[string][]byte{}}
+	calls := 0
+	handler := Idempotency(store, func(context.Context, []byte) string { return "" })(NewHandler(func() error {
+		calls++
+		return nil
+	}))
+	for i := 0; i < 3; i++ {
+		_, err := handler.Invoke(context.Background(), []byte(`{}`))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 0, store.puts)
+}
+
+func TestIdempotencyConcurrentDuplicates(t *testing.T) {
+	store := &memoryIdempotencyStore{records: map[string][]byte{}}
+	var calls int32
+	release := make(chan struct{})
+	handler := Idempotency(store, payloadKey)(NewHandler(func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "done", nil
+	}))
+
+	n := 5
+	responses := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := handler.Invoke(context.Background(), []byte(`"same"`))
+			assert.NoError(t, err)
+			responses[i] = string(response)
+		}(i)
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, response := range responses {
+		assert.Equal(t, `"done"`, response)
+	}
+}
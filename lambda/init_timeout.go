@@ -0,0 +1,26 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"time"
+)
+
+var (
+	// initTimeout is the time Lambda allows for the init phase, which ends when the runtime first asks for an invoke
+	initTimeout = 10 * time.Second
+	// initStart approximates the start of the init phase with the initialization of this package
+	initStart = time.Now()
+)
+
+// startInitTimeoutTimer arranges for onTimeout to be called once the init phase exceeds initTimeout.
+// onTimeout is called right away when the init phase already exceeded it. The returned function stops the timer.
+func startInitTimeoutTimer(onTimeout func()) (stop func()) {
+	remaining := initTimeout - time.Since(initStart)
+	if remaining <= 0 {
+		onTimeout()
+		return func() {}
+	}
+	timer := time.AfterFunc(remaining, onTimeout)
+	return func() { timer.Stop() }
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setInitTiming(start time.Time, timeout time.Duration) func() {
+	originalStart, originalTimeout := initStart, initTimeout
+	initStart, initTimeout = start, timeout
+	return func() { initStart, initTimeout = originalStart, originalTimeout }
+}
+
+func TestInitTimeoutTimerFires(t *testing.T) {
+	defer setInitTiming(time.Now(), 20*time.Millisecond)()
+	fired := make(chan struct{})
+	defer startInitTimeoutTimer(func() { close(fired) })()
+	select {
+	case <-fired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the init timeout handler was not called")
+	}
+}
+
+func TestInitTimeoutTimerStopped(t *testing.T) {
+	defer setInitTiming(time.Now(), 50*time.Millisecond)()
+	fired := make(chan struct{})
+	stop := startInitTimeoutTimer(func() { close(fired) })
+	stop()
+	select {
+	case <-fired:
+		t.Fatal("the init timeout handler was called after the timer was stopped")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestInitTimeoutHandlerAlreadyExceeded(t *testing.T) {
+	defer setInitTiming(time.Now().Add(-11*time.Second), 10*time.Second)()
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	calls, getsBeforeCall := 0, -1
+	handler := NewHandlerWithOptions(func() error { return nil }, WithInitTimeoutHandler(func() {
+		calls++
+		getsBeforeCall = record.nGets
+	}))
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 0, getsBeforeCall)
+	require.Equal(t, 1, record.nPosts)
+}
+
+func TestInitTimeoutHandlerWithinTimeout(t *testing.T) {
+	defer setInitTiming(time.Now(), 10*time.Second)()
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	called := false
+	handler := NewHandlerWithOptions(func() error { return nil }, WithInitTimeoutHandler(func() { called = true }))
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.False(t, called)
+	require.Equal(t, 1, record.nPosts)
+}
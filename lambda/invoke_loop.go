@@ -6,11 +6,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/lambda/messages"
@@ -20,35 +24,127 @@ import (
 const (
 	msPerS  = int64(time.Second / time.Millisecond)
 	nsPerMS = int64(time.Millisecond / time.Nanosecond)
+
+	runtimeAPISelfTestTimeout = 2 * time.Second
 )
 
+// metricsFlushTimeout is the least time given to publish the metrics of an invoke, when its deadline is unknown, passed or closer than that
+var metricsFlushTimeout = 2 * time.Second
+
 // TODO: replace with time.UnixMillis after dropping version <1.17 from CI workflows
 func unixMS(ms int64) time.Time {
 	return time.Unix(ms/msPerS, (ms%msPerS)*nsPerMS)
 }
 
 // startRuntimeAPILoop will return an error if handling a particular invoke resulted in a non-recoverable error
+// It only returns nil once the Runtime API closes the connection, and WithExitOnEOF was used,
+// or once the Runtime API signals a reset with the status set by WithRuntimeResetStatus
 func startRuntimeAPILoop(api string, handler Handler) error {
 	client := newRuntimeAPIClient(api)
 	h := newHandler(handler)
+	client.nextRetry = h.nextRetry
+	client.resetStatus = h.runtimeResetStatus
+	// the base context is canceled once the loop returns, so that resources derived from it are released
+	baseContext, cancelBaseContext := context.WithCancel(h.baseContext)
+	defer cancelBaseContext()
+	h.baseContext = baseContext
+	if missing := missingEnv(h.requiredEnv); len(missing) > 0 {
+		return reportInitFailure(client, &messages.InvokeResponse_Error{
+			Type:    "Runtime.MissingEnvironmentVariables",
+			Message: "missing required environment variables: " + strings.Join(missing, ", "),
+		})
+	}
+	if h.envConfig != nil {
+		if err := loadEnvConfig(h.envConfigPrefix, h.envConfig); err != nil {
+			return reportInitFailure(client, &messages.InvokeResponse_Error{
+				Type:    "Runtime.InvalidEnvironmentConfig",
+				Message: err.Error(),
+			})
+		}
+		h.baseContext = lambdacontext.NewConfigContext(h.baseContext, h.envConfig)
+	}
+	stopInitTimeoutTimer := func() {}
+	if h.initTimeoutHandler != nil {
+		stopInitTimeoutTimer = startInitTimeoutTimer(h.initTimeoutHandler)
+	}
+	if h.startupSelfTest {
+		if err := checkRuntimeAPIReachable(api); err != nil {
+			return err
+		}
+	}
+	stopInitTimeoutTimer()
 	for {
 		invoke, err := client.next()
 		if err != nil {
+			if h.exitOnEOF && errors.Is(err, io.EOF) {
+				return nil
+			}
+			// on a reset, the base context is canceled, and WithBetweenInvokes callbacks get a last call to tear down what it held, before a clean exit
+			if errors.Is(err, errRuntimeReset) {
+				cancelBaseContext()
+				if h.betweenInvokes != nil {
+					h.betweenInvokes(h.baseContext)
+				}
+				return nil
+			}
 			return err
 		}
 		if err = handleInvoke(invoke, h); err != nil {
 			return err
 		}
+		if h.betweenInvokes != nil {
+			h.betweenInvokes(h.baseContext)
+		}
 	}
 }
 
 // handleInvoke returns an error if the function panics, or some other non-recoverable error occurred
 func handleInvoke(invoke *invoke, handler *handlerOptions) error {
+	if handler.perInvokeReset != nil {
+		handler.perInvokeReset()
+	}
+	// the metrics are flushed last, also when the invoke returns a panic's error, and until its deadline once it is known
+	flushDeadline := time.Time{}
+	if handler.metrics != nil {
+		defer func() {
+			flushMetrics(handler, flushDeadline)
+		}()
+	}
+	// the invoke counts as failed until the handler returns without an error
+	info := InvokeInfo{RequestID: invoke.id, Failed: true, PayloadSize: len(invoke.payload)}
+	if handler.invokeObserver != nil {
+		defer func() {
+			handler.invokeObserver(info)
+		}()
+	}
+	var responseContentType string
+	if handler.loopStats != nil {
+		defer func() {
+			handler.loopStats.record(info, responseContentType)
+		}()
+	}
+	// the invoke is recorded first, so that the errors of payloads rejected below are recorded with it
+	if handler.invokeRecorder != nil {
+		handler.invokeRecorder.start(invoke.id, invoke.payload)
+	}
+	if handler.maxPayloadBytes > 0 && int64(len(invoke.payload)) > handler.maxPayloadBytes {
+		return reportFailure(invoke, &messages.InvokeResponse_Error{
+			Type:    "Runtime.PayloadTooLarge",
+			Message: fmt.Sprintf("payload of %d bytes exceeds the maximum of %d bytes", len(invoke.payload), handler.maxPayloadBytes),
+		}, handler)
+	}
+
 	// set the deadline
 	deadline, err := parseDeadline(invoke)
+	if handler.invokeTimeout > 0 {
+		if timeoutDeadline := time.Now().Add(handler.invokeTimeout); err != nil || timeoutDeadline.Before(deadline) {
+			deadline, err = timeoutDeadline, nil
+		}
+	}
 	if err != nil {
-		return reportFailure(invoke, lambdaErrorResponse(err))
+		return reportFailure(invoke, lambdaErrorResponse(err), handler)
 	}
+	flushDeadline = deadline
 	ctx, cancel := context.WithDeadline(handler.baseContext, deadline)
 	defer cancel()
 
@@ -58,12 +154,22 @@ func handleInvoke(invoke *invoke, handler *handlerOptions) error {
 		InvokedFunctionArn: invoke.headers.Get(headerInvokedFunctionARN),
 	}
 	if err := parseClientContext(invoke, &lc.ClientContext); err != nil {
-		return reportFailure(invoke, lambdaErrorResponse(err))
+		return reportFailure(invoke, lambdaErrorResponse(err), handler)
 	}
 	if err := parseCognitoIdentity(invoke, &lc.Identity); err != nil {
-		return reportFailure(invoke, lambdaErrorResponse(err))
+		return reportFailure(invoke, lambdaErrorResponse(err), handler)
 	}
 	ctx = lambdacontext.NewContext(ctx, &lc)
+	ctx = lambdacontext.NewHeadersContext(ctx, invoke.headers)
+	if handler.correlationHeader != "" {
+		ctx = lambdacontext.NewCorrelationIDContext(ctx, handler.correlationHeader, correlationID(invoke, handler.correlationHeader))
+	}
+	if handler.preInvoke != nil {
+		// the hook gets a copy of the headers, so that it cannot change those seen by the rest of the invoke
+		if hookCtx := handler.preInvoke(ctx, invoke.headers.Clone()); hookCtx != nil {
+			ctx = hookCtx
+		}
+	}
 
 	// set the trace id
 	traceID := invoke.headers.Get(headerTraceID)
@@ -71,41 +177,207 @@ func handleInvoke(invoke *invoke, handler *handlerOptions) error {
 	// nolint:staticcheck
 	ctx = context.WithValue(ctx, "x-amzn-trace-id", traceID)
 
+	handler.invokesStarted++
+	if handler.invokeStartLog != nil {
+		logInvokeStart(handler.invokeStartLog, invoke.id, handler.invokesStarted == 1, deadline)
+	}
+
 	// call the handler, marshal any returned error
-	response, invokeErr := callBytesHandlerFunc(ctx, invoke.payload, handler.handlerFunc)
+	goroutinesBefore := runtime.NumGoroutine()
+	invokeStart := time.Now()
+	stopHeartbeat := func() {}
+	if handler.heartbeat != nil {
+		stopHeartbeat = handler.heartbeat.start(invoke.id, deadline)
+	}
+	response, invokeErr := callBytesHandlerFunc(ctx, invoke.payload, handler)
+	stopHeartbeat()
+	info.Duration = time.Since(invokeStart)
+	info.Failed = invokeErr != nil
+	info.Panicked = invokeErr != nil && invokeErr.ShouldExit
+	if handler.goroutineLeakCheck {
+		checkGoroutineLeak(invoke.id, goroutinesBefore, handler.goroutineLeakThreshold)
+	}
 	if invokeErr != nil {
-		if err := reportFailure(invoke, invokeErr); err != nil {
-			return err
+		// a response returned along with the error may hold resources, such as a file, so close it before reporting the failure
+		if response, ok := response.(io.Closer); ok {
+			_ = response.Close()
 		}
-		if invokeErr.ShouldExit {
-			return fmt.Errorf("calling the handler function resulted in a panic, the process should exit")
+		panicked := invokeErr.ShouldExit
+		if handler.exitPolicy != nil {
+			invokeErr.ShouldExit = handler.exitPolicy(invokeErr)
 		}
-		return nil
+		reportErr := reportFailure(invoke, invokeErr, handler)
+		// the process exits when the report fails too, so the crash is reported either way
+		exitErr := exitAfterFailure(ctx, invokeErr, panicked, handler)
+		if reportErr != nil {
+			return reportErr
+		}
+		return exitErr
 	}
 	// if the response needs to be closed (ex: net.Conn, os.File), ensure it's closed before the next invoke to prevent a resource leak
 	if response, ok := response.(io.Closer); ok {
 		defer response.Close()
 	}
 
+	body := response
+
 	// if the response defines a content-type, plumb it through
 	contentType := contentTypeBytes
 	type ContentType interface{ ContentType() string }
 	if response, ok := response.(ContentType); ok {
 		contentType = response.ContentType()
 	}
+	// if the response defines a status code or headers, stream it with the http integration prelude
+	if streaming, ok := withStreamingPrelude(response, contentType); ok {
+		response, contentType = streaming, contentTypeHTTPIntegrationResponse
+	}
+
+	if handler.strictContentLength {
+		var err error
+		if response, err = withContentLengthCheck(body, response); err != nil {
+			info.Failed = true
+			return reportFailure(invoke, lambdaErrorResponse(err), handler)
+		}
+	}
+	checkedResponse := response
 
-	if err := invoke.success(response, contentType); err != nil {
+	responseContentType = contentType
+
+	if handler.invokeRecorder != nil {
+		var closeRecording func()
+		response, closeRecording = handler.invokeRecorder.recordResponse(response)
+		defer closeRecording()
+	}
+
+	// responses in the http integration format are sent as a stream, so the body can be written as it is produced
+	send := invoke.success
+	if contentType == contentTypeHTTPIntegrationResponse {
+		send = invoke.streamingSuccess
+	}
+	if err := send(response, contentType); err != nil {
 		return fmt.Errorf("unexpected error occurred when sending the function functionResponse to the API: %v", err)
 	}
 
+	// a response found to be shorter or longer than declared as it was sent fails the invoke, the error was sent as the error trailer
+	if contentLengthFailure(checkedResponse) != nil {
+		info.Failed = true
+		responseContentType = ""
+	}
+	// the error of a streaming handler that had started its response was sent as the error trailer, and still fails the invoke
+	if invokeErr := streamedFailure(body); invokeErr != nil {
+		info.Failed = true
+		info.Panicked = invokeErr.ShouldExit
+		responseContentType = ""
+		panicked := invokeErr.ShouldExit
+		if handler.exitPolicy != nil {
+			invokeErr.ShouldExit = handler.exitPolicy(invokeErr)
+		}
+		return exitAfterFailure(ctx, invokeErr, panicked, handler)
+	}
 	return nil
 }
 
-func reportFailure(invoke *invoke, invokeErr *messages.InvokeResponse_Error) error {
+// exitAfterFailure returns the error the invoke loop exits with when invokeErr requires the process to exit, after calling the crash reporter
+func exitAfterFailure(ctx context.Context, invokeErr *messages.InvokeResponse_Error, panicked bool, handler *handlerOptions) error {
+	if !invokeErr.ShouldExit {
+		return nil
+	}
+	if handler.crashReporter != nil {
+		handler.crashReporter(ctx, invokeErr)
+	}
+	err := fmt.Errorf("calling the handler function resulted in a panic, the process should exit")
+	if !panicked {
+		err = fmt.Errorf("calling the handler function resulted in an error, the exit policy requires the process to exit")
+	}
+	if handler.exitCode != nil {
+		return &exitError{err: err, code: handler.exitCode(invokeErr)}
+	}
+	return err
+}
+
+// checkRuntimeAPIReachable opens, then closes, a connection to the Runtime API.
+// A request is not sent, as requesting the next invoke would start it.
+func checkRuntimeAPIReachable(api string) error {
+	conn, err := net.DialTimeout("tcp", api, runtimeAPISelfTestTimeout)
+	if err != nil {
+		return fmt.Errorf("startup self test failed, the Runtime API at %s is unreachable: %v", api, err)
+	}
+	return conn.Close()
+}
+
+type invokeStartLine struct {
+	Type            string `json:"type"`
+	RequestID       string `json:"requestId"`
+	ColdStart       bool   `json:"coldStart"`
+	RemainingTimeMS int64  `json:"remainingTimeMs"`
+}
+
+func logInvokeStart(w io.Writer, requestID string, coldStart bool, deadline time.Time) {
+	line, _ := json.Marshal(invokeStartLine{
+		Type:            "invoke.start",
+		RequestID:       requestID,
+		ColdStart:       coldStart,
+		RemainingTimeMS: int64(time.Until(deadline) / time.Millisecond),
+	})
+	_, _ = w.Write(append(line, '\n'))
+}
+
+func checkGoroutineLeak(requestID string, before int, threshold int) {
+	after := runtime.NumGoroutine()
+	if after-before <= threshold {
+		return
+	}
+	stacks := make([]byte, 64*1024)
+	stacks = stacks[:runtime.Stack(stacks, true)]
+	log.Printf("WARNING! Goroutine count grew from %d to %d during invoke %s, the handler may be leaking goroutines! stacks:\n%s", before, after, requestID, stacks)
+}
+
+// exitError is returned by the invoke loop when the process should exit with a status chosen by WithExitCode
+type exitError struct {
+	err  error
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("%v, exit status %d", e.err, e.code)
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// reportInitFailure sends an init error to the Runtime API, and returns the error for Start to exit with
+func reportInitFailure(client *runtimeAPIClient, initErr *messages.InvokeResponse_Error) error {
+	if err := client.initError(bytes.NewReader(safeMarshal(initErr)), contentTypeJSON, initErr.Type); err != nil {
+		return fmt.Errorf("%s, and the init error could not be reported: %v", initErr.Message, err)
+	}
+	return errors.New(initErr.Message)
+}
+
+// flushMetrics publishes the data points buffered by an invoke, until its deadline or for metricsFlushTimeout, whichever is later,
+// so that a stuck publisher cannot hold up the next invoke indefinitely
+func flushMetrics(handler *handlerOptions, deadline time.Time) {
+	if minDeadline := time.Now().Add(metricsFlushTimeout); deadline.Before(minDeadline) {
+		deadline = minDeadline
+	}
+	ctx, cancel := context.WithDeadline(handler.baseContext, deadline)
+	defer cancel()
+	if err := handler.metrics.Flush(ctx); err != nil {
+		log.Printf("failed to publish metrics: %v", err)
+	}
+}
+
+func reportFailure(invoke *invoke, invokeErr *messages.InvokeResponse_Error, handler *handlerOptions) error {
+	if handler.maxErrorMessageLen > 0 {
+		invokeErr.Message = truncateErrorMessage(invokeErr.Message, handler.maxErrorMessageLen)
+	}
 	errorPayload := safeMarshal(invokeErr)
-	log.Printf("%s", errorPayload)
+	logFailure(invoke.id, invokeErr, errorPayload, handler)
+	if handler.invokeRecorder != nil {
+		handler.invokeRecorder.recordError(errorPayload)
+	}
 
-	causeForXRay, err := json.Marshal(makeXRayError(invokeErr))
+	causeForXRay, err := json.Marshal(makeXRayError(invokeErr, handler.stackFilter))
 	if err != nil {
 		return fmt.Errorf("unexpected error occured when serializing the function error cause for X-Ray: %v", err)
 	}
@@ -116,15 +388,90 @@ func reportFailure(invoke *invoke, invokeErr *messages.InvokeResponse_Error) err
 	return nil
 }
 
-func callBytesHandlerFunc(ctx context.Context, payload []byte, handler handlerFunc) (response io.Reader, invokeErr *messages.InvokeResponse_Error) {
+func logFailure(requestID string, invokeErr *messages.InvokeResponse_Error, errorPayload []byte, handler *handlerOptions) {
+	if handler.failureCoalescer != nil {
+		logLine, summary := handler.failureCoalescer.observe(errorPayload)
+		if summary != "" {
+			writeFailureLog([]byte(summary), handler)
+		}
+		if !logLine {
+			return
+		}
+	}
+	if handler.structuredLogging {
+		if failureLogAllowed(handler) {
+			fmt.Fprintf(log.Writer(), "%s\n", structuredFailureLog(requestID, invokeErr, time.Now()))
+		}
+		return
+	}
+	writeFailureLog(errorPayload, handler)
+}
+
+func writeFailureLog(line []byte, handler *handlerOptions) {
+	if failureLogAllowed(handler) {
+		log.Printf("%s", line)
+	}
+}
+
+func failureLogAllowed(handler *handlerOptions) bool {
+	if handler.errorLogLimiter != nil {
+		allowed, dropped := handler.errorLogLimiter.allow()
+		if dropped > 0 {
+			log.Printf("%d failure logs were dropped by the error log rate limit", dropped)
+		}
+		return allowed
+	}
+	return true
+}
+
+// structuredFailureLog formats a failure as a single line JSON object, in the format of the structured logs of the managed runtimes
+func structuredFailureLog(requestID string, invokeErr *messages.InvokeResponse_Error, now time.Time) []byte {
+	return safeMarshal(struct {
+		Timestamp    string                                      `json:"timestamp"`
+		Level        string                                      `json:"level"`
+		RequestID    string                                      `json:"requestId"`
+		ErrorType    string                                      `json:"errorType"`
+		ErrorMessage string                                      `json:"errorMessage"`
+		StackTrace   []*messages.InvokeResponse_Error_StackFrame `json:"stackTrace,omitempty"`
+	}{
+		Timestamp:    now.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Level:        "ERROR",
+		RequestID:    requestID,
+		ErrorType:    invokeErr.Type,
+		ErrorMessage: invokeErr.Message,
+		StackTrace:   invokeErr.StackTrace,
+	})
+}
+
+func callBytesHandlerFunc(ctx context.Context, payload []byte, h *handlerOptions) (response io.Reader, invokeErr *messages.InvokeResponse_Error) {
 	defer func() {
 		if err := recover(); err != nil {
 			invokeErr = lambdaPanicResponse(err)
+			formatPanicMessage(invokeErr, err, h.panicValueFormatter)
+			if h.errorFormatter != nil {
+				panicErr, ok := err.(error)
+				if !ok {
+					panicErr = *invokeErr
+				}
+				if formatted := h.errorFormatter(panicErr); formatted != nil {
+					formatted.ShouldExit = true
+					invokeErr = formatted
+				}
+			}
 		}
 	}()
-	response, err := handler(ctx, payload)
+	response, err := h.handlerFunc(ctx, payload)
 	if err != nil {
-		return nil, lambdaErrorResponse(err)
+		if h.errorFormatter != nil {
+			if formatted := h.errorFormatter(err); formatted != nil {
+				// streaming handlers return their panics, which still require the process to exit
+				if panicErr, ok := err.(messages.InvokeResponse_Error); ok && panicErr.ShouldExit {
+					formatted.ShouldExit = true
+				}
+				return response, formatted
+			}
+		}
+		return response, lambdaErrorResponse(err)
 	}
 	return response, nil
 }
@@ -134,7 +481,9 @@ func parseDeadline(invoke *invoke) (time.Time, error) {
 	if err != nil {
 		return time.Time{}, fmt.Errorf("failed to parse deadline: %v", err)
 	}
-	return unixMS(deadlineEpochMS), nil
+	// carry a monotonic clock reading, so that time remaining until the deadline is not skewed by changes to the wall clock during the invoke
+	now := time.Now()
+	return now.Add(unixMS(deadlineEpochMS).Sub(now)), nil
 }
 
 func parseCognitoIdentity(invoke *invoke, out *lambdacontext.CognitoIdentity) error {
@@ -174,9 +523,11 @@ func safeMarshal(v interface{}) []byte {
 }
 
 type xrayException struct {
+	ID      string                                      `json:"id,omitempty"`
 	Type    string                                      `json:"type"`
 	Message string                                      `json:"message"`
 	Stack   []*messages.InvokeResponse_Error_StackFrame `json:"stack"`
+	Cause   string                                      `json:"cause,omitempty"`
 }
 
 type xrayError struct {
@@ -185,10 +536,48 @@ type xrayError struct {
 	Paths            []string        `json:"paths"`
 }
 
-func makeXRayError(invokeResponseError *messages.InvokeResponse_Error) *xrayError {
-	paths := make([]string, 0, len(invokeResponseError.StackTrace))
-	visitedPaths := make(map[string]struct{}, len(invokeResponseError.StackTrace))
-	for _, frame := range invokeResponseError.StackTrace {
+// appendXRayCauses appends an exception for each of causes, and for their own causes, linking the exception at index to the first of them.
+// Each exception only has a single cause in X-Ray, so the others are listed without being linked to.
+func appendXRayCauses(exceptions []xrayException, index int, causes []*messages.InvokeResponse_Error) []xrayException {
+	if exceptions[index].ID == "" {
+		exceptions[index].ID = xrayExceptionID(index)
+	}
+	for i, cause := range causes {
+		exceptions = append(exceptions, xrayException{
+			ID:      xrayExceptionID(len(exceptions)),
+			Type:    cause.Type,
+			Message: cause.Message,
+			Stack:   []*messages.InvokeResponse_Error_StackFrame{},
+		})
+		if i == 0 {
+			exceptions[index].Cause = exceptions[len(exceptions)-1].ID
+		}
+		if len(cause.Causes) > 0 {
+			exceptions = appendXRayCauses(exceptions, len(exceptions)-1, cause.Causes)
+		}
+	}
+	return exceptions
+}
+
+// xrayExceptionID returns the id of the exception at index, as the 16 hexadecimal digits expected by X-Ray
+func xrayExceptionID(index int) string {
+	return fmt.Sprintf("%016x", index+1)
+}
+
+func makeXRayError(invokeResponseError *messages.InvokeResponse_Error, stackFilter func(messages.InvokeResponse_Error_StackFrame) bool) *xrayError {
+	stack := invokeResponseError.StackTrace
+	if stackFilter != nil && stack != nil {
+		stack = make([]*messages.InvokeResponse_Error_StackFrame, 0, len(invokeResponseError.StackTrace))
+		for _, frame := range invokeResponseError.StackTrace {
+			if stackFilter(*frame) {
+				stack = append(stack, frame)
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(stack))
+	visitedPaths := make(map[string]struct{}, len(stack))
+	for _, frame := range stack {
 		if _, exists := visitedPaths[frame.Path]; !exists {
 			visitedPaths[frame.Path] = struct{}{}
 			paths = append(paths, frame.Path)
@@ -199,11 +588,14 @@ func makeXRayError(invokeResponseError *messages.InvokeResponse_Error) *xrayErro
 	exceptions := []xrayException{{
 		Type:    invokeResponseError.Type,
 		Message: invokeResponseError.Message,
-		Stack:   invokeResponseError.StackTrace,
+		Stack:   stack,
 	}}
 	if exceptions[0].Stack == nil {
 		exceptions[0].Stack = []*messages.InvokeResponse_Error_StackFrame{}
 	}
+	if len(invokeResponseError.Causes) > 0 {
+		exceptions = appendXRayCauses(exceptions, 0, invokeResponseError.Causes)
+	}
 	return &xrayError{
 		WorkingDirectory: cwd,
 		Paths:            paths,
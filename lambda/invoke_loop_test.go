@@ -4,19 +4,27 @@ package lambda
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil" //nolint: staticcheck
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 	"unicode/utf8"
 
+	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda/messages"
+	"github.com/aws/aws-lambda-go/lambda/metrics"
 	"github.com/aws/aws-lambda-go/lambdacontext"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -40,6 +48,109 @@ func TestFatalErrors(t *testing.T) {
 	assert.Equal(t, "a fatal error", invokeErr.Message)
 }
 
+func TestExitPolicy(t *testing.T) {
+	type fatalConfigError struct{ error }
+	testCases := []struct {
+		name          string
+		handler       interface{}
+		policy        func(*messages.InvokeResponse_Error) bool
+		expectedError string
+		expectedGets  int
+		expectedPosts int
+	}{
+		{
+			name:          "default policy keeps serving on errors",
+			handler:       func() error { return errors.New("boring") },
+			expectedError: "got unexpected status code: 410",
+			expectedGets:  3,
+			expectedPosts: 2,
+		},
+		{
+			name:          "policy forces exit on a specific error type",
+			handler:       func() error { return fatalConfigError{errors.New("missing table name")} },
+			policy:        func(err *messages.InvokeResponse_Error) bool { return err.ShouldExit || err.Type == "fatalConfigError" },
+			expectedError: "calling the handler function resulted in an error, the exit policy requires the process to exit",
+			expectedGets:  1,
+			expectedPosts: 1,
+		},
+		{
+			name:          "policy ignores other error types",
+			handler:       func() error { return errors.New("boring") },
+			policy:        func(err *messages.InvokeResponse_Error) bool { return err.ShouldExit || err.Type == "fatalConfigError" },
+			expectedError: "got unexpected status code: 410",
+			expectedGets:  3,
+			expectedPosts: 2,
+		},
+		{
+			name:          "policy keeps serving after a panic",
+			handler:       func() error { panic("oops") },
+			policy:        func(err *messages.InvokeResponse_Error) bool { return false },
+			expectedError: "got unexpected status code: 410",
+			expectedGets:  3,
+			expectedPosts: 2,
+		},
+		{
+			name:          "default policy exits after a panic",
+			handler:       func() error { panic("oops") },
+			expectedError: "calling the handler function resulted in a panic, the process should exit",
+			expectedGets:  1,
+			expectedPosts: 1,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ts, record := runtimeAPIServer(``, 2)
+			defer ts.Close()
+			var options []Option
+			if testCase.policy != nil {
+				options = append(options, WithExitPolicy(testCase.policy))
+			}
+			handler := NewHandlerWithOptions(testCase.handler, options...)
+			endpoint := strings.Split(ts.URL, "://")[1]
+			err := startRuntimeAPILoop(endpoint, handler)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testCase.expectedError)
+			assert.Equal(t, testCase.expectedGets, record.nGets)
+			assert.Equal(t, testCase.expectedPosts, record.nPosts)
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 2)
+	defer ts.Close()
+	var received []*messages.InvokeResponse_Error
+	handler := NewHandlerWithOptions(func() error { panic("oops") }, WithExitCode(func(err *messages.InvokeResponse_Error) int {
+		received = append(received, err)
+		return 3
+	}))
+	err := startRuntimeAPILoop(strings.Split(ts.URL, "://")[1], handler)
+
+	var exitErr *exitError
+	require.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, 3, exitErr.code)
+	assert.EqualError(t, err, "calling the handler function resulted in a panic, the process should exit, exit status 3")
+	require.Len(t, received, 1)
+	assert.Equal(t, "oops", received[0].Message)
+	assert.Equal(t, "string", received[0].Type)
+	assert.True(t, received[0].ShouldExit)
+	assert.Equal(t, 1, record.nPosts)
+}
+
+func TestExitCodeNotCalledWhenServingContinues(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 2)
+	defer ts.Close()
+	called := false
+	handler := NewHandlerWithOptions(func() error { return errors.New("boring") }, WithExitCode(func(err *messages.InvokeResponse_Error) int {
+		called = true
+		return 3
+	}))
+	err := startRuntimeAPILoop(strings.Split(ts.URL, "://")[1], handler)
+	assert.EqualError(t, err, "failed to GET http://"+strings.Split(ts.URL, "://")[1]+"/2018-06-01/runtime/invocation/next: got unexpected status code: 410")
+	assert.False(t, called)
+	assert.Equal(t, 2, record.nPosts)
+}
+
 func TestRuntimeAPILoop(t *testing.T) {
 	nInvokes := 10
 
@@ -61,6 +172,241 @@ func TestRuntimeAPILoop(t *testing.T) {
 	assert.Equal(t, nInvokes, record.nPosts)
 }
 
+func TestBetweenInvokes(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 3)
+	defer ts.Close()
+	type ctxKey struct{}
+	var events []string
+	handler := NewHandlerWithOptions(func(ctx context.Context) error {
+		events = append(events, fmt.Sprintf("invoke gets=%d posts=%d", record.nGets, record.nPosts))
+		if len(events) == 3 {
+			return errors.New("failed invoke")
+		}
+		return nil
+	},
+		WithContext(context.WithValue(context.Background(), ctxKey{}, "base")),
+		WithBetweenInvokes(func(ctx context.Context) {
+			events = append(events, fmt.Sprintf("between gets=%d posts=%d ctx=%v", record.nGets, record.nPosts, ctx.Value(ctxKey{})))
+		}),
+	)
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.Equal(t, []string{
+		"invoke gets=1 posts=0",
+		"between gets=1 posts=1 ctx=base",
+		"invoke gets=2 posts=1",
+		"between gets=2 posts=2 ctx=base",
+		"invoke gets=3 posts=2",
+		"between gets=3 posts=3 ctx=base",
+	}, events)
+}
+
+func TestBetweenInvokesNotCalledBeforeExit(t *testing.T) {
+	ts, _ := runtimeAPIServer(``, 3)
+	defer ts.Close()
+	called := false
+	handler := NewHandlerWithOptions(func() error { panic("oops") }, WithBetweenInvokes(func(ctx context.Context) { called = true }))
+	assert.Error(t, startRuntimeAPILoop(serverAddress(ts), handler))
+	assert.False(t, called)
+}
+
+func TestPerInvokeReset(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 3)
+	defer ts.Close()
+	var events []string
+	cache := map[string]int{}
+	handler := NewHandlerWithOptions(func() error {
+		cache["invokes"]++
+		events = append(events, fmt.Sprintf("invoke gets=%d posts=%d cached=%d", record.nGets, record.nPosts, cache["invokes"]))
+		if record.nGets == 2 {
+			return errors.New("failed invoke")
+		}
+		return nil
+	},
+		WithPerInvokeReset(func() {
+			events = append(events, fmt.Sprintf("reset gets=%d posts=%d", record.nGets, record.nPosts))
+			cache = map[string]int{}
+		}),
+		WithBetweenInvokes(func(ctx context.Context) {
+			events = append(events, "between")
+		}),
+	)
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.Equal(t, []string{
+		"reset gets=1 posts=0",
+		"invoke gets=1 posts=0 cached=1",
+		"between",
+		"reset gets=2 posts=1",
+		"invoke gets=2 posts=1 cached=1",
+		"between",
+		"reset gets=3 posts=2",
+		"invoke gets=3 posts=2 cached=1",
+		"between",
+	}, events)
+}
+
+func TestMetricsFlushedBetweenInvokes(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 3)
+	defer ts.Close()
+	var events []string
+	buffer := metrics.NewBuffer("Service", 2, func(ctx context.Context, namespace string, data []metrics.Datum) error {
+		events = append(events, fmt.Sprintf("publish posts=%d namespace=%s data=%d", record.nPosts, namespace, len(data)))
+		if record.nPosts == 2 {
+			return errors.New("throttled")
+		}
+		return nil
+	})
+	handler := NewHandlerWithOptions(func(ctx context.Context) error {
+		for i := 0; i < 3; i++ {
+			metrics.Collector(ctx).Add("Processed", 1, "Count", nil)
+		}
+		events = append(events, fmt.Sprintf("invoke posts=%d buffered=%d", record.nPosts, buffer.Len()))
+		return nil
+	},
+		WithMetrics(buffer),
+		WithBetweenInvokes(func(ctx context.Context) {
+			events = append(events, fmt.Sprintf("between buffered=%d", buffer.Len()))
+		}),
+	)
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.Equal(t, []string{
+		"invoke posts=0 buffered=3",
+		"publish posts=1 namespace=Service data=2",
+		"publish posts=1 namespace=Service data=1",
+		"between buffered=0",
+		"invoke posts=1 buffered=3",
+		"publish posts=2 namespace=Service data=2",
+		"between buffered=0",
+		"invoke posts=2 buffered=3",
+		"publish posts=3 namespace=Service data=2",
+		"publish posts=3 namespace=Service data=1",
+		"between buffered=0",
+	}, events)
+}
+
+func TestMetricsFlushedAfterPanic(t *testing.T) {
+	defer setMetricsFlushTimeout(10 * time.Millisecond)()
+	ts, record := runtimeAPIServer(``, 2)
+	defer ts.Close()
+	var flushErr error
+	buffer := metrics.NewBuffer("Service", 0, func(ctx context.Context, namespace string, data []metrics.Datum) error {
+		// a stuck publisher, which only returns once the flush times out
+		<-ctx.Done()
+		flushErr = ctx.Err()
+		return flushErr
+	})
+	handler := NewHandlerWithOptions(func(ctx context.Context) error {
+		metrics.Collector(ctx).Add("Processed", 1, "Count", nil)
+		panic("oops")
+	}, WithMetrics(buffer))
+	err := startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.EqualError(t, err, "calling the handler function resulted in a panic, the process should exit")
+	assert.Equal(t, 1, record.nPosts)
+	assert.Equal(t, context.DeadlineExceeded, flushErr)
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func setMetricsFlushTimeout(timeout time.Duration) func() {
+	original := metricsFlushTimeout
+	metricsFlushTimeout = timeout
+	return func() { metricsFlushTimeout = original }
+}
+
+func TestRuntimeReset(t *testing.T) {
+	gets := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		gets++
+		if gets > 1 {
+			w.WriteHeader(http.StatusResetContent)
+			return
+		}
+		w.Header().Set(headerAWSRequestID, "dummyid")
+		w.Header().Set(headerDeadlineMS, "22")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	var betweenErrs []error
+	drained := make(chan struct{})
+	handler := NewHandlerWithOptions(func() error { return nil }, WithBetweenInvokes(func(ctx context.Context) {
+		betweenErrs = append(betweenErrs, ctx.Err())
+		if len(betweenErrs) == 1 {
+			// a pooled resource released once the base context is done
+			go func() {
+				<-ctx.Done()
+				close(drained)
+			}()
+		}
+	}), WithRuntimeResetStatus(http.StatusResetContent))
+	err := startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, gets)
+	assert.Equal(t, []error{nil, context.Canceled}, betweenErrs)
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("resources derived from the base context should be released on a reset")
+	}
+}
+
+func TestBaseContextCanceledWhenLoopReturns(t *testing.T) {
+	ts, _ := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	var betweenCtx context.Context
+	calls := 0
+	handler := NewHandlerWithOptions(func() error { return nil }, WithBetweenInvokes(func(ctx context.Context) {
+		betweenCtx = ctx
+		calls++
+	}))
+	err := startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, errRuntimeReset)
+	assert.Equal(t, 1, calls, "WithBetweenInvokes callbacks only get a last call on a reset")
+	require.NotNil(t, betweenCtx)
+	assert.ErrorIs(t, betweenCtx.Err(), context.Canceled)
+}
+
+func TestPanicValueFormatter(t *testing.T) {
+	type panicDetails struct {
+		Code   int    `json:"code"`
+		Reason string `json:"reason"`
+	}
+	formatter := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return "custom: " + string(b)
+	}
+	testCases := []struct {
+		name     string
+		panicked interface{}
+		options  []Option
+		expected string
+	}{
+		{"default formatting of a struct", panicDetails{42, "boom"}, nil, "{42 boom}"},
+		{"custom formatting of a struct", panicDetails{42, "boom"}, []Option{WithPanicValueFormatter(formatter)}, `custom: {"code":42,"reason":"boom"}`},
+		{"custom formatting of a string", "boom", []Option{WithPanicValueFormatter(formatter)}, `custom: "boom"`},
+		{"errors keep their message", errors.New("boom"), []Option{WithPanicValueFormatter(formatter)}, "boom"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ts, record := runtimeAPIServer(``, 1)
+			defer ts.Close()
+			handler := NewHandlerWithOptions(func() error { panic(testCase.panicked) }, testCase.options...)
+			assert.Error(t, startRuntimeAPILoop(serverAddress(ts), handler))
+			require.Equal(t, 1, record.nPosts)
+			var invokeErr messages.InvokeResponse_Error
+			require.NoError(t, json.Unmarshal(record.responses[0], &invokeErr))
+			assert.Equal(t, testCase.expected, invokeErr.Message)
+			assert.NotEmpty(t, invokeErr.StackTrace)
+		})
+	}
+}
+
 func TestCustomErrorMarshaling(t *testing.T) {
 	type CustomError struct{ error }
 	errors := []error{
@@ -264,6 +610,74 @@ func TestReadPayload(t *testing.T) {
 	assert.Equal(t, contentTypeJSON, record.contentTypes[0])
 }
 
+func TestTaggedContentType(t *testing.T) {
+	type taggedResponse struct {
+		ContentType string `json:"-" lambda:"contentType"`
+		Message     string `json:"message"`
+	}
+	testCases := []struct {
+		name                string
+		handler             interface{}
+		expectedResponse    string
+		expectedContentType string
+	}{
+		{
+			name: "struct value",
+			handler: func() (taggedResponse, error) {
+				return taggedResponse{ContentType: "application/vnd.example+json", Message: "hi"}, nil
+			},
+			expectedResponse:    `{"message":"hi"}`,
+			expectedContentType: "application/vnd.example+json",
+		},
+		{
+			name: "struct pointer",
+			handler: func() (*taggedResponse, error) {
+				return &taggedResponse{ContentType: "text/plain", Message: "hi"}, nil
+			},
+			expectedResponse:    `{"message":"hi"}`,
+			expectedContentType: "text/plain",
+		},
+		{
+			name: "empty field keeps the json content type",
+			handler: func() (taggedResponse, error) {
+				return taggedResponse{Message: "hi"}, nil
+			},
+			expectedResponse:    `{"message":"hi"}`,
+			expectedContentType: contentTypeJSON,
+		},
+		{
+			name: "nil pointer keeps the json content type",
+			handler: func() (*taggedResponse, error) {
+				return nil, nil
+			},
+			expectedResponse:    `null`,
+			expectedContentType: contentTypeJSON,
+		},
+		{
+			name: "tag on a non-string field is ignored",
+			handler: func() (struct {
+				ContentType int `json:"contentType" lambda:"contentType"`
+			}, error) {
+				return struct {
+					ContentType int `json:"contentType" lambda:"contentType"`
+				}{ContentType: 1}, nil
+			},
+			expectedResponse:    `{"contentType":1}`,
+			expectedContentType: contentTypeJSON,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ts, record := runtimeAPIServer(`{}`, 1)
+			defer ts.Close()
+			_ = startRuntimeAPILoop(strings.Split(ts.URL, "://")[1], NewHandler(testCase.handler))
+			require.Len(t, record.responses, 1)
+			assert.Equal(t, testCase.expectedResponse, string(record.responses[0]))
+			assert.Equal(t, testCase.expectedContentType, record.contentTypes[0])
+		})
+	}
+}
+
 type readCloser struct {
 	closed bool
 	reader *strings.Reader
@@ -358,6 +772,34 @@ func TestContextDeserializationErrors(t *testing.T) {
 	}`, string(record.responses[2]))
 }
 
+func TestGoroutineLeakCheck(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	leaked := make(chan struct{})
+	defer close(leaked)
+	handler := NewHandlerWithOptions(func(leak bool) error {
+		if leak {
+			go func() { <-leaked }()
+		}
+		return nil
+	}, WithGoroutineLeakCheck(0))
+
+	ts, _ := runtimeAPIServer(`false`, 1)
+	defer ts.Close()
+	endpoint := strings.Split(ts.URL, "://")[1]
+	_ = startRuntimeAPILoop(endpoint, handler)
+	assert.NotContains(t, logs.String(), "may be leaking goroutines")
+
+	ts, _ = runtimeAPIServer(`true`, 1)
+	defer ts.Close()
+	endpoint = strings.Split(ts.URL, "://")[1]
+	_ = startRuntimeAPILoop(endpoint, handler)
+	assert.Contains(t, logs.String(), "during invoke dummyid, the handler may be leaking goroutines!")
+	assert.Contains(t, logs.String(), "TestGoroutineLeakCheck")
+}
+
 type invalidPayload struct{}
 
 func (invalidPayload) MarshalJSON() ([]byte, error) {
@@ -371,11 +813,14 @@ func TestSafeMarshal_SerializationError(t *testing.T) {
 }
 
 type requestRecord struct {
-	nGets        int
-	nPosts       int
-	responses    [][]byte
-	contentTypes []string
-	xrayCauses   []string
+	nGets         int
+	nPosts        int
+	responses     [][]byte
+	contentTypes  []string
+	xrayCauses    []string
+	responseModes []string
+	errorTrailers []string
+	postPaths     []string
 }
 
 type eventMetadata struct {
@@ -442,6 +887,9 @@ func runtimeAPIServer(eventPayload string, failAfter int, overrides ...eventMeta
 			record.responses = append(record.responses, response.Bytes())
 			record.contentTypes = append(record.contentTypes, r.Header.Get("Content-Type"))
 			record.xrayCauses = append(record.xrayCauses, r.Header.Get(headerXRayErrorCause))
+			record.responseModes = append(record.responseModes, r.Header.Get(headerResponseMode))
+			record.errorTrailers = append(record.errorTrailers, r.Trailer.Get(trailerLambdaErrorType))
+			record.postPaths = append(record.postPaths, r.URL.Path)
 		default:
 			w.WriteHeader(http.StatusBadRequest)
 		}
@@ -449,3 +897,1114 @@ func runtimeAPIServer(eventPayload string, failAfter int, overrides ...eventMeta
 
 	return ts, record
 }
+
+func TestStreamingResponseMode(t *testing.T) {
+	ts, record := runtimeAPIServer(`{}`, 2)
+	defer ts.Close()
+	streamed := false
+	handler := NewHandler(func() (interface{}, error) {
+		if streamed {
+			return "buffered", nil
+		}
+		streamed = true
+		return &events.LambdaFunctionURLStreamingResponse{
+			StatusCode: 200,
+			Body:       strings.NewReader("streamed"),
+		}, nil
+	})
+	endpoint := strings.Split(ts.URL, "://")[1]
+	_ = startRuntimeAPILoop(endpoint, handler)
+	require.Equal(t, 2, record.nPosts)
+
+	assert.Equal(t, contentTypeHTTPIntegrationResponse, record.contentTypes[0])
+	assert.Equal(t, responseModeStreaming, record.responseModes[0])
+	assert.True(t, strings.HasSuffix(string(record.responses[0]), "streamed"))
+
+	assert.Equal(t, contentTypeJSON, record.contentTypes[1])
+	assert.Equal(t, "", record.responseModes[1])
+	assert.Equal(t, `"buffered"`, string(record.responses[1]))
+}
+
+type statusReader struct {
+	reader  io.Reader
+	status  int
+	headers http.Header
+}
+
+func (r *statusReader) Read(p []byte) (int, error) { return r.reader.Read(p) }
+
+func (r *statusReader) StatusCode() int      { return r.status }
+func (r *statusReader) Headers() http.Header { return r.headers }
+func (r *statusReader) ContentType() string  { return "text/csv" }
+
+type headersOnlyReader struct {
+	reader io.Reader
+}
+
+func (r *headersOnlyReader) Read(p []byte) (int, error) { return r.reader.Read(p) }
+
+func (r *headersOnlyReader) Headers() http.Header {
+	return http.Header{"X-Custom": {"a", "b"}}
+}
+
+func TestStreamingPreludeFromResponseInterfaces(t *testing.T) {
+	testCases := []struct {
+		name     string
+		response io.Reader
+		prelude  string
+	}{
+		{
+			name: "status, headers, and content type",
+			response: &statusReader{
+				reader:  strings.NewReader("a,b\n1,2\n"),
+				status:  http.StatusCreated,
+				headers: http.Header{"Cache-Control": {"no-store"}, "Set-Cookie": {"a=1", "b=2"}},
+			},
+			prelude: `{"statusCode":201,"headers":{"Cache-Control":"no-store","Content-Type":"text/csv"},"cookies":["a=1","b=2"]}`,
+		},
+		{
+			name:     "headers only",
+			response: &headersOnlyReader{strings.NewReader("a,b\n1,2\n")},
+			prelude:  `{"statusCode":200,"headers":{"X-Custom":"a, b"}}`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ts, record := runtimeAPIServer(`{}`, 1)
+			defer ts.Close()
+			handler := NewHandler(func() (io.Reader, error) {
+				return testCase.response, nil
+			})
+			_ = startRuntimeAPILoop(serverAddress(ts), handler)
+			require.Equal(t, 1, record.nPosts)
+
+			assert.Equal(t, contentTypeHTTPIntegrationResponse, record.contentTypes[0])
+			assert.Equal(t, responseModeStreaming, record.responseModes[0])
+			prelude, body, found := splitPrelude(string(record.responses[0]))
+			require.True(t, found)
+			assert.JSONEq(t, testCase.prelude, prelude)
+			assert.Equal(t, "a,b\n1,2\n", body)
+		})
+	}
+}
+
+type encodedReader struct {
+	reader   io.Reader
+	encoding string
+}
+
+func (r *encodedReader) Read(p []byte) (int, error) { return r.reader.Read(p) }
+func (r *encodedReader) ContentType() string        { return contentTypeJSON }
+func (r *encodedReader) ContentEncoding() string    { return r.encoding }
+
+func gzipped(t *testing.T, s string) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	_, err := w.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return b.Bytes()
+}
+
+func TestContentEncodingResponse(t *testing.T) {
+	compressed := gzipped(t, `{"hello":"world"}`)
+	testCases := []struct {
+		name    string
+		handler interface{}
+		prelude string
+	}{
+		{
+			name: "content encoding and content type",
+			handler: func() (io.Reader, error) {
+				return &encodedReader{bytes.NewReader(compressed), "gzip"}, nil
+			},
+			prelude: `{"statusCode":200,"headers":{"Content-Type":"application/json","Content-Encoding":"gzip"}}`,
+		},
+		{
+			name: "headers take precedence",
+			handler: func() (io.Reader, http.Header, error) {
+				return &encodedReader{bytes.NewReader(compressed), "gzip"}, http.Header{"Content-Encoding": {"x-gzip"}}, nil
+			},
+			prelude: `{"statusCode":200,"headers":{"Content-Type":"application/json","Content-Encoding":"x-gzip"}}`,
+		},
+		{
+			name: "content encoding with a content type metadata",
+			handler: func() (io.Reader, csvMetadata, error) {
+				return &encodedReader{bytes.NewReader(compressed), "gzip"}, csvMetadata{}, nil
+			},
+			prelude: `{"statusCode":200,"headers":{"Content-Type":"text/csv","Content-Encoding":"gzip"}}`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ts, record := runtimeAPIServer(`{}`, 1)
+			defer ts.Close()
+			_ = startRuntimeAPILoop(serverAddress(ts), NewHandler(testCase.handler))
+			require.Equal(t, 1, record.nPosts)
+
+			assert.Equal(t, contentTypeHTTPIntegrationResponse, record.contentTypes[0])
+			assert.Equal(t, responseModeStreaming, record.responseModes[0])
+			prelude, body, found := splitPrelude(string(record.responses[0]))
+			require.True(t, found)
+			assert.JSONEq(t, testCase.prelude, prelude)
+			r, err := gzip.NewReader(strings.NewReader(body))
+			require.NoError(t, err)
+			decompressed, err := ioutil.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, `{"hello":"world"}`, string(decompressed))
+		})
+	}
+}
+
+func TestEmptyContentEncodingResponse(t *testing.T) {
+	ts, record := runtimeAPIServer(`{}`, 1)
+	defer ts.Close()
+	handler := NewHandler(func() (io.Reader, error) {
+		return &encodedReader{strings.NewReader(`{"hello":"world"}`), ""}, nil
+	})
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 1, record.nPosts)
+	assert.Equal(t, contentTypeJSON, record.contentTypes[0])
+	assert.Equal(t, "", record.responseModes[0])
+	assert.Equal(t, `{"hello":"world"}`, string(record.responses[0]))
+}
+
+type csvMetadata struct{}
+
+func (csvMetadata) ContentType() string { return "text/csv" }
+
+func TestResponseMetadataReturnValue(t *testing.T) {
+	testCases := []struct {
+		name        string
+		handler     interface{}
+		contentType string
+		prelude     string
+		body        string
+	}{
+		{
+			name: "headers",
+			handler: func() (map[string]string, http.Header, error) {
+				return map[string]string{"hello": "world"}, http.Header{"Cache-Control": {"no-store"}, "Set-Cookie": {"a=1"}}, nil
+			},
+			contentType: contentTypeHTTPIntegrationResponse,
+			prelude:     `{"statusCode":200,"headers":{"Cache-Control":"no-store","Content-Type":"application/json"},"cookies":["a=1"]}`,
+			body:        `{"hello":"world"}`,
+		},
+		{
+			name: "header pointer",
+			handler: func(ctx context.Context) (string, *http.Header, error) {
+				return "hello", &http.Header{"X-Custom": {"a", "b"}}, nil
+			},
+			contentType: contentTypeHTTPIntegrationResponse,
+			prelude:     `{"statusCode":200,"headers":{"X-Custom":"a, b","Content-Type":"application/json"}}`,
+			body:        `"hello"`,
+		},
+		{
+			name: "status, headers, and content type",
+			handler: func() (io.Reader, *statusReader, error) {
+				return strings.NewReader("a,b\n1,2\n"), &statusReader{status: http.StatusCreated, headers: http.Header{"X-Custom": {"c"}}}, nil
+			},
+			contentType: contentTypeHTTPIntegrationResponse,
+			prelude:     `{"statusCode":201,"headers":{"X-Custom":"c","Content-Type":"text/csv"}}`,
+			body:        "a,b\n1,2\n",
+		},
+		{
+			name: "content type only",
+			handler: func() (io.Reader, csvMetadata, error) {
+				return strings.NewReader("a,b\n1,2\n"), csvMetadata{}, nil
+			},
+			contentType: "text/csv",
+			body:        "a,b\n1,2\n",
+		},
+		{
+			name: "nil headers",
+			handler: func() (string, *http.Header, error) {
+				return "hello", nil, nil
+			},
+			contentType: contentTypeJSON,
+			body:        `"hello"`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ts, record := runtimeAPIServer(`{}`, 1)
+			defer ts.Close()
+			_ = startRuntimeAPILoop(serverAddress(ts), NewHandler(testCase.handler))
+			require.Equal(t, 1, record.nPosts)
+
+			assert.Equal(t, testCase.contentType, record.contentTypes[0])
+			response := string(record.responses[0])
+			if testCase.prelude != "" {
+				prelude, body, found := splitPrelude(response)
+				require.True(t, found)
+				assert.JSONEq(t, testCase.prelude, prelude)
+				response = body
+			}
+			assert.Equal(t, testCase.body, response)
+		})
+	}
+}
+
+type createdMetadata struct{}
+
+func (createdMetadata) StatusCode() int      { return http.StatusCreated }
+func (createdMetadata) ContentType() string  { return "text/csv" }
+func (createdMetadata) Headers() http.Header { return http.Header{"Location": {"/items/1"}} }
+
+func TestResponseMetadataMergedIntoProxyResponses(t *testing.T) {
+	returned := &events.APIGatewayProxyResponse{StatusCode: 200, Headers: map[string]string{"X-Existing": "1"}, Body: "a,b"}
+	testCases := []struct {
+		name     string
+		handler  interface{}
+		expected string
+	}{
+		{
+			name: "rest api",
+			handler: func() (*events.APIGatewayProxyResponse, http.Header, error) {
+				return returned, http.Header{"Cache-Control": {"no-store"}, "Set-Cookie": {"a=1", "b=2"}, "Vary": {"Accept", "Origin"}}, nil
+			},
+			expected: `{"statusCode":200,"headers":{"X-Existing":"1","Cache-Control":"no-store"},"multiValueHeaders":{"Set-Cookie":["a=1","b=2"],"Vary":["Accept","Origin"]},"body":"a,b"}`,
+		},
+		{
+			name: "http api",
+			handler: func() (events.APIGatewayV2HTTPResponse, http.Header, error) {
+				return events.APIGatewayV2HTTPResponse{StatusCode: 200, Cookies: []string{"c=3"}}, http.Header{"Set-Cookie": {"a=1"}, "Vary": {"Accept", "Origin"}}, nil
+			},
+			expected: `{"statusCode":200,"headers":{"Vary":"Accept, Origin"},"multiValueHeaders":null,"body":"","cookies":["c=3","a=1"]}`,
+		},
+		{
+			name: "alb",
+			handler: func() (events.ALBTargetGroupResponse, createdMetadata, error) {
+				return events.ALBTargetGroupResponse{StatusCode: 200}, createdMetadata{}, nil
+			},
+			expected: `{"statusCode":201,"statusDescription":"","headers":{"Content-Type":"text/csv","Location":"/items/1"},"multiValueHeaders":null,"isBase64Encoded":false}`,
+		},
+		{
+			name: "function url",
+			handler: func() (*events.LambdaFunctionURLResponse, createdMetadata, error) {
+				return &events.LambdaFunctionURLResponse{StatusCode: 200}, createdMetadata{}, nil
+			},
+			expected: `{"statusCode":201,"headers":{"Content-Type":"text/csv","Location":"/items/1"},"body":"","isBase64Encoded":false,"cookies":null}`,
+		},
+		{
+			name: "nil metadata",
+			handler: func() (events.APIGatewayProxyResponse, *http.Header, error) {
+				return events.APIGatewayProxyResponse{StatusCode: 204}, nil, nil
+			},
+			expected: `{"statusCode":204,"headers":null,"multiValueHeaders":null,"body":""}`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ts, record := runtimeAPIServer(`{}`, 1)
+			defer ts.Close()
+			_ = startRuntimeAPILoop(serverAddress(ts), NewHandler(testCase.handler))
+			require.Equal(t, 1, record.nPosts)
+
+			assert.Equal(t, contentTypeJSON, record.contentTypes[0])
+			assert.Equal(t, "", record.responseModes[0])
+			assert.JSONEq(t, testCase.expected, string(record.responses[0]))
+		})
+	}
+	assert.Equal(t, &events.APIGatewayProxyResponse{StatusCode: 200, Headers: map[string]string{"X-Existing": "1"}, Body: "a,b"}, returned,
+		"the response returned by the handler should be left untouched")
+}
+
+func TestResponseMetadataWithError(t *testing.T) {
+	ts, record := runtimeAPIServer(`{}`, 1)
+	defer ts.Close()
+	handler := NewHandler(func() (string, http.Header, error) {
+		return "", http.Header{"X-Custom": {"a"}}, errors.New("failed")
+	})
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 1, record.nPosts)
+	assert.Contains(t, string(record.responses[0]), `"errorMessage":"failed"`)
+}
+
+type closeCounter struct {
+	io.Reader
+	closes int
+}
+
+func (c *closeCounter) Close() error {
+	c.closes++
+	return nil
+}
+
+func TestResponseClosedOnError(t *testing.T) {
+	ts, record := runtimeAPIServer(`{}`, 1)
+	defer ts.Close()
+	response := &closeCounter{Reader: strings.NewReader("partial")}
+	handler := NewHandler(func() (io.ReadCloser, error) {
+		return response, errors.New("failed after opening the response")
+	})
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 1, record.nPosts)
+	assert.Contains(t, string(record.responses[0]), `"errorMessage":"failed after opening the response"`)
+	assert.Equal(t, 1, response.closes)
+
+	response = &closeCounter{Reader: strings.NewReader("partial")}
+	_, err := handler.Invoke(context.Background(), []byte(`{}`))
+	assert.EqualError(t, err, "failed after opening the response")
+	assert.Equal(t, 1, response.closes)
+}
+
+func TestMaxPayloadBytes(t *testing.T) {
+	ts, record := runtimeAPIServer(`{"hello":"world"}`, 2)
+	defer ts.Close()
+	calls := 0
+	handler := newHandler(func(event interface{}) (interface{}, error) {
+		calls++
+		return event, nil
+	}, WithMaxPayloadBytes(8))
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 2, record.nPosts)
+	assert.Equal(t, 0, calls)
+	assert.JSONEq(t, `{"errorType":"Runtime.PayloadTooLarge","errorMessage":"payload of 17 bytes exceeds the maximum of 8 bytes"}`, string(record.responses[0]))
+	assert.Equal(t, "/2018-06-01/runtime/invocation/dummyid/error", record.postPaths[0])
+
+	// payloads within the limit are unaffected
+	ts, record = runtimeAPIServer(`{"a":1}`, 1)
+	defer ts.Close()
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 1, record.nPosts)
+	assert.Equal(t, 1, calls)
+	assert.JSONEq(t, `{"a":1}`, string(record.responses[0]))
+}
+
+func TestCrashReporter(t *testing.T) {
+	ts, record := runtimeAPIServer(`{}`, 3)
+	defer ts.Close()
+	var reported []*messages.InvokeResponse_Error
+	postsWhenReported := -1
+	handler := newHandler(func(ctx context.Context, event map[string]bool) error {
+		if event["fail"] {
+			return errors.New("not a crash")
+		}
+		panic("oops")
+	}, WithCrashReporter(func(ctx context.Context, invokeErr *messages.InvokeResponse_Error) {
+		lc, ok := lambdacontext.FromContext(ctx)
+		require.True(t, ok)
+		assert.Equal(t, "dummyid", lc.AwsRequestID)
+		reported = append(reported, invokeErr)
+		postsWhenReported = record.nPosts
+	}))
+	err := startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.EqualError(t, err, "calling the handler function resulted in a panic, the process should exit")
+	require.Len(t, reported, 1)
+	assert.Equal(t, "oops", reported[0].Message)
+	assert.True(t, reported[0].ShouldExit)
+	assert.Equal(t, 1, postsWhenReported, "the crash should be reported after the failure is sent to the Runtime API")
+
+	// errors not requiring an exit are not crashes
+	reported = nil
+	ts, _ = runtimeAPIServer(`{"fail":true}`, 1)
+	defer ts.Close()
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.Empty(t, reported)
+}
+
+func TestExitOnEOF(t *testing.T) {
+	newServer := func() *httptest.Server {
+		served := false
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			if served {
+				// simulate an emulator shutting down after a single invoke
+				conn, _, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				_ = conn.Close()
+				return
+			}
+			served = true
+			w.Header().Add(headerAWSRequestID, "dummyid")
+			w.Header().Add(headerDeadlineMS, "22")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}))
+	}
+
+	t.Run("WithExitOnEOF() returns nil", func(t *testing.T) {
+		ts := newServer()
+		defer ts.Close()
+		handler := NewHandlerWithOptions(func() error { return nil }, WithExitOnEOF())
+		assert.NoError(t, startRuntimeAPILoop(serverAddress(ts), handler))
+	})
+
+	t.Run("default returns the EOF error", func(t *testing.T) {
+		ts := newServer()
+		defer ts.Close()
+		handler := NewHandler(func() error { return nil })
+		err := startRuntimeAPILoop(serverAddress(ts), handler)
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Contains(t, err.Error(), "failed to get the next invoke")
+	})
+}
+
+func TestNextRetry(t *testing.T) {
+	newFlakyServer := func(failures int32) (*httptest.Server, *int32) {
+		// the count is read by the test after the loop sees the dropped connection, which the race detector cannot follow
+		gets := new(int32)
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			n := atomic.AddInt32(gets, 1)
+			if n <= failures || n > failures+1 {
+				// drop the connection, as during a cold start race, and after the first invoke to stop the loop
+				conn, _, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				_ = conn.Close()
+				return
+			}
+			w.Header().Add(headerAWSRequestID, "dummyid")
+			w.Header().Add(headerDeadlineMS, "22")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		})), gets
+	}
+
+	t.Run("retries until the next invoke is received", func(t *testing.T) {
+		ts, gets := newFlakyServer(1)
+		defer ts.Close()
+		invokes := 0
+		handler := NewHandlerWithOptions(func() error {
+			invokes++
+			return nil
+		}, WithNextRetry(1, time.Millisecond))
+		err := startRuntimeAPILoop(serverAddress(ts), handler)
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, 1, invokes)
+		assert.GreaterOrEqual(t, atomic.LoadInt32(gets), int32(3))
+	})
+
+	t.Run("returns the error once the retries are exhausted", func(t *testing.T) {
+		ts, gets := newFlakyServer(3)
+		defer ts.Close()
+		handler := NewHandlerWithOptions(func() error { return nil }, WithNextRetry(2, time.Millisecond))
+		err := startRuntimeAPILoop(serverAddress(ts), handler)
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, int32(3), atomic.LoadInt32(gets))
+	})
+
+	t.Run("default does not retry", func(t *testing.T) {
+		ts, gets := newFlakyServer(1)
+		defer ts.Close()
+		err := startRuntimeAPILoop(serverAddress(ts), NewHandler(func() error { return nil }))
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, int32(1), atomic.LoadInt32(gets))
+	})
+}
+
+func TestNextRetryCanceled(t *testing.T) {
+	calls := 0
+	client := newRuntimeAPIClient("localhost")
+	client.nextRetry = retryPolicy{retries: 3, backoff: time.Millisecond}
+	client.httpClient.Transport = roundTripFunc(func(*http.Request) (*http.Response, error) {
+		calls++
+		return nil, context.Canceled
+	})
+	_, err := client.next()
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type declaredLengthReader struct {
+	reader io.Reader
+	length int64
+}
+
+func (r *declaredLengthReader) Read(p []byte) (int, error) { return r.reader.Read(p) }
+func (r *declaredLengthReader) ContentLength() int64       { return r.length }
+
+type declaredLengthBuffer struct {
+	buffer *bytes.Buffer
+	length int64
+}
+
+func (b *declaredLengthBuffer) Read(p []byte) (int, error) { return b.buffer.Read(p) }
+func (b *declaredLengthBuffer) Len() int                   { return b.buffer.Len() }
+func (b *declaredLengthBuffer) ContentLength() int64       { return b.length }
+
+type declaredLengthStatusReader struct {
+	declaredLengthReader
+}
+
+func (r *declaredLengthStatusReader) StatusCode() int { return http.StatusCreated }
+
+func TestStrictContentLength(t *testing.T) {
+	testCases := []struct {
+		name     string
+		response io.Reader
+		body     string
+		error    string
+	}{
+		{
+			name:     "matching length",
+			response: &declaredLengthReader{strings.NewReader("hello"), 5},
+			body:     "hello",
+		},
+		{
+			name:     "matching length of a buffer",
+			response: &declaredLengthBuffer{bytes.NewBufferString("hello"), 5},
+			body:     "hello",
+		},
+		{
+			name:     "longer than declared",
+			response: &declaredLengthReader{strings.NewReader("hello world"), 5},
+			error:    "response declares a content length of 5 bytes, but has more",
+		},
+		{
+			name:     "shorter than declared",
+			response: &declaredLengthReader{strings.NewReader("hi"), 5},
+			error:    "response declares a content length of 5 bytes, but has 2 bytes",
+		},
+		{
+			name:     "buffer longer than declared",
+			response: &declaredLengthBuffer{bytes.NewBufferString("hello world"), 5},
+			error:    "response declares a content length of 5 bytes, but has 11 bytes",
+		},
+		{
+			name:     "streamed body",
+			response: &declaredLengthStatusReader{declaredLengthReader{strings.NewReader("hello"), 5}},
+			body:     "hello",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mux := NewInvokeMux(func() (io.Reader, error) {
+				return testCase.response, nil
+			}, WithStrictContentLength())
+			result := mux.Invoke([]byte(`{}`), nil)
+			require.NoError(t, result.Err)
+			if testCase.error != "" {
+				assert.Contains(t, string(result.Error), testCase.error)
+				return
+			}
+			assert.Nil(t, result.Error)
+			assert.True(t, strings.HasSuffix(string(result.Response), testCase.body))
+		})
+	}
+}
+
+func TestContentLengthIgnoredByDefault(t *testing.T) {
+	mux := NewInvokeMux(func() (io.Reader, error) {
+		return &declaredLengthReader{strings.NewReader("hello world"), 5}, nil
+	})
+	result := mux.Invoke([]byte(`{}`), nil)
+	assert.Nil(t, result.Error)
+	assert.Equal(t, "hello world", string(result.Response))
+}
+
+func TestMaxErrorMessageLen(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	handler := NewHandlerWithOptions(func() error {
+		return errors.New(strings.Repeat("a", 100))
+	}, WithMaxErrorMessageLen(10))
+	endpoint := strings.Split(ts.URL, "://")[1]
+	_ = startRuntimeAPILoop(endpoint, handler)
+	require.Equal(t, 1, record.nPosts)
+
+	var invokeErr messages.InvokeResponse_Error
+	require.NoError(t, json.Unmarshal(record.responses[0], &invokeErr))
+	assert.Equal(t, "aaaaaaaaaa...", invokeErr.Message)
+	assert.Contains(t, record.xrayCauses[0], `"message":"aaaaaaaaaa..."`)
+}
+
+func TestTruncateErrorMessage(t *testing.T) {
+	assert.Equal(t, "short", truncateErrorMessage("short", 5))
+	assert.Equal(t, "shor...", truncateErrorMessage("short", 4))
+	assert.Equal(t, "...", truncateErrorMessage("short", 0))
+	// "é" is two bytes, and is not split
+	assert.Equal(t, "caf...", truncateErrorMessage("café au lait", 4))
+	assert.Equal(t, "café...", truncateErrorMessage("café au lait", 5))
+}
+
+func TestStartupSelfTest(t *testing.T) {
+	t.Run("reachable", func(t *testing.T) {
+		ts, record := runtimeAPIServer(``, 1)
+		defer ts.Close()
+		handler := NewHandlerWithOptions(func() error { return nil }, WithStartupSelfTest())
+		err := startRuntimeAPILoop(serverAddress(ts), handler)
+		assert.Contains(t, err.Error(), "failed to GET")
+		assert.Equal(t, 2, record.nGets)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		ts := httptest.NewServer(http.NotFoundHandler())
+		address := serverAddress(ts)
+		ts.Close()
+		handler := NewHandlerWithOptions(func() error { return nil }, WithStartupSelfTest())
+		err := startRuntimeAPILoop(address, handler)
+		require.Error(t, err)
+		assert.True(t, strings.HasPrefix(err.Error(), "startup self test failed, the Runtime API at "+address+" is unreachable: "), err.Error())
+	})
+}
+
+func TestInvokeStartLog(t *testing.T) {
+	deadline := strconv.FormatInt(time.Now().Add(time.Minute).UnixNano()/int64(time.Millisecond), 10)
+	metadata := []eventMetadata{defaultInvokeMetadata(), defaultInvokeMetadata()}
+	metadata[0].requestID, metadata[0].deadline = "first", deadline
+	metadata[1].requestID, metadata[1].deadline = "second", deadline
+	ts, record := runtimeAPIServer(``, 2, metadata...)
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	var linesAtEntry []string
+	handler := NewHandlerWithOptions(func() error {
+		linesAtEntry = append(linesAtEntry, buf.String())
+		return nil
+	}, WithInvokeStartLog(&buf))
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 2, record.nPosts)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{lines[0] + "\n", buf.String()}, linesAtEntry, "the line is written before the handler is called")
+
+	var first, second invokeStartLine
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, "invoke.start", first.Type)
+	assert.Equal(t, "first", first.RequestID)
+	assert.True(t, first.ColdStart)
+	assert.Equal(t, "second", second.RequestID)
+	assert.False(t, second.ColdStart)
+	assert.Greater(t, first.RemainingTimeMS, int64(55*1000))
+	assert.LessOrEqual(t, first.RemainingTimeMS, int64(60*1000))
+}
+
+func TestStructuredLogging(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	handler := NewHandlerWithOptions(func() error {
+		return errors.New("table not found")
+	}, WithStructuredLogging(true))
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+
+	require.Equal(t, 1, record.nPosts)
+	assert.JSONEq(t, `{"errorMessage":"table not found","errorType":"errorString"}`, string(record.responses[0]))
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	var failureLog map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &failureLog))
+	assert.Equal(t, "ERROR", failureLog["level"])
+	assert.Equal(t, "dummyid", failureLog["requestId"])
+	assert.Equal(t, "errorString", failureLog["errorType"])
+	assert.Equal(t, "table not found", failureLog["errorMessage"])
+	timestamp, err := time.Parse(time.RFC3339, failureLog["timestamp"].(string))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), timestamp, time.Minute)
+	assert.NotContains(t, failureLog, "stackTrace")
+}
+
+func TestStructuredFailureLog(t *testing.T) {
+	invokeErr := &messages.InvokeResponse_Error{
+		Type:       "Runtime.Panic",
+		Message:    "boom",
+		StackTrace: []*messages.InvokeResponse_Error_StackFrame{{Path: "main.go", Line: 7, Label: "main"}},
+	}
+	line := structuredFailureLog("abc-123", invokeErr, time.Date(2023, 5, 4, 3, 2, 1, 5e6, time.FixedZone("PDT", -7*60*60)))
+	assert.JSONEq(t, `{
+		"timestamp": "2023-05-04T10:02:01.005Z",
+		"level": "ERROR",
+		"requestId": "abc-123",
+		"errorType": "Runtime.Panic",
+		"errorMessage": "boom",
+		"stackTrace": [{"path": "main.go", "line": 7, "label": "main"}]
+	}`, string(line))
+	assert.NotContains(t, string(line), "\n")
+}
+
+func TestStructuredLoggingDisabledByDefault(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	ts, _ := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	handler := NewHandler(func() error {
+		return errors.New("table not found")
+	})
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+
+	assert.Contains(t, logs.String(), `{"errorMessage":"table not found","errorType":"errorString"}`)
+	assert.NotContains(t, logs.String(), `"level"`)
+}
+
+func TestInvokeHeadersInContext(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	var seen http.Header
+	handler := newHandler(func(ctx context.Context) (string, error) {
+		headers, ok := lambdacontext.HeadersFromContext(ctx)
+		require.True(t, ok)
+		seen = headers
+		headers.Set("X-Custom-Header", "changed by the handler")
+		again, _ := lambdacontext.HeadersFromContext(ctx)
+		return again.Get("X-Custom-Header"), nil
+	})
+	headers := http.Header{}
+	headers.Set(headerAWSRequestID, "abc-123")
+	headers.Set(headerDeadlineMS, strconv.FormatInt(time.Now().Add(time.Minute).UnixNano()/int64(time.Millisecond), 10))
+	headers.Set("X-Custom-Header", "not modeled")
+	fakeInvoke := &invoke{
+		id:      "abc-123",
+		payload: []byte(`{}`),
+		headers: headers,
+		client:  newRuntimeAPIClient(serverAddress(ts)),
+	}
+	require.NoError(t, handleInvoke(fakeInvoke, handler))
+
+	assert.Equal(t, "abc-123", seen.Get(headerAWSRequestID))
+	require.Equal(t, 1, record.nPosts)
+	assert.Equal(t, `"not modeled"`, string(record.responses[0]))
+	assert.Equal(t, "not modeled", headers.Get("X-Custom-Header"))
+}
+
+func TestIdentityFromCognitoIdentityHeader(t *testing.T) {
+	mux := NewInvokeMux(func(ctx context.Context) (string, error) {
+		identity, ok := lambdacontext.IdentityFromContext(ctx)
+		if !ok {
+			return "none", nil
+		}
+		return fmt.Sprintf("%s %t", identity.PoolID(), identity.IsAnonymous()), nil
+	})
+	result := mux.Invoke([]byte(`{}`), http.Header{headerCognitoIdentity: {`{"cognitoIdentityId":"us-east-1:1234","cognitoIdentityPoolId":"us-east-1:pool"}`}})
+	assert.Equal(t, `"us-east-1:pool false"`, string(result.Response))
+	result = mux.Invoke([]byte(`{}`), http.Header{headerCognitoIdentity: {`{"cognitoIdentityPoolId":"us-east-1:pool"}`}})
+	assert.Equal(t, `"us-east-1:pool true"`, string(result.Response))
+	result = mux.Invoke([]byte(`{}`), nil)
+	assert.Equal(t, `"none"`, string(result.Response))
+}
+
+func TestInvokeMuxRunsBetweenInvokes(t *testing.T) {
+	var events []string
+	buffer := metrics.NewBuffer("Service", 0, func(ctx context.Context, namespace string, data []metrics.Datum) error {
+		events = append(events, fmt.Sprintf("publish data=%d", len(data)))
+		return nil
+	})
+	mux := NewInvokeMux(func(ctx context.Context, fail bool) error {
+		metrics.Collector(ctx).Add("Processed", 1, "Count", nil)
+		events = append(events, "invoke")
+		if fail {
+			panic("oops")
+		}
+		return nil
+	},
+		WithMetrics(buffer),
+		WithBetweenInvokes(func(ctx context.Context) {
+			events = append(events, "between")
+		}),
+	)
+	result := mux.Invoke([]byte(`false`), nil)
+	require.NoError(t, result.Err)
+	result = mux.Invoke([]byte(`true`), nil)
+	assert.Error(t, result.Err)
+	assert.Equal(t, []string{"invoke", "publish data=1", "between", "invoke", "publish data=1"}, events)
+}
+
+func TestPreInvoke(t *testing.T) {
+	type requestKey struct{}
+	mux := NewInvokeMux(
+		func(ctx context.Context) (string, error) {
+			value, _ := ctx.Value(requestKey{}).(string)
+			return value, nil
+		},
+		WithPreInvoke(func(ctx context.Context, headers http.Header) context.Context {
+			lc, ok := lambdacontext.FromContext(ctx)
+			require.True(t, ok)
+			if headers.Get("X-Skip") != "" {
+				return nil
+			}
+			return context.WithValue(ctx, requestKey{}, lc.AwsRequestID+" "+headers.Get(headerAWSRequestID))
+		}),
+	)
+
+	result := mux.Invoke([]byte(`{}`), http.Header{headerAWSRequestID: {"abc-123"}})
+	require.NoError(t, result.Err)
+	assert.Equal(t, `"abc-123 abc-123"`, string(result.Response))
+
+	result = mux.Invoke([]byte(`{}`), http.Header{"X-Skip": {"true"}})
+	require.NoError(t, result.Err)
+	assert.Nil(t, result.Error)
+	assert.Equal(t, `""`, string(result.Response))
+}
+
+func TestErrorFormatter(t *testing.T) {
+	redact := func(err error) *messages.InvokeResponse_Error {
+		var invokeErr messages.InvokeResponse_Error
+		if errors.As(err, &invokeErr) {
+			return &messages.InvokeResponse_Error{Type: invokeErr.Type, Message: "redacted panic " + invokeErr.Message}
+		}
+		if err.Error() == "not found" {
+			return nil
+		}
+		return &messages.InvokeResponse_Error{Type: "InternalError"}
+	}
+	testCases := []struct {
+		name       string
+		handler    func() error
+		expected   string
+		shouldExit bool
+	}{
+		{"message stripped", func() error { return errors.New("user 1234 not found in table users") }, `{"errorType":"InternalError","errorMessage":""}`, false},
+		{"nil falls back to the default", func() error { return errors.New("not found") }, `{"errorType":"errorString","errorMessage":"not found"}`, false},
+		{"panicked error", func() error { panic(errors.New("user 1234 not found")) }, `{"errorType":"InternalError","errorMessage":""}`, true},
+		{"panicked value", func() error { panic("boom") }, `{"errorType":"string","errorMessage":"redacted panic boom"}`, true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ts, record := runtimeAPIServer(``, 1)
+			defer ts.Close()
+			handler := NewHandlerWithOptions(testCase.handler, WithErrorFormatter(redact))
+			err := startRuntimeAPILoop(serverAddress(ts), handler)
+			if testCase.shouldExit {
+				assert.EqualError(t, err, "calling the handler function resulted in a panic, the process should exit")
+			} else {
+				assert.Equal(t, 2, record.nGets)
+			}
+			require.Equal(t, 1, record.nPosts)
+			assert.JSONEq(t, testCase.expected, string(record.responses[0]))
+		})
+	}
+}
+
+func TestCorrelationHeader(t *testing.T) {
+	testCases := []struct {
+		name     string
+		payload  string
+		expected string
+	}{
+		{"http api event header", `{"headers":{"x-request-id":"from-event"}}`, "from-event"},
+		{"rest api multi-value header", `{"multiValueHeaders":{"X-Request-Id":["first","second"]}}`, "first"},
+		{"falls back to the request id", `{"headers":{"x-other":"value"}}`, "dummyid"},
+		{"not an http event", `"hello"`, "dummyid"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ts, record := runtimeAPIServer(testCase.payload, 1)
+			defer ts.Close()
+			var logs bytes.Buffer
+			logger := log.New(&logs, "", 0)
+			handler := NewHandlerWithOptions(func(ctx context.Context) (string, error) {
+				logger.Printf("correlation id: %s", lambdacontext.CorrelationID(ctx))
+				return lambdacontext.CorrelationID(ctx), nil
+			}, WithCorrelationHeader("X-Request-Id"))
+			_ = startRuntimeAPILoop(serverAddress(ts), handler)
+			require.Equal(t, 1, record.nPosts)
+			assert.Equal(t, strconv.Quote(testCase.expected), string(record.responses[0]))
+			assert.Equal(t, "correlation id: "+testCase.expected+"\n", logs.String())
+		})
+	}
+}
+
+func TestCorrelationIDPrefersInvokeHeaders(t *testing.T) {
+	fakeInvoke := &invoke{
+		headers: http.Header{"X-Request-Id": {"from-invoke"}},
+		payload: []byte(`{"headers":{"x-request-id":"from-event"}}`),
+	}
+	assert.Equal(t, "from-invoke", correlationID(fakeInvoke, "x-request-id"))
+}
+
+func TestCorrelationIDSkipsEventsWithoutHeaders(t *testing.T) {
+	fakeInvoke := &invoke{
+		headers: http.Header{},
+		payload: []byte(`{"Records":[{"body":"` + strings.Repeat("x", 64*1024) + `"}]}`),
+	}
+	var id string
+	allocs := testing.AllocsPerRun(10, func() {
+		id = correlationID(fakeInvoke, "X-Request-Id")
+	})
+	assert.Equal(t, "", id)
+	assert.Zero(t, allocs, "an event without headers should not be decoded")
+}
+
+func TestRemainingTimeInHandler(t *testing.T) {
+	handler := func(ctx context.Context) (time.Duration, error) {
+		return lambdacontext.RemainingTime(ctx), nil
+	}
+
+	// the deadline of the test server's invokes is in 1970
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	_ = startRuntimeAPILoop(serverAddress(ts), NewHandler(handler))
+	require.Equal(t, 1, record.nPosts)
+	assert.Equal(t, "0", string(record.responses[0]))
+
+	deadline := strconv.FormatInt(time.Now().Add(time.Minute).UnixNano()/nsPerMS, 10)
+	result := NewInvokeMux(handler).Invoke(nil, http.Header{headerDeadlineMS: {deadline}})
+	remaining, err := strconv.ParseInt(string(result.Response), 10, 64)
+	require.NoError(t, err)
+	assert.True(t, time.Duration(remaining) > 58*time.Second && time.Duration(remaining) <= time.Minute, result.Response)
+}
+
+func TestParseDeadlineIsMonotonic(t *testing.T) {
+	deadlineMS := time.Now().Add(time.Minute).UnixNano() / nsPerMS
+	deadline, err := parseDeadline(&invoke{headers: http.Header{headerDeadlineMS: {strconv.FormatInt(deadlineMS, 10)}}})
+	require.NoError(t, err)
+	assert.Equal(t, deadlineMS, deadline.UnixNano()/nsPerMS)
+	assert.True(t, deadline.Equal(unixMS(deadlineMS)))
+	assert.NotEqual(t, deadline.String(), deadline.Round(0).String(), "the deadline should carry a monotonic clock reading")
+}
+
+func TestStackFilter(t *testing.T) {
+	invokeErr := messages.InvokeResponse_Error{
+		Type:    "Runtime.Panic",
+		Message: "boom",
+		StackTrace: []*messages.InvokeResponse_Error_StackFrame{
+			{Label: "main.handler", Path: "example.com/orders/main.go", Line: 21},
+			{Label: "Value.call", Path: "reflect/value.go", Line: 556},
+			{Label: "reflectHandler.func1", Path: "github.com/aws/aws-lambda-go/lambda/handler.go", Line: 300},
+			{Label: "store.save", Path: "example.com/orders/store.go", Line: 7},
+			{Label: "callBytesHandlerFunc", Path: "github.com/aws/aws-lambda-go@v1.41.0/lambda/invoke_loop.go", Line: 320},
+			{Label: "main.handler.func1", Path: "example.com/orders/main.go", Line: 30},
+		},
+	}
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	handler := NewHandlerWithOptions(func() error {
+		return invokeErr
+	}, WithStackFilter(SkipRuntimeFrames))
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+
+	require.Equal(t, 1, record.nPosts)
+	wd, _ := os.Getwd()
+	assert.JSONEq(t, `{
+		"working_directory": "`+wd+`",
+		"paths": ["example.com/orders/main.go", "example.com/orders/store.go"],
+		"exceptions": [{
+			"type": "Runtime.Panic",
+			"message": "boom",
+			"stack": [
+				{"label": "main.handler", "path": "example.com/orders/main.go", "line": 21},
+				{"label": "store.save", "path": "example.com/orders/store.go", "line": 7},
+				{"label": "main.handler.func1", "path": "example.com/orders/main.go", "line": 30}
+			]
+		}]
+	}`, record.xrayCauses[0])
+	var response messages.InvokeResponse_Error
+	require.NoError(t, json.Unmarshal(record.responses[0], &response))
+	assert.Len(t, response.StackTrace, 6, "the error response keeps every frame")
+}
+
+func TestStackFilterCustom(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	handler := NewHandlerWithOptions(func() error {
+		panic("boom")
+	}, WithStackFilter(func(messages.InvokeResponse_Error_StackFrame) bool { return false }))
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+
+	require.Equal(t, 1, record.nPosts)
+	var cause xrayError
+	require.NoError(t, json.Unmarshal([]byte(record.xrayCauses[0]), &cause))
+	assert.Empty(t, cause.Paths)
+	assert.Empty(t, cause.Exceptions[0].Stack)
+	assert.NotNil(t, cause.Exceptions[0].Stack)
+}
+
+type notFoundError struct{ key string }
+
+func (e *notFoundError) Error() string { return e.key + " not found" }
+
+type multiError []error
+
+func (m multiError) Error() string   { return "multiple errors" }
+func (m multiError) Unwrap() []error { return m }
+
+func TestXRayErrorCauses(t *testing.T) {
+	testCases := []struct {
+		name       string
+		err        error
+		exceptions string
+	}{
+		{
+			name: "wrapped error",
+			err:  fmt.Errorf("failed to load the order: %w", &notFoundError{"order-1"}),
+			exceptions: `[
+				{"id": "0000000000000001", "type": "wrapError", "message": "failed to load the order: order-1 not found", "stack": [], "cause": "0000000000000002"},
+				{"id": "0000000000000002", "type": "notFoundError", "message": "order-1 not found", "stack": []}
+			]`,
+		},
+		{
+			name: "error wrapping several errors",
+			err:  multiError{&notFoundError{"a"}, fmt.Errorf("retry: %w", &notFoundError{"b"})},
+			exceptions: `[
+				{"id": "0000000000000001", "type": "multiError", "message": "multiple errors", "stack": [], "cause": "0000000000000002"},
+				{"id": "0000000000000002", "type": "notFoundError", "message": "a not found", "stack": []},
+				{"id": "0000000000000003", "type": "wrapError", "message": "retry: b not found", "stack": [], "cause": "0000000000000004"},
+				{"id": "0000000000000004", "type": "notFoundError", "message": "b not found", "stack": []}
+			]`,
+		},
+		{
+			name:       "simple error",
+			err:        &notFoundError{"order-1"},
+			exceptions: `[{"type": "notFoundError", "message": "order-1 not found", "stack": []}]`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ts, record := runtimeAPIServer(``, 1)
+			defer ts.Close()
+			_ = startRuntimeAPILoop(serverAddress(ts), NewHandler(func() error { return testCase.err }))
+			require.Equal(t, 1, record.nPosts)
+			var cause struct {
+				Exceptions json.RawMessage `json:"exceptions"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(record.xrayCauses[0]), &cause))
+			assert.JSONEq(t, testCase.exceptions, string(cause.Exceptions))
+			assert.NotContains(t, string(record.responses[0]), "cause", "the error response is unchanged")
+		})
+	}
+}
+
+func TestInvokeTimeout(t *testing.T) {
+	var remaining time.Duration
+	handler := func(ctx context.Context) error {
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok)
+		remaining = time.Until(deadline)
+		return nil
+	}
+	deadlineIn := func(d time.Duration) http.Header {
+		deadline := time.Now().Add(d).UnixNano() / nsPerMS
+		return http.Header{headerDeadlineMS: {strconv.FormatInt(deadline, 10)}}
+	}
+
+	t.Run("invoke deadline is sooner", func(t *testing.T) {
+		result := NewInvokeMux(handler, WithInvokeTimeout(time.Hour)).Invoke([]byte(`{}`), deadlineIn(time.Minute))
+		assert.Nil(t, result.Error)
+		assert.True(t, remaining > 50*time.Second && remaining <= time.Minute, remaining)
+	})
+
+	t.Run("invoke timeout is sooner", func(t *testing.T) {
+		result := NewInvokeMux(handler, WithInvokeTimeout(time.Second)).Invoke([]byte(`{}`), deadlineIn(time.Minute))
+		assert.Nil(t, result.Error)
+		assert.True(t, remaining > 0 && remaining <= time.Second, remaining)
+	})
+
+	t.Run("unparseable deadline falls back to the invoke timeout", func(t *testing.T) {
+		headers := http.Header{headerDeadlineMS: {"soon"}}
+		result := NewInvokeMux(handler, WithInvokeTimeout(time.Second)).Invoke([]byte(`{}`), headers)
+		assert.Nil(t, result.Error)
+		assert.True(t, remaining > 0 && remaining <= time.Second, remaining)
+
+		result = NewInvokeMux(handler).Invoke([]byte(`{}`), headers)
+		assert.Contains(t, string(result.Error), "failed to parse deadline")
+	})
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil" //nolint: staticcheck
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const invokeMuxDefaultTimeout = 15 * time.Minute
+
+// InvokeMux sends invokes to a handler through the same code path as Start, without a Runtime API server or any socket,
+// so that tests exercise the handler's real decoding, encoding, and error reporting.
+// Each invoke is followed by the WithMetrics flush and, unless it requires the process to exit, by the WithBetweenInvokes callback.
+// What Start does before the first invoke is skipped: the WithRequiredEnv checks, the loading of WithEnvConfig,
+// the WithInitTimeoutHandler timer and the WithStartupSelfTest check.
+// See the lambdatest package for a harness built on it. An InvokeMux must not be used by concurrent goroutines.
+type InvokeMux struct {
+	handler  *handlerOptions
+	client   *runtimeAPIClient
+	api      *inMemoryRuntimeAPI
+	nInvokes int
+}
+
+// InvokeResult is the outcome of an invoke sent with InvokeMux.Invoke.
+type InvokeResult struct {
+	RequestID   string // RequestID is the request id of the invoke
+	Response    []byte // Response is the body of the response, and is nil when the invoke failed before responding
+	ContentType string // ContentType is the content type of the response, or of the error
+	Streamed    bool   // Streamed is true when the response was sent as a stream
+	Error       []byte // Error is the serialized error of a failed invoke, including one that failed while streaming its response
+	Err         error  // Err is set when the invoke requires the process to exit, such as after a panic, as Start would then exit
+}
+
+// NewInvokeMux returns an InvokeMux calling handler, which may be any handler accepted by Start or a Handler, with the options of StartWithOptions.
+func NewInvokeMux(handler interface{}, options ...Option) *InvokeMux {
+	api := &inMemoryRuntimeAPI{}
+	client := newRuntimeAPIClient("lambda.invoke-mux")
+	client.httpClient = &http.Client{Transport: api}
+	return &InvokeMux{
+		handler: newHandler(handler, options...),
+		client:  client,
+		api:     api,
+	}
+}
+
+// Invoke sends the payload to the handler, along with the headers of the Runtime API's next invocation response,
+// such as Lambda-Runtime-Invoked-Function-Arn or Lambda-Runtime-Client-Context.
+// The Lambda-Runtime-Aws-Request-Id header defaults to "invoke-1", "invoke-2", and so on,
+// and the Lambda-Runtime-Deadline-Ms header to 15 minutes after the call.
+func (m *InvokeMux) Invoke(payload []byte, headers http.Header) InvokeResult {
+	m.nInvokes++
+	headers = headers.Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+	if headers.Get(headerAWSRequestID) == "" {
+		headers.Set(headerAWSRequestID, "invoke-"+strconv.Itoa(m.nInvokes))
+	}
+	if headers.Get(headerDeadlineMS) == "" {
+		deadline := time.Now().Add(invokeMuxDefaultTimeout).UnixNano() / nsPerMS
+		headers.Set(headerDeadlineMS, strconv.FormatInt(deadline, 10))
+	}
+	invoke := &invoke{
+		id:      headers.Get(headerAWSRequestID),
+		payload: payload,
+		headers: headers,
+		client:  m.client,
+	}
+	m.api.result = InvokeResult{RequestID: invoke.id}
+	err := handleInvoke(invoke, m.handler)
+	if err == nil && m.handler.betweenInvokes != nil {
+		m.handler.betweenInvokes(m.handler.baseContext)
+	}
+	result := m.api.result
+	result.Err = err
+	return result
+}
+
+// inMemoryRuntimeAPI is an http.RoundTripper receiving the responses and errors posted by runtimeAPIClient
+type inMemoryRuntimeAPI struct {
+	result InvokeResult
+}
+
+func (api *inMemoryRuntimeAPI) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost {
+		return nil, fmt.Errorf("unexpected %s request to %s", req.Method, req.URL)
+	}
+	var body []byte
+	var err error
+	if req.Body != nil {
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+	}
+	api.result.ContentType = req.Header.Get("Content-Type")
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/response"):
+		api.result.Response = body
+		api.result.Streamed = req.Header.Get(headerResponseMode) == responseModeStreaming
+		if encoded := req.Trailer.Get(trailerLambdaErrorBody); encoded != "" {
+			if api.result.Error, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+				return nil, err
+			}
+		}
+	case strings.HasSuffix(req.URL.Path, "/error"):
+		api.result.Error = body
+	default:
+		return nil, fmt.Errorf("unexpected request to %s", req.URL)
+	}
+	return &http.Response{
+		StatusCode: http.StatusAccepted,
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+		Request:    req,
+	}, nil
+}
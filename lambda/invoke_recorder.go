@@ -0,0 +1,58 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"io"
+	"io/ioutil" //nolint: staticcheck
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// invokeRecorder writes the payload, response, and error of each invoke to files in dir.
+// Files for an invoke share the prefix <timestamp>-<request id>, and end in .request, .response, or .error.
+type invokeRecorder struct {
+	dir     string
+	now     func() time.Time
+	current string
+}
+
+func newInvokeRecorder(dir string) *invokeRecorder {
+	return &invokeRecorder{dir: dir, now: time.Now}
+}
+
+// start records the payload of a new invoke. Following calls to recordResponse and recordError are recorded for this invoke.
+func (r *invokeRecorder) start(requestID string, payload []byte) {
+	r.current = filepath.Join(r.dir, r.now().UTC().Format("20060102T150405.000000000Z")+"-"+filepath.Base(requestID))
+	r.write(".request", payload)
+}
+
+func (r *invokeRecorder) recordError(errorPayload []byte) {
+	r.write(".error", errorPayload)
+}
+
+// recordResponse returns a reader that copies the response to a file as it is read, and a function closing that file.
+func (r *invokeRecorder) recordResponse(response io.Reader) (io.Reader, func()) {
+	file, err := os.Create(r.current + ".response")
+	if err != nil {
+		log.Printf("WARNING! failed to record the invoke response: %v", err)
+		return response, func() {}
+	}
+	closeFile := func() {
+		if err := file.Close(); err != nil {
+			log.Printf("WARNING! failed to record the invoke response: %v", err)
+		}
+	}
+	if response == nil {
+		return response, closeFile
+	}
+	return io.TeeReader(response, file), closeFile
+}
+
+func (r *invokeRecorder) write(suffix string, b []byte) {
+	if err := ioutil.WriteFile(r.current+suffix, b, 0644); err != nil { // nolint:gosec
+		log.Printf("WARNING! failed to record the invoke%s: %v", suffix, err)
+	}
+}
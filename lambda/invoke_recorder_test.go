@@ -0,0 +1,115 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"errors"
+	"io/ioutil" //nolint: staticcheck
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvokeRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "invoke-recorder")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	metadata := []eventMetadata{defaultInvokeMetadata(), defaultInvokeMetadata()}
+	metadata[0].requestID = "first"
+	metadata[1].requestID = "second"
+	ts, record := runtimeAPIServer(`{"name":"gopher"}`, 2, metadata...)
+	defer ts.Close()
+
+	invokes := 0
+	handler := NewHandlerWithOptions(func(event map[string]string) (string, error) {
+		invokes++
+		if invokes > 1 {
+			return "", errors.New("second invoke failed")
+		}
+		return "hello " + event["name"], nil
+	}, WithInvokeRecorder(dir))
+	recorder := handler.(*handlerOptions).invokeRecorder
+	start := time.Date(2023, 5, 4, 3, 2, 1, 0, time.UTC)
+	recorder.now = func() time.Time {
+		start = start.Add(time.Second)
+		return start
+	}
+	endpoint := strings.Split(ts.URL, "://")[1]
+	_ = startRuntimeAPILoop(endpoint, handler)
+	require.Equal(t, 2, record.nPosts)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	var names []string
+	for _, file := range files {
+		names = append(names, filepath.Base(file))
+	}
+	assert.Equal(t, []string{
+		"20230504T030202.000000000Z-first.request",
+		"20230504T030202.000000000Z-first.response",
+		"20230504T030203.000000000Z-second.error",
+		"20230504T030203.000000000Z-second.request",
+	}, names)
+
+	read := func(name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return string(b)
+	}
+	assert.Equal(t, `{"name":"gopher"}`, read(names[0]))
+	assert.Equal(t, `"hello gopher"`, read(names[1]))
+	assert.JSONEq(t, `{"errorMessage":"second invoke failed","errorType":"errorString"}`, read(names[2]))
+	assert.Equal(t, `{"name":"gopher"}`, read(names[3]))
+	assert.Equal(t, `"hello gopher"`, string(record.responses[0]))
+}
+
+func TestInvokeRecorderPayloadTooLarge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "invoke-recorder")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	metadata := []eventMetadata{defaultInvokeMetadata(), defaultInvokeMetadata()}
+	metadata[0].requestID = "first"
+	metadata[1].requestID = "second"
+	ts, record := runtimeAPIServer(`{"name":"gopher"}`, 2, metadata...)
+	defer ts.Close()
+
+	handler := NewHandlerWithOptions(func() error { return nil }, WithInvokeRecorder(dir), WithMaxPayloadBytes(8))
+	recorder := handler.(*handlerOptions).invokeRecorder
+	start := time.Date(2023, 5, 4, 3, 2, 1, 0, time.UTC)
+	recorder.now = func() time.Time {
+		start = start.Add(time.Second)
+		return start
+	}
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 2, record.nPosts)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	var names []string
+	for _, file := range files {
+		names = append(names, filepath.Base(file))
+	}
+	assert.Equal(t, []string{
+		"20230504T030202.000000000Z-first.error",
+		"20230504T030202.000000000Z-first.request",
+		"20230504T030203.000000000Z-second.error",
+		"20230504T030203.000000000Z-second.request",
+	}, names)
+	for _, name := range []string{names[0], names[2]} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"errorType":"Runtime.PayloadTooLarge","errorMessage":"payload of 17 bytes exceeds the maximum of 8 bytes"}`, string(b))
+	}
+	_, err = os.Stat(".error")
+	assert.True(t, os.IsNotExist(err), "no error should be recorded outside of the recorder's directory")
+}
+
+func TestInvokeRecorderDisabledByDefault(t *testing.T) {
+	handler := NewHandler(func() error { return nil })
+	assert.Nil(t, handler.(*handlerOptions).invokeRecorder)
+}
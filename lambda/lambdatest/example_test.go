@@ -0,0 +1,48 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdatest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/lambda/lambdatest"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+func ExampleHarness_Invoke() {
+	harness := lambdatest.New(func(ctx context.Context, name string) (string, error) {
+		lc, _ := lambdacontext.FromContext(ctx)
+		return fmt.Sprintf("hello %s from %s", name, lc.InvokedFunctionArn), nil
+	})
+	result := harness.Invoke([]byte(`"gopher"`), http.Header{
+		"Lambda-Runtime-Invoked-Function-Arn": {"arn:aws:lambda:us-east-1:123456789012:function:hello"},
+	})
+	fmt.Println(result.RequestID)
+	fmt.Println(string(result.Response))
+	// Output:
+	// invoke-1
+	// "hello gopher from arn:aws:lambda:us-east-1:123456789012:function:hello"
+}
+
+func ExampleHarness_Run() {
+	harness := lambdatest.New(func(name string) (string, error) {
+		if name == "" {
+			return "", errors.New("name is required")
+		}
+		return strings.ToUpper(name), nil
+	})
+	results := harness.Run(
+		lambdatest.Invoke{Payload: []byte(`"gopher"`)},
+		lambdatest.Invoke{Payload: []byte(`""`)},
+	)
+	for _, result := range results {
+		fmt.Printf("%s: response=%s error=%s\n", result.RequestID, result.Response, result.Error)
+	}
+	// Output:
+	// invoke-1: response="GOPHER" error=
+	// invoke-2: response= error={"errorMessage":"name is required","errorType":"errorString"}
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+// Package lambdatest runs handlers in tests through the same invoke path as lambda.Start,
+// with its reflection based decoding and encoding, error reporting, and options, but without a Runtime API server or any socket.
+package lambdatest
+
+import (
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// Invoke is an invoke to send to the handler.
+type Invoke struct {
+	Payload []byte      // Payload is the event, usually JSON
+	Headers http.Header // Headers are the Runtime API headers of the invoke, such as Lambda-Runtime-Client-Context, and may be nil
+}
+
+// Harness sends invokes to a single handler, keeping its state between invokes as the Lambda execution environment does.
+// A Harness must not be used by concurrent goroutines.
+type Harness struct {
+	mux *lambda.InvokeMux
+}
+
+// New returns a Harness calling handler, which may be any handler accepted by lambda.Start, with the options of lambda.StartWithOptions.
+func New(handler interface{}, options ...lambda.Option) *Harness {
+	return &Harness{mux: lambda.NewInvokeMux(handler, options...)}
+}
+
+// Invoke sends a single invoke to the handler. See lambda.InvokeMux.Invoke for the defaults of the request id and deadline headers.
+func (h *Harness) Invoke(payload []byte, headers http.Header) lambda.InvokeResult {
+	return h.mux.Invoke(payload, headers)
+}
+
+// Run sends the invokes to the handler in order, returning the result of each.
+// It stops after an invoke that requires the process to exit, such as one that panicked, so fewer results than invokes may be returned.
+func (h *Harness) Run(invokes ...Invoke) []lambda.InvokeResult {
+	results := make([]lambda.InvokeResult, 0, len(invokes))
+	for _, invoke := range invokes {
+		result := h.mux.Invoke(invoke.Payload, invoke.Headers)
+		results = append(results, result)
+		if result.Err != nil {
+			break
+		}
+	}
+	return results
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdatest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambda/messages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStopsAfterPanic(t *testing.T) {
+	calls := 0
+	harness := New(func(event map[string]int) (int, error) {
+		calls++
+		if event["n"] < 0 {
+			panic("negative")
+		}
+		return event["n"] * 2, nil
+	})
+	results := harness.Run(
+		Invoke{Payload: []byte(`{"n":21}`)},
+		Invoke{Payload: []byte(`{"n":-1}`), Headers: http.Header{"Lambda-Runtime-Aws-Request-Id": {"custom-id"}}},
+		Invoke{Payload: []byte(`{"n":1}`)},
+	)
+	require.Len(t, results, 2)
+	assert.Equal(t, 2, calls)
+
+	assert.Equal(t, "invoke-1", results[0].RequestID)
+	assert.Equal(t, "42", string(results[0].Response))
+	assert.Equal(t, "application/json", results[0].ContentType)
+	assert.Nil(t, results[0].Error)
+	assert.NoError(t, results[0].Err)
+
+	assert.Equal(t, "custom-id", results[1].RequestID)
+	assert.Nil(t, results[1].Response)
+	assert.Contains(t, string(results[1].Error), `"errorMessage":"negative"`)
+	assert.EqualError(t, results[1].Err, "calling the handler function resulted in a panic, the process should exit")
+}
+
+func TestInvokeKeepsState(t *testing.T) {
+	total := 0
+	harness := New(func(ctx context.Context, n int) (int, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			return 0, errors.New("no deadline")
+		}
+		total += n
+		return total, nil
+	})
+	assert.Equal(t, "1", string(harness.Invoke([]byte(`1`), nil).Response))
+	assert.Equal(t, "3", string(harness.Invoke([]byte(`2`), nil).Response))
+
+	result := harness.Invoke([]byte(`"not a number"`), nil)
+	assert.Equal(t, "invoke-3", result.RequestID)
+	assert.JSONEq(t, `{"errorMessage":"json: cannot unmarshal string into Go value of type int","errorType":"UnmarshalTypeError"}`, string(result.Error))
+}
+
+func TestInvokeWithOptions(t *testing.T) {
+	harness := New(func() error {
+		return errors.New("secret")
+	}, lambda.WithErrorFormatter(func(err error) *messages.InvokeResponse_Error {
+		return &messages.InvokeResponse_Error{Type: "InternalError", Message: "redacted"}
+	}))
+	result := harness.Invoke(nil, nil)
+	assert.JSONEq(t, `{"errorMessage":"redacted","errorType":"InternalError"}`, string(result.Error))
+}
+
+func TestInvokeStreamingResponse(t *testing.T) {
+	harness := New(func(ctx context.Context, w lambda.ResponseWriter) error {
+		if _, err := w.Write([]byte("partial")); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return errors.New("stream broke")
+	})
+	result := harness.Invoke([]byte(`{}`), nil)
+	assert.True(t, result.Streamed)
+	assert.Contains(t, string(result.Response), "partial")
+	assert.JSONEq(t, `{"errorMessage":"stream broke","errorType":"errorString"}`, string(result.Error))
+	assert.NoError(t, result.Err)
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"time"
+)
+
+// logRateLimiter allows up to limit log lines within each fixed window of duration per.
+type logRateLimiter struct {
+	limit       int
+	per         time.Duration
+	now         func() time.Time
+	windowStart time.Time
+	count       int
+	dropped     int
+}
+
+func newLogRateLimiter(limit int, per time.Duration) *logRateLimiter {
+	return &logRateLimiter{limit: limit, per: per, now: time.Now}
+}
+
+// allow reports whether a log line may be written now.
+// When a new window starts, it also returns the number of lines dropped during the previous windows.
+func (l *logRateLimiter) allow() (allowed bool, dropped int) {
+	now := l.now()
+	if l.windowStart.IsZero() || now.Sub(l.windowStart) >= l.per {
+		l.windowStart = now
+		l.count = 0
+		dropped, l.dropped = l.dropped, 0
+	}
+	if l.count >= l.limit {
+		l.dropped++
+		return false, dropped
+	}
+	l.count++
+	return true, dropped
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorLogRateLimit(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	nInvokes := 5
+	ts, record := runtimeAPIServer(``, nInvokes)
+	defer ts.Close()
+	handler := NewHandlerWithOptions(func() error {
+		return errors.New("dependency is down")
+	}, WithErrorLogRateLimit(2, time.Hour))
+	endpoint := strings.Split(ts.URL, "://")[1]
+	_ = startRuntimeAPILoop(endpoint, handler)
+
+	assert.Equal(t, nInvokes, record.nPosts)
+	for i := 0; i < nInvokes; i++ {
+		assert.JSONEq(t, `{"errorMessage":"dependency is down","errorType":"errorString"}`, string(record.responses[i]))
+	}
+	assert.Equal(t, 2, strings.Count(logs.String(), "dependency is down"))
+}
+
+func TestLogRateLimiterWindows(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newLogRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	expect := func(allowed bool, dropped int) {
+		t.Helper()
+		gotAllowed, gotDropped := limiter.allow()
+		assert.Equal(t, allowed, gotAllowed)
+		assert.Equal(t, dropped, gotDropped)
+	}
+
+	expect(true, 0)
+	expect(true, 0)
+	expect(false, 0)
+	expect(false, 0)
+
+	now = now.Add(59 * time.Second)
+	expect(false, 0)
+
+	now = now.Add(time.Second)
+	expect(true, 3)
+	expect(true, 0)
+	expect(false, 0)
+
+	now = now.Add(time.Hour)
+	expect(true, 1)
+}
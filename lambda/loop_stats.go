@@ -0,0 +1,53 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import "time"
+
+// LoopStats are counters about the invokes served by the current process, see WithLoopStats.
+type LoopStats struct {
+	Invokes         int64            // Invokes is the number of invokes served, including those that failed before the handler was called
+	Failures        int64            // Failures is the number of invokes where the handler returned an error or panicked, or the invoke failed before the handler was called
+	Panics          int64            // Panics is the number of invokes where the handler panicked
+	TotalDuration   time.Duration    // TotalDuration is the time spent in the handler, across all invokes
+	AverageDuration time.Duration    // AverageDuration is the average time spent in the handler for an invoke
+	ContentType     string           // ContentType is the content type of the last invoke's response, and is empty when the invoke failed
+	ContentTypes    map[string]int64 // ContentTypes is the number of responses sent with each content type, across all invokes
+}
+
+// InvokeInfo describes an invoke served by the current process, see WithInvokeObserver.
+type InvokeInfo struct {
+	RequestID   string        // RequestID is the request id of the invoke
+	Duration    time.Duration // Duration is the time spent in the handler, and is zero when the invoke failed before the handler was called
+	Failed      bool          // Failed is true when the handler returned an error or panicked, or the invoke failed before the handler was called
+	Panicked    bool          // Panicked is true when the handler panicked
+	PayloadSize int           // PayloadSize is the size of the invoke's payload, in bytes
+}
+
+type loopStats struct {
+	stats    LoopStats
+	callback func(LoopStats)
+}
+
+func (l *loopStats) record(info InvokeInfo, contentType string) {
+	l.stats.Invokes++
+	if info.Failed {
+		l.stats.Failures++
+	}
+	if info.Panicked {
+		l.stats.Panics++
+	}
+	l.stats.TotalDuration += info.Duration
+	l.stats.AverageDuration = l.stats.TotalDuration / time.Duration(l.stats.Invokes)
+	l.stats.ContentType = contentType
+	// the map is copied, so that the stats given to earlier callbacks do not change
+	contentTypes := make(map[string]int64, len(l.stats.ContentTypes)+1)
+	for key, n := range l.stats.ContentTypes {
+		contentTypes[key] = n
+	}
+	if contentType != "" {
+		contentTypes[contentType]++
+	}
+	l.stats.ContentTypes = contentTypes
+	l.callback(l.stats)
+}
@@ -0,0 +1,178 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoopStats(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 4)
+	defer ts.Close()
+
+	invokes := 0
+	var stats []LoopStats
+	handler := NewHandlerWithOptions(func() error {
+		invokes++
+		time.Sleep(time.Millisecond)
+		switch invokes {
+		case 2:
+			return errors.New("failed")
+		case 4:
+			panic("panicked")
+		}
+		return nil
+	}, WithLoopStats(func(s LoopStats) {
+		stats = append(stats, s)
+	}))
+	err := startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.EqualError(t, err, "calling the handler function resulted in a panic, the process should exit")
+	require.Equal(t, 4, record.nPosts)
+	require.Len(t, stats, 4)
+
+	counts := func(s LoopStats) [3]int64 { return [3]int64{s.Invokes, s.Failures, s.Panics} }
+	assert.Equal(t, [3]int64{1, 0, 0}, counts(stats[0]))
+	assert.Equal(t, [3]int64{2, 1, 0}, counts(stats[1]))
+	assert.Equal(t, [3]int64{3, 1, 0}, counts(stats[2]))
+	assert.Equal(t, [3]int64{4, 2, 1}, counts(stats[3]))
+
+	last := stats[3]
+	assert.GreaterOrEqual(t, last.TotalDuration, 4*time.Millisecond)
+	assert.Equal(t, last.TotalDuration/4, last.AverageDuration)
+	assert.Greater(t, last.TotalDuration, stats[2].TotalDuration)
+}
+
+func TestLoopStatsContentTypes(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 4)
+	defer ts.Close()
+
+	invokes := 0
+	var stats []LoopStats
+	handler := NewHandlerWithOptions(func() (interface{}, error) {
+		invokes++
+		switch invokes {
+		case 2:
+			return strings.NewReader("plain bytes"), nil
+		case 3:
+			return nil, errors.New("failed")
+		}
+		return map[string]string{"hello": "world"}, nil
+	}, WithLoopStats(func(s LoopStats) {
+		stats = append(stats, s)
+	}))
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 4, record.nPosts)
+	require.Len(t, stats, 4)
+
+	for i, expected := range []string{contentTypeJSON, contentTypeBytes, "", contentTypeJSON} {
+		assert.Equal(t, expected, stats[i].ContentType)
+		if expected != "" {
+			assert.Equal(t, expected, record.contentTypes[i], "the recorded content type should be the one sent")
+		}
+	}
+	assert.Equal(t, map[string]int64{contentTypeJSON: 1}, stats[0].ContentTypes)
+	assert.Equal(t, map[string]int64{contentTypeJSON: 1, contentTypeBytes: 1}, stats[2].ContentTypes)
+	assert.Equal(t, map[string]int64{contentTypeJSON: 2, contentTypeBytes: 1}, stats[3].ContentTypes)
+}
+
+func TestLoopStatsBeforeHandler(t *testing.T) {
+	var stats []LoopStats
+	var infos []InvokeInfo
+	calls := 0
+	mux := NewInvokeMux(func() (io.Reader, error) {
+		calls++
+		switch calls {
+		case 1:
+			return &declaredLengthBuffer{bytes.NewBufferString("hello world"), 5}, nil
+		case 2:
+			return &declaredLengthReader{strings.NewReader("hi"), 5}, nil
+		}
+		return strings.NewReader("hello"), nil
+	}, WithMaxPayloadBytes(2), WithStrictContentLength(), WithLoopStats(func(s LoopStats) {
+		stats = append(stats, s)
+	}), WithInvokeObserver(func(info InvokeInfo) {
+		infos = append(infos, info)
+	}))
+	result := mux.Invoke([]byte(`"too large"`), nil)
+	require.NoError(t, result.Err)
+	assert.NotNil(t, result.Error)
+	for i := 0; i < 3; i++ {
+		result = mux.Invoke([]byte(`{}`), nil)
+		require.NoError(t, result.Err)
+	}
+	assert.Nil(t, result.Error)
+	assert.Equal(t, 3, calls)
+
+	require.Len(t, stats, 4)
+	assert.Equal(t, LoopStats{Invokes: 1, Failures: 1, ContentTypes: map[string]int64{}}, stats[0])
+	assert.Equal(t, [2]int64{2, 2}, [2]int64{stats[1].Invokes, stats[1].Failures}, "a response rejected by its declared length counts as a failure")
+	assert.Equal(t, [2]int64{3, 3}, [2]int64{stats[2].Invokes, stats[2].Failures}, "a response found shorter than declared as it is sent counts as a failure")
+	assert.Equal(t, "", stats[2].ContentType)
+	assert.Equal(t, [2]int64{4, 3}, [2]int64{stats[3].Invokes, stats[3].Failures})
+	assert.Equal(t, map[string]int64{contentTypeBytes: 1}, stats[3].ContentTypes)
+
+	require.Len(t, infos, 4)
+	for i, failed := range []bool{true, true, true, false} {
+		assert.Equal(t, failed, infos[i].Failed)
+	}
+}
+
+func TestInvokeObserver(t *testing.T) {
+	ts, record := runtimeAPIServer(`{"n": 1}`, 3)
+	defer ts.Close()
+
+	invokes := 0
+	var infos []InvokeInfo
+	handler := NewHandlerWithOptions(func() error {
+		invokes++
+		time.Sleep(time.Millisecond)
+		switch invokes {
+		case 2:
+			return errors.New("failed")
+		case 3:
+			panic("panicked")
+		}
+		return nil
+	}, WithInvokeObserver(func(info InvokeInfo) {
+		infos = append(infos, info)
+	}))
+	err := startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.EqualError(t, err, "calling the handler function resulted in a panic, the process should exit")
+	require.Equal(t, 3, record.nPosts)
+	require.Len(t, infos, 3)
+
+	outcomes := func(info InvokeInfo) [2]bool { return [2]bool{info.Failed, info.Panicked} }
+	assert.Equal(t, [2]bool{false, false}, outcomes(infos[0]))
+	assert.Equal(t, [2]bool{true, false}, outcomes(infos[1]))
+	assert.Equal(t, [2]bool{true, true}, outcomes(infos[2]))
+	for _, info := range infos {
+		assert.Equal(t, "dummyid", info.RequestID)
+		assert.Equal(t, 8, info.PayloadSize)
+		assert.GreaterOrEqual(t, info.Duration, time.Millisecond)
+	}
+}
+
+func TestInvokeObserverBeforeHandler(t *testing.T) {
+	var infos []InvokeInfo
+	calls := 0
+	mux := NewInvokeMux(func() error {
+		calls++
+		return nil
+	}, WithMaxPayloadBytes(2), WithInvokeObserver(func(info InvokeInfo) {
+		infos = append(infos, info)
+	}))
+	result := mux.Invoke([]byte(`"too large"`), nil)
+	require.NoError(t, result.Err)
+	assert.NotNil(t, result.Error)
+	assert.Equal(t, 0, calls)
+	require.Len(t, infos, 1)
+	assert.Equal(t, InvokeInfo{RequestID: "invoke-1", Failed: true, PayloadSize: 11}, infos[0])
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+)
+
+const (
+	memoryMBPerVCPU = 1769 // Lambda allocates the equivalent of one vCPU at 1,769 MB
+	maxLambdaVCPUs  = 6
+)
+
+// setAutoMaxProcs sets GOMAXPROCS from the function's memory size, unless the GOMAXPROCS environment variable is set.
+func setAutoMaxProcs() {
+	if os.Getenv("GOMAXPROCS") != "" {
+		return
+	}
+	memoryMB, err := strconv.Atoi(os.Getenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE"))
+	if err != nil || memoryMB <= 0 {
+		return
+	}
+	runtime.GOMAXPROCS(maxProcsForMemory(memoryMB))
+}
+
+// maxProcsForMemory returns the number of vCPUs Lambda allocates for memoryMB, rounded up.
+func maxProcsForMemory(memoryMB int) int {
+	procs := (memoryMB + memoryMBPerVCPU - 1) / memoryMBPerVCPU
+	if procs < 1 {
+		return 1
+	}
+	if procs > maxLambdaVCPUs {
+		return maxLambdaVCPUs
+	}
+	return procs
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"log"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetAutoMaxProcs(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	os.Setenv("GOMAXPROCS", "")
+	defer os.Unsetenv("GOMAXPROCS")
+	testCases := []struct {
+		memory   string
+		expected int
+	}{
+		{"128", 1},
+		{"1769", 1},
+		{"1770", 2},
+		{"3008", 2},
+		{"5307", 3},
+		{"10240", 6},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.memory, func(t *testing.T) {
+			os.Setenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE", testCase.memory)
+			defer os.Unsetenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE")
+			setAutoMaxProcs()
+			assert.Equal(t, testCase.expected, runtime.GOMAXPROCS(0))
+		})
+	}
+}
+
+func TestSetAutoMaxProcsSkipped(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	runtime.GOMAXPROCS(3)
+
+	os.Setenv("GOMAXPROCS", "")
+	defer os.Unsetenv("GOMAXPROCS")
+	os.Setenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE", "not a number")
+	defer os.Unsetenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE")
+	setAutoMaxProcs()
+	assert.Equal(t, 3, runtime.GOMAXPROCS(0))
+
+	os.Setenv("GOMAXPROCS", "3")
+	os.Setenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE", "128")
+	setAutoMaxProcs()
+	assert.Equal(t, 3, runtime.GOMAXPROCS(0))
+}
+
+func TestWithAutoMaxProcs(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	os.Setenv("GOMAXPROCS", "")
+	defer os.Unsetenv("GOMAXPROCS")
+	os.Setenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE", "3008")
+	defer os.Unsetenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE")
+	os.Setenv("AWS_LAMBDA_RUNTIME_API", "")
+	defer os.Unsetenv("AWS_LAMBDA_RUNTIME_API")
+
+	logFatalf = func(format string, v ...interface{}) {}
+	defer func() { logFatalf = log.Fatalf }()
+	StartWithOptions(func() error { return nil }, WithAutoMaxProcs())
+	assert.Equal(t, 2, runtime.GOMAXPROCS(0))
+}
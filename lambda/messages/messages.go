@@ -39,6 +39,7 @@ type InvokeResponse_Error struct {
 	Type       string                             `json:"errorType"`
 	StackTrace []*InvokeResponse_Error_StackFrame `json:"stackTrace,omitempty"`
 	ShouldExit bool                               `json:"-"`
+	Causes     []*InvokeResponse_Error            `json:"-"` // Causes are the errors wrapped by the error, sent to X-Ray as the causes of its exception
 }
 
 func (e InvokeResponse_Error) Error() string {
@@ -0,0 +1,132 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+// Package metrics buffers CloudWatch metric data points recorded by handlers, and publishes them in batches
+// suited to PutMetricData between invokes, through a publisher owning the AWS SDK dependency.
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultBatchSize is the number of data points passed to the publisher at once when NewBuffer is given a batch size of 0,
+// the maximum accepted by a single PutMetricData request.
+const DefaultBatchSize = 1000
+
+// Datum is a metric data point, mapping to a MetricDatum of PutMetricData.
+type Datum struct {
+	Name       string            // Name is the name of the metric
+	Value      float64           // Value is the value of the data point
+	Unit       string            // Unit is the unit of the value, such as "Count" or "Milliseconds", and may be empty
+	Dimensions map[string]string // Dimensions are the dimensions of the metric, by name
+	Timestamp  time.Time         // Timestamp is when the data point was recorded
+}
+
+// Publisher sends a batch of data points for namespace, such as with a PutMetricData request.
+type Publisher func(ctx context.Context, namespace string, data []Datum) error
+
+// Buffer holds the data points recorded for a namespace until they are flushed. It is safe for concurrent use.
+// Methods on a nil Buffer do nothing, so that handlers can record metrics whether or not a Buffer was configured.
+type Buffer struct {
+	namespace string
+	batchSize int
+	publish   Publisher
+	mu        sync.Mutex
+	data      []Datum
+}
+
+// NewBuffer returns a Buffer passing the data points of namespace to publish, in batches of at most batchSize, or DefaultBatchSize when batchSize is 0.
+//
+// Usage:
+//
+//	buffer := metrics.NewBuffer("MyService", 0, func(ctx context.Context, namespace string, data []metrics.Datum) error {
+//		_, err := cloudwatchClient.PutMetricData(ctx, toPutMetricDataInput(namespace, data))
+//		return err
+//	})
+//	lambda.StartWithOptions(handler, lambda.WithMetrics(buffer))
+func NewBuffer(namespace string, batchSize int, publish Publisher) *Buffer {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &Buffer{namespace: namespace, batchSize: batchSize, publish: publish}
+}
+
+// Add records a data point for the metric name, timestamped with the current time. The dimensions map is copied.
+func (b *Buffer) Add(name string, value float64, unit string, dimensions map[string]string) {
+	b.AddDatum(Datum{Name: name, Value: value, Unit: unit, Dimensions: dimensions})
+}
+
+// AddDatum records datum, timestamped with the current time unless it has a Timestamp. The dimensions map is copied.
+func (b *Buffer) AddDatum(datum Datum) {
+	if b == nil {
+		return
+	}
+	if datum.Timestamp.IsZero() {
+		datum.Timestamp = time.Now()
+	}
+	if datum.Dimensions != nil {
+		dimensions := make(map[string]string, len(datum.Dimensions))
+		for name, value := range datum.Dimensions {
+			dimensions[name] = value
+		}
+		datum.Dimensions = dimensions
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, datum)
+}
+
+// Len returns the number of data points waiting to be flushed.
+func (b *Buffer) Len() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.data)
+}
+
+// Flush passes the buffered data points to the publisher, in the order they were added, one batch at a time.
+// It stops at the first batch the publisher fails to send, and returns its error. The data points of that batch, and of the following ones, are dropped,
+// so that a failing publisher does not grow the buffer across invokes.
+func (b *Buffer) Flush(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	data := b.data
+	b.data = nil
+	b.mu.Unlock()
+	for len(data) > 0 {
+		n := b.batchSize
+		if n > len(data) {
+			n = len(data)
+		}
+		if err := b.publish(ctx, b.namespace, data[:n:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+type bufferKey struct{}
+
+// NewContext returns a new Context that carries buffer.
+func NewContext(parent context.Context, buffer *Buffer) context.Context {
+	return context.WithValue(parent, bufferKey{}, buffer)
+}
+
+// Collector returns the Buffer stored in ctx, such as by lambda.WithMetrics, or nil, on which recording data points does nothing.
+//
+// Usage:
+//
+//	func handler(ctx context.Context, order Order) error {
+//		metrics.Collector(ctx).Add("OrderTotal", order.Total, "None", map[string]string{"Region": order.Region})
+//		return process(ctx, order)
+//	}
+func Collector(ctx context.Context) *Buffer {
+	buffer, _ := ctx.Value(bufferKey{}).(*Buffer)
+	return buffer
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlushBatches(t *testing.T) {
+	var batches [][]Datum
+	buffer := NewBuffer("Service", 2, func(ctx context.Context, namespace string, data []Datum) error {
+		assert.Equal(t, "Service", namespace)
+		batches = append(batches, data)
+		return nil
+	})
+	dimensions := map[string]string{"Region": "us-west-2"}
+	for i := 0; i < 5; i++ {
+		buffer.Add("Metric"+strconv.Itoa(i), float64(i), "Count", dimensions)
+	}
+	dimensions["Region"] = "changed"
+	assert.Equal(t, 5, buffer.Len())
+
+	require.NoError(t, buffer.Flush(context.Background()))
+	assert.Equal(t, 0, buffer.Len())
+	require.Len(t, batches, 3)
+	var names []string
+	for i, batch := range batches {
+		assert.LessOrEqual(t, len(batch), 2)
+		for _, datum := range batch {
+			names = append(names, datum.Name)
+			assert.Equal(t, map[string]string{"Region": "us-west-2"}, datum.Dimensions)
+			assert.Equal(t, "Count", datum.Unit)
+			assert.False(t, datum.Timestamp.IsZero())
+		}
+		if i < 2 {
+			// appending to a batch must not overwrite the next one
+			_ = append(batch, Datum{})
+		}
+	}
+	assert.Equal(t, []string{"Metric0", "Metric1", "Metric2", "Metric3", "Metric4"}, names)
+	assert.Equal(t, "Metric2", batches[1][0].Name)
+
+	require.NoError(t, buffer.Flush(context.Background()))
+	assert.Len(t, batches, 3, "flushing an empty buffer should not publish anything")
+}
+
+func TestFlushError(t *testing.T) {
+	calls := 0
+	buffer := NewBuffer("Service", 1, func(ctx context.Context, namespace string, data []Datum) error {
+		calls++
+		return errors.New("throttled")
+	})
+	timestamp := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	buffer.AddDatum(Datum{Name: "A", Value: 1, Timestamp: timestamp})
+	buffer.AddDatum(Datum{Name: "B", Value: 2})
+	assert.EqualError(t, buffer.Flush(context.Background()), "throttled")
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 0, buffer.Len(), "the data points should be dropped")
+}
+
+func TestDefaultBatchSize(t *testing.T) {
+	var sizes []int
+	buffer := NewBuffer("Service", 0, func(ctx context.Context, namespace string, data []Datum) error {
+		sizes = append(sizes, len(data))
+		return nil
+	})
+	for i := 0; i < DefaultBatchSize+1; i++ {
+		buffer.Add("Metric", 1, "", nil)
+	}
+	require.NoError(t, buffer.Flush(context.Background()))
+	assert.Equal(t, []int{DefaultBatchSize, 1}, sizes)
+}
+
+func TestCollector(t *testing.T) {
+	assert.Nil(t, Collector(context.Background()))
+	Collector(context.Background()).Add("Ignored", 1, "", nil)
+	assert.Equal(t, 0, Collector(context.Background()).Len())
+	assert.NoError(t, Collector(context.Background()).Flush(context.Background()))
+
+	buffer := NewBuffer("Service", 0, nil)
+	ctx := NewContext(context.Background(), buffer)
+	Collector(ctx).Add("Recorded", 1, "", nil)
+	assert.Same(t, buffer, Collector(ctx))
+	assert.Equal(t, 1, buffer.Len())
+}
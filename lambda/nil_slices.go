@@ -0,0 +1,129 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// maxNilSliceDepth bounds the traversal, so that self-referential values are left for the json encoder to report.
+const maxNilSliceDepth = 1000
+
+// nilSlicesAsEmpty returns a copy of v where every nil slice, including those nested in structs, maps, pointers, and interfaces,
+// is replaced by an empty slice. Values that implement json.Marshaler or encoding.TextMarshaler are left as-is,
+// as are []byte, which encode as a base64 string rather than as an array.
+// When nothing needs to be replaced, v itself is returned.
+func nilSlicesAsEmpty(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if replaced, ok := replaceNilSlices(reflect.ValueOf(v), 0); ok {
+		return replaced.Interface()
+	}
+	return v
+}
+
+// replaceNilSlices returns the replacement for v, and whether one was needed.
+func replaceNilSlices(v reflect.Value, depth int) (reflect.Value, bool) {
+	if depth > maxNilSliceDepth {
+		return v, false
+	}
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return v, false
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, ok := replaceNilSlices(v.Elem(), depth+1)
+		if !ok {
+			return v, false
+		}
+		replaced := reflect.New(t).Elem()
+		replaced.Set(elem)
+		return replaced, true
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, ok := replaceNilSlices(v.Elem(), depth+1)
+		if !ok {
+			return v, false
+		}
+		replaced := reflect.New(t.Elem())
+		replaced.Elem().Set(elem)
+		return replaced, true
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return v, false
+		}
+		if v.IsNil() {
+			return reflect.MakeSlice(t, 0, 0), true
+		}
+		return replaceElems(v, reflect.MakeSlice(t, v.Len(), v.Len()), depth)
+	case reflect.Array:
+		return replaceElems(v, reflect.New(t).Elem(), depth)
+	case reflect.Map:
+		if v.IsNil() {
+			return v, false
+		}
+		var replaced reflect.Value
+		iter := v.MapRange()
+		for iter.Next() {
+			elem, ok := replaceNilSlices(iter.Value(), depth+1)
+			if !ok {
+				continue
+			}
+			if !replaced.IsValid() {
+				replaced = reflect.MakeMapWithSize(t, v.Len())
+				for _, key := range v.MapKeys() {
+					replaced.SetMapIndex(key, v.MapIndex(key))
+				}
+			}
+			replaced.SetMapIndex(iter.Key(), elem)
+		}
+		return replaced, replaced.IsValid()
+	case reflect.Struct:
+		var replaced reflect.Value
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			field, ok := replaceNilSlices(v.Field(i), depth+1)
+			if !ok {
+				continue
+			}
+			if !replaced.IsValid() {
+				// copying the whole struct first preserves the unexported fields
+				replaced = reflect.New(t).Elem()
+				replaced.Set(v)
+			}
+			replaced.Field(i).Set(field)
+		}
+		return replaced, replaced.IsValid()
+	}
+	return v, false
+}
+
+// replaceElems copies the elements of v into dst, replacing nil slices, and reports whether any replacement was made.
+func replaceElems(v reflect.Value, dst reflect.Value, depth int) (reflect.Value, bool) {
+	changed := false
+	for i := 0; i < v.Len(); i++ {
+		elem, ok := replaceNilSlices(v.Index(i), depth+1)
+		changed = changed || ok
+		dst.Index(i).Set(elem)
+	}
+	if !changed {
+		return v, false
+	}
+	return dst, true
+}
@@ -97,3 +97,20 @@ func formatFrame(inputFrame runtime.Frame) *messages.InvokeResponse_Error_StackF
 		Label: label,
 	}
 }
+
+// SkipRuntimeFrames is a filter for WithStackFilter, dropping the frames of the aws-lambda-go lambda package and its subpackages,
+// of the reflect package used to call the handler, and of the Go runtime, so that only the frames of the function's code are kept.
+func SkipRuntimeFrames(frame messages.InvokeResponse_Error_StackFrame) bool {
+	if strings.HasPrefix(frame.Path, "runtime/") || strings.HasPrefix(frame.Path, "reflect/") {
+		return false
+	}
+	segments := strings.Split(frame.Path, "/")
+	for i := 0; i+3 < len(segments); i++ {
+		// paths in the module cache carry the version, as in github.com/aws/aws-lambda-go@v1.41.0/lambda/invoke_loop.go
+		module := strings.Split(segments[i+2], "@")[0]
+		if segments[i] == "github.com" && segments[i+1] == "aws" && module == "aws-lambda-go" && segments[i+3] == "lambda" {
+			return false
+		}
+	}
+	return true
+}
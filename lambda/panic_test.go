@@ -145,3 +145,23 @@ func getPackagePath() (string, error) {
 	}
 	return strings.Join(paths, "/"), nil
 }
+
+func TestSkipRuntimeFrames(t *testing.T) {
+	testCases := []struct {
+		path string
+		keep bool
+	}{
+		{"github.com/aws/aws-lambda-go/lambda/invoke_loop.go", false},
+		{"github.com/aws/aws-lambda-go@v1.41.0/lambda/handler.go", false},
+		{"github.com/aws/aws-lambda-go/lambda/handlertrace/trace.go", false},
+		{"reflect/value.go", false},
+		{"runtime/panic.go", false},
+		{"github.com/aws/aws-lambda-go/events/sqs.go", true},
+		{"github.com/aws/aws-lambda-go-extensions/lambda/main.go", true},
+		{"example.com/orders/lambda/handler.go", true},
+		{"main.go", true},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.keep, SkipRuntimeFrames(messages.InvokeResponse_Error_StackFrame{Path: testCase.path, Label: "f", Line: 1}), testCase.path)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ProxyErrorMapper converts an error returned by the handler to the proxy response sent in its place, see WithProxyErrorMapper.
+type ProxyErrorMapper func(err error) events.APIGatewayProxyResponse
+
+// DefaultProxyErrorMapper converts err to a response with a JSON body of the form {"message":"..."}.
+//
+// If err, or an error it wraps, has a StatusCode() int method, the status code and the error message are sent.
+// If err, or an error it wraps, has an ErrorCode() string method, the body is instead the ErrorEnvelope of the code and the error message,
+// sent with the status code of err, or 500 Internal Server Error when it has none. NewAPIError returns such errors.
+// If err, or an error it wraps, has a Headers() http.Header method, the headers are added to the response.
+// Other errors are sent as 500 Internal Server Error, without their message.
+func DefaultProxyErrorMapper(err error) events.APIGatewayProxyResponse {
+	status := http.StatusInternalServerError
+	message := http.StatusText(status)
+	var withStatus interface{ StatusCode() int }
+	hasStatus := errors.As(err, &withStatus)
+	if hasStatus {
+		status = withStatus.StatusCode()
+		message = err.Error()
+	}
+	var response events.APIGatewayProxyResponse
+	var withCode interface{ ErrorCode() string }
+	if errors.As(err, &withCode) {
+		response = ErrorEnvelope(withCode.ErrorCode(), err.Error(), status)
+	} else {
+		body, _ := json.Marshal(struct {
+			Message string `json:"message"`
+		}{message})
+		response = events.APIGatewayProxyResponse{
+			StatusCode: status,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Body:       string(body),
+		}
+	}
+	var withHeaders interface{ Headers() http.Header }
+	if errors.As(err, &withHeaders) {
+		for key, values := range withHeaders.Headers() {
+			switch len(values) {
+			case 0:
+			case 1:
+				response.Headers[key] = values[0]
+			default:
+				if response.MultiValueHeaders == nil {
+					response.MultiValueHeaders = map[string][]string{}
+				}
+				response.MultiValueHeaders[key] = values
+			}
+		}
+	}
+	return response
+}
+
+type httpError struct {
+	status  int
+	message string
+}
+
+// NewHTTPError returns an error that DefaultProxyErrorMapper sends with the given status code and message.
+func NewHTTPError(status int, message string) error {
+	return &httpError{status: status, message: message}
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+func (e *httpError) StatusCode() int {
+	return e.status
+}
+
+// ErrorEnvelope builds a proxy response with the given status, and a JSON body of the form {"error":{"code":"...","message":"..."}},
+// so that every error of an API has the same shape.
+//
+// Usage:
+//
+//	lambda.Start(func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+//		if request.PathParameters["id"] == "" {
+//			return lambda.ErrorEnvelope("MissingID", "the id path parameter is required", http.StatusBadRequest), nil
+//		}
+//		return getItem(ctx, request.PathParameters["id"])
+//	})
+func ErrorEnvelope(code string, message string, status int) events.APIGatewayProxyResponse {
+	type envelopeError struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	body, _ := json.Marshal(struct {
+		Error envelopeError `json:"error"`
+	}{envelopeError{code, message}})
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+	}
+}
+
+type apiError struct {
+	httpError
+	code string
+}
+
+// NewAPIError returns an error that DefaultProxyErrorMapper sends as the ErrorEnvelope of code and message, with the given status code.
+func NewAPIError(status int, code string, message string) error {
+	return &apiError{httpError{status: status, message: message}, code}
+}
+
+func (e *apiError) ErrorCode() string {
+	return e.code
+}
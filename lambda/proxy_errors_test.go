@@ -0,0 +1,140 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type rateLimitedError struct{}
+
+func (rateLimitedError) Error() string   { return "slow down" }
+func (rateLimitedError) StatusCode() int { return http.StatusTooManyRequests }
+func (rateLimitedError) Headers() http.Header {
+	return http.Header{"Retry-After": {"30"}, "Vary": {"Origin", "Accept"}}
+}
+
+type codedError struct{}
+
+func (codedError) Error() string     { return "try again" }
+func (codedError) ErrorCode() string { return "Unavailable" }
+
+func TestDefaultProxyErrorMapper(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected events.APIGatewayProxyResponse
+	}{
+		{
+			name: "status code and headers",
+			err:  fmt.Errorf("wrapped: %w", rateLimitedError{}),
+			expected: events.APIGatewayProxyResponse{
+				StatusCode:        http.StatusTooManyRequests,
+				Headers:           map[string]string{"Content-Type": "application/json", "Retry-After": "30"},
+				MultiValueHeaders: map[string][]string{"Vary": {"Origin", "Accept"}},
+				Body:              `{"message":"wrapped: slow down"}`,
+			},
+		},
+		{
+			name: "http error",
+			err:  NewHTTPError(http.StatusNotFound, "no such user"),
+			expected: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusNotFound,
+				Headers:    map[string]string{"Content-Type": "application/json"},
+				Body:       `{"message":"no such user"}`,
+			},
+		},
+		{
+			name: "api error",
+			err:  NewAPIError(http.StatusConflict, "VersionConflict", "the item was changed"),
+			expected: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusConflict,
+				Headers:    map[string]string{"Content-Type": "application/json"},
+				Body:       `{"error":{"code":"VersionConflict","message":"the item was changed"}}`,
+			},
+		},
+		{
+			name: "error code without a status code",
+			err:  fmt.Errorf("wrapped: %w", codedError{}),
+			expected: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Headers:    map[string]string{"Content-Type": "application/json"},
+				Body:       `{"error":{"code":"Unavailable","message":"wrapped: try again"}}`,
+			},
+		},
+		{
+			name: "other errors hide their message",
+			err:  errors.New("connection to 10.0.0.1 refused"),
+			expected: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusInternalServerError,
+				Headers:    map[string]string{"Content-Type": "application/json"},
+				Body:       `{"message":"Internal Server Error"}`,
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, DefaultProxyErrorMapper(testCase.err))
+		})
+	}
+}
+
+func TestWithProxyErrorMapper(t *testing.T) {
+	handler := NewHandlerWithOptions(func() (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{}, rateLimitedError{}
+	}, WithProxyErrorMapper(nil))
+	response, err := handler.Invoke(context.Background(), []byte(`{}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"statusCode": 429,
+		"headers": {"Content-Type": "application/json", "Retry-After": "30"},
+		"multiValueHeaders": {"Vary": ["Origin", "Accept"]},
+		"body": "{\"message\":\"slow down\"}"
+	}`, string(response))
+
+	handler = NewHandlerWithOptions(func() error {
+		return errors.New("failed")
+	}, WithProxyErrorMapper(func(err error) events.APIGatewayProxyResponse {
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusBadGateway, Body: err.Error()}
+	}))
+	response, err = handler.Invoke(context.Background(), []byte(`{}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"statusCode":502,"headers":null,"multiValueHeaders":null,"body":"failed"}`, string(response))
+}
+
+func TestWithoutProxyErrorMapper(t *testing.T) {
+	handler := NewHandler(func() (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{}, rateLimitedError{}
+	})
+	_, err := handler.Invoke(context.Background(), []byte(`{}`))
+	assert.Equal(t, rateLimitedError{}, err)
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	response := ErrorEnvelope("NotFound", `no user named "gopher"`, http.StatusNotFound)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	assert.Equal(t, map[string]string{"Content-Type": "application/json"}, response.Headers)
+	assert.JSONEq(t, `{"error":{"code":"NotFound","message":"no user named \"gopher\""}}`, response.Body)
+}
+
+func TestErrorEnvelopeFromProxyErrorMapper(t *testing.T) {
+	handler := NewHandlerWithOptions(func() (events.APIGatewayProxyResponse, error) {
+		return events.APIGatewayProxyResponse{}, fmt.Errorf("lookup failed: %w", NewAPIError(http.StatusNotFound, "UserNotFound", "no such user"))
+	}, WithProxyErrorMapper(nil))
+	response, err := handler.Invoke(context.Background(), []byte(`{}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"statusCode": 404,
+		"headers": {"Content-Type": "application/json"},
+		"multiValueHeaders": null,
+		"body": "{\"error\":{\"code\":\"UserNotFound\",\"message\":\"lookup failed: no such user\"}}"
+	}`, string(response))
+}
@@ -0,0 +1,29 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"crypto/sha1" //nolint: gosec
+	"fmt"
+)
+
+// requestIDNamespace is the namespace of the UUIDs returned by DeterministicRequestID,
+// itself the version 5 UUID of "https://github.com/aws/aws-lambda-go/lambda#DeterministicRequestID" in the RFC 4122 URL namespace
+var requestIDNamespace = [16]byte{0x65, 0xf9, 0xca, 0x14, 0xce, 0xe7, 0x50, 0xcb, 0x98, 0xff, 0xc5, 0x0f, 0x1a, 0xc6, 0x99, 0xcb}
+
+// DeterministicRequestID returns a request id shaped like the UUIDs generated by Lambda, derived from seed.
+// The same seed always yields the same id, so tests embedding the request id in their expected output are reproducible.
+// The id is the version 5 UUID of seed in a namespace specific to this package, and must not be relied on for uniqueness outside of tests.
+//
+// Usage:
+//
+//	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+//		AwsRequestID: lambda.DeterministicRequestID(t.Name()),
+//	})
+func DeterministicRequestID(seed string) string {
+	sum := sha1.Sum(append(requestIDNamespace[:], seed...)) //nolint: gosec
+	id := sum[:16]
+	id[6] = (id[6] & 0x0f) | 0x50 // version 5
+	id[8] = (id[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
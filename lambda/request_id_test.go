@@ -0,0 +1,19 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeterministicRequestID(t *testing.T) {
+	id := DeterministicRequestID("TestGolden")
+	assert.Equal(t, id, DeterministicRequestID("TestGolden"))
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, id)
+	assert.NotEqual(t, id, DeterministicRequestID("TestOther"))
+	// as computed by other UUID implementations, such as Python's uuid.uuid5
+	assert.Equal(t, "84a089b6-745f-503d-bde0-2cc55d9ff1e0", DeterministicRequestID(""))
+	assert.Equal(t, "c6710bd7-75f4-5c3f-a0cd-34d97c426a7f", id)
+}
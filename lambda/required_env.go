@@ -0,0 +1,18 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"os"
+)
+
+// missingEnv returns the keys that are not set in the environment, in the order given
+func missingEnv(keys []string) []string {
+	var missing []string
+	for _, key := range keys {
+		if _, ok := os.LookupEnv(key); !ok {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiredEnvPresent(t *testing.T) {
+	os.Setenv("REQUIRED_ENV_TABLE", "orders")
+	defer os.Unsetenv("REQUIRED_ENV_TABLE")
+	os.Setenv("REQUIRED_ENV_EMPTY", "")
+	defer os.Unsetenv("REQUIRED_ENV_EMPTY")
+	ts, record := runtimeAPIServer(`{}`, 1)
+	defer ts.Close()
+
+	handler := NewHandlerWithOptions(func() error { return nil }, WithRequiredEnv("REQUIRED_ENV_TABLE", "REQUIRED_ENV_EMPTY"))
+	err := startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.Error(t, err, "the loop should have run until the test server ended it")
+	assert.Equal(t, 2, record.nGets)
+	require.Equal(t, 1, record.nPosts)
+	assert.Equal(t, "/2018-06-01/runtime/invocation/dummyid/response", record.postPaths[0])
+}
+
+func TestRequiredEnvMissing(t *testing.T) {
+	os.Setenv("REQUIRED_ENV_TABLE", "orders")
+	defer os.Unsetenv("REQUIRED_ENV_TABLE")
+	require.NoError(t, os.Unsetenv("REQUIRED_ENV_QUEUE"))
+	require.NoError(t, os.Unsetenv("REQUIRED_ENV_BUCKET"))
+	ts, record := runtimeAPIServer(`{}`, 1)
+	defer ts.Close()
+
+	calls := 0
+	handler := NewHandlerWithOptions(func() error {
+		calls++
+		return nil
+	}, WithRequiredEnv("REQUIRED_ENV_QUEUE", "REQUIRED_ENV_TABLE"), WithRequiredEnv("REQUIRED_ENV_BUCKET"))
+	err := startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.EqualError(t, err, "missing required environment variables: REQUIRED_ENV_QUEUE, REQUIRED_ENV_BUCKET")
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, 0, record.nGets)
+	require.Equal(t, 1, record.nPosts)
+	assert.Equal(t, "/2018-06-01/runtime/init/error", record.postPaths[0])
+	assert.JSONEq(t, `{
+		"errorType": "Runtime.MissingEnvironmentVariables",
+		"errorMessage": "missing required environment variables: REQUIRED_ENV_QUEUE, REQUIRED_ENV_BUCKET"
+	}`, string(record.responses[0]))
+}
+
+func TestRequiredEnvMissingInitErrorNotAccepted(t *testing.T) {
+	require.NoError(t, os.Unsetenv("REQUIRED_ENV_QUEUE"))
+	err := startRuntimeAPILoop("127.0.0.1:1", NewHandlerWithOptions(func() error { return nil }, WithRequiredEnv("REQUIRED_ENV_QUEUE")))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing required environment variables: REQUIRED_ENV_QUEUE, and the init error could not be reported: failed to POST to http://127.0.0.1:1/2018-06-01/runtime/init/error")
+}
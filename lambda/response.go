@@ -0,0 +1,244 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// CacheableResponse builds a Function URL response carrying the Cache-Control, Surrogate-Control, and Vary headers
+// needed for caching by CloudFront or another shared cache placed in front of the function.
+// maxAge is rounded down to whole seconds. Each vary value must be a valid HTTP header name, or "*".
+//
+// Usage:
+//
+//	lambda.Start(func() (*events.LambdaFunctionURLResponse, error) {
+//		return lambda.CacheableResponse("<html><body>hello!</body></html>", 5*time.Minute, "Accept-Encoding")
+//	})
+func CacheableResponse(body string, maxAge time.Duration, vary ...string) (*events.LambdaFunctionURLResponse, error) {
+	if maxAge < 0 {
+		return nil, fmt.Errorf("max-age must not be negative, got %s", maxAge)
+	}
+	seconds := strconv.FormatInt(int64(maxAge/time.Second), 10)
+	headers := map[string]string{
+		"Cache-Control":     "public, max-age=" + seconds,
+		"Surrogate-Control": "max-age=" + seconds,
+	}
+	if len(vary) > 0 {
+		names := make([]string, 0, len(vary))
+		for _, name := range vary {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				if len(vary) > 1 {
+					return nil, fmt.Errorf("vary \"*\" must not be combined with other header names")
+				}
+				names = append(names, name)
+				continue
+			}
+			if !isHeaderToken(name) {
+				return nil, fmt.Errorf("vary header name %q is not a valid HTTP header name", name)
+			}
+			names = append(names, http.CanonicalHeaderKey(name))
+		}
+		headers["Vary"] = strings.Join(names, ", ")
+	}
+	return &events.LambdaFunctionURLResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       body,
+	}, nil
+}
+
+// Redirect builds a Function URL response redirecting the caller to location, with a 301, 302, 303, 307, or 308 status.
+// location may be an absolute URL, or a URL relative to the request, such as "/login". It is sent as given.
+// The response is also valid for API Gateway HTTP APIs using the 2.0 payload format.
+//
+// Usage:
+//
+//	lambda.Start(func() (*events.LambdaFunctionURLResponse, error) {
+//		return lambda.Redirect(http.StatusFound, "https://example.com/login")
+//	})
+func Redirect(status int, location string) (*events.LambdaFunctionURLResponse, error) {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return nil, fmt.Errorf("status %d is not a redirect status", status)
+	}
+	if location == "" {
+		return nil, fmt.Errorf("redirect location must not be empty")
+	}
+	if strings.ContainsAny(location, "\r\n") {
+		return nil, fmt.Errorf("redirect location %q must not contain line breaks", location)
+	}
+	if _, err := url.Parse(location); err != nil {
+		return nil, fmt.Errorf("redirect location %q is not a valid URL: %v", location, err)
+	}
+	return &events.LambdaFunctionURLResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Location": location},
+	}, nil
+}
+
+// ObjectMetadata describes an object served with ObjectResponse, typically from the output of an S3 GetObject call.
+type ObjectMetadata struct {
+	ContentType  string        // ContentType is detected from the object's contents when empty
+	ETag         string        // ETag is sent as is, and must include its quotes
+	LastModified time.Time     // LastModified is not sent when zero
+	MaxAge       time.Duration // MaxAge sets Cache-Control to "public, max-age=N" when positive, and "no-cache" otherwise
+}
+
+// ObjectResponse builds a Function URL response serving a small object, such as a file read from S3, without redirecting to a pre-signed URL.
+// It sets the Content-Type, Content-Length, and caching headers. Binary content is base64 encoded.
+// Function URL responses are limited to 6 MB, so larger objects should be streamed instead.
+//
+// Usage:
+//
+//	lambda.Start(func(ctx context.Context) (*events.LambdaFunctionURLResponse, error) {
+//		object, err := s3Client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("bucket"), Key: aws.String("logo.png")})
+//		if err != nil {
+//			return nil, err
+//		}
+//		defer object.Body.Close()
+//		body, err := io.ReadAll(object.Body)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return lambda.ObjectResponse(body, lambda.ObjectMetadata{
+//			ContentType:  aws.ToString(object.ContentType),
+//			ETag:         aws.ToString(object.ETag),
+//			LastModified: aws.ToTime(object.LastModified),
+//			MaxAge:       time.Hour,
+//		}), nil
+//	})
+func ObjectResponse(body []byte, metadata ObjectMetadata) *events.LambdaFunctionURLResponse {
+	contentType := metadata.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	headers := map[string]string{
+		"Content-Type":   contentType,
+		"Content-Length": strconv.Itoa(len(body)),
+		"Cache-Control":  "no-cache",
+	}
+	if metadata.MaxAge > 0 {
+		headers["Cache-Control"] = "public, max-age=" + strconv.FormatInt(int64(metadata.MaxAge/time.Second), 10)
+	}
+	if metadata.ETag != "" {
+		headers["ETag"] = metadata.ETag
+	}
+	if !metadata.LastModified.IsZero() {
+		headers["Last-Modified"] = metadata.LastModified.UTC().Format(http.TimeFormat)
+	}
+	response := &events.LambdaFunctionURLResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+	}
+	if isTextContentType(contentType) && utf8.Valid(body) {
+		response.Body = string(body)
+	} else {
+		response.Body = base64.StdEncoding.EncodeToString(body)
+		response.IsBase64Encoded = true
+	}
+	return response
+}
+
+// isTextContentType reports whether a response with contentType can be sent without base64 encoding
+func isTextContentType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json",
+		mediaType == "application/javascript",
+		mediaType == "application/xml",
+		mediaType == "image/svg+xml",
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	return false
+}
+
+// isHeaderToken reports whether s is a non-empty RFC 7230 token.
+func isHeaderToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// withResponseHeader returns val with the header added, when val is a proxy, ALB, or Function URL response.
+// Responses and their header maps are copied before being modified, so that the value returned by the handler, and maps shared between responses, are left untouched.
+// Any other value is returned unchanged.
+func withResponseHeader(val interface{}, name, value string) interface{} {
+	switch response := val.(type) {
+	case events.APIGatewayProxyResponse:
+		response.Headers = copyWithHeader(response.Headers, name, value)
+		return response
+	case *events.APIGatewayProxyResponse:
+		if response != nil {
+			copied := *response
+			copied.Headers = copyWithHeader(copied.Headers, name, value)
+			return &copied
+		}
+	case events.APIGatewayV2HTTPResponse:
+		response.Headers = copyWithHeader(response.Headers, name, value)
+		return response
+	case *events.APIGatewayV2HTTPResponse:
+		if response != nil {
+			copied := *response
+			copied.Headers = copyWithHeader(copied.Headers, name, value)
+			return &copied
+		}
+	case events.ALBTargetGroupResponse:
+		response.Headers = copyWithHeader(response.Headers, name, value)
+		return response
+	case *events.ALBTargetGroupResponse:
+		if response != nil {
+			copied := *response
+			copied.Headers = copyWithHeader(copied.Headers, name, value)
+			return &copied
+		}
+	case events.LambdaFunctionURLResponse:
+		response.Headers = copyWithHeader(response.Headers, name, value)
+		return response
+	case *events.LambdaFunctionURLResponse:
+		if response != nil {
+			copied := *response
+			copied.Headers = copyWithHeader(copied.Headers, name, value)
+			return &copied
+		}
+	case *events.LambdaFunctionURLStreamingResponse:
+		if response != nil {
+			copied := *response
+			copied.Headers = copyWithHeader(copied.Headers, name, value)
+			return &copied
+		}
+	}
+	return val
+}
+
+func copyWithHeader(headers map[string]string, name, value string) map[string]string {
+	copied := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		copied[k] = v
+	}
+	copied[name] = value
+	return copied
+}
@@ -0,0 +1,256 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var (
+	headerType          = reflect.TypeOf(http.Header{})
+	headerPointerType   = reflect.TypeOf(&http.Header{})
+	contentTypeMetadata = reflect.TypeOf((*interface{ ContentType() string })(nil)).Elem()
+	headersMetadata     = reflect.TypeOf((*interface{ Headers() http.Header })(nil)).Elem()
+	statusCodeMetadata  = reflect.TypeOf((*interface{ StatusCode() int })(nil)).Elem()
+)
+
+// isResponseMetadataType reports whether t may be the second of three values returned by a handler
+func isResponseMetadataType(t reflect.Type) bool {
+	if t == headerType || t == headerPointerType {
+		return true
+	}
+	return t.Implements(contentTypeMetadata) || t.Implements(headersMetadata) || t.Implements(statusCodeMetadata)
+}
+
+// responseMetadata is the content type, headers, and status code of the metadata returned by a handler along with its response
+type responseMetadata struct {
+	contentType string
+	header      http.Header
+	status      int
+}
+
+func readResponseMetadata(metadata reflect.Value) responseMetadata {
+	var m responseMetadata
+	switch value := metadata.Interface().(type) {
+	case http.Header:
+		m.header = value
+	case *http.Header:
+		m.header = *value
+	default:
+		if contentType, ok := value.(interface{ ContentType() string }); ok {
+			m.contentType = contentType.ContentType()
+		}
+		if headers, ok := value.(interface{ Headers() http.Header }); ok {
+			m.header = headers.Headers()
+		}
+		if status, ok := value.(interface{ StatusCode() int }); ok {
+			m.status = status.StatusCode()
+		}
+	}
+	return m
+}
+
+// withResponseMetadata applies the content type, headers, and status code of metadata, returned by a handler along with its response, to body.
+// A response with headers or a status code is streamed with the http integration prelude of Function URL response streaming,
+// as an io.Reader "TOut" implementing them would be. Proxy responses are handled by mergeResponseMetadata instead.
+// body is returned unchanged when metadata is nil, or has neither.
+func withResponseMetadata(body io.Reader, metadata reflect.Value) io.Reader {
+	if isNilValue(metadata) {
+		return body
+	}
+	m := readResponseMetadata(metadata)
+	response := &metadataResponse{Reader: body, header: m.header, status: m.status}
+	if contentType, ok := body.(interface{ ContentType() string }); ok {
+		response.contentType = contentType.ContentType()
+	}
+	if contentEncoding, ok := body.(interface{ ContentEncoding() string }); ok {
+		response.contentEncoding = contentEncoding.ContentEncoding()
+	}
+	if m.contentType != "" {
+		response.contentType = m.contentType
+	}
+	if response.header == nil && response.status == 0 && response.contentEncoding == "" {
+		if response.contentType == "" {
+			return body
+		}
+		return &contentTypeResponse{body, response.contentType}
+	}
+	return response
+}
+
+// mergeResponseMetadata sets the content type, headers, and status code of metadata on val, when val is an API Gateway, ALB, or Function URL response,
+// as these are sent as JSON for the integration to build the HTTP response from, and would be broken by the streaming prelude.
+// The response is copied, along with its header maps, so that the value returned by the handler is left untouched.
+// The second return value is false, and val is returned unchanged, for any other response.
+func mergeResponseMetadata(val interface{}, metadata reflect.Value) (interface{}, bool) {
+	switch val.(type) {
+	case events.APIGatewayProxyResponse, *events.APIGatewayProxyResponse,
+		events.APIGatewayV2HTTPResponse, *events.APIGatewayV2HTTPResponse,
+		events.ALBTargetGroupResponse, *events.ALBTargetGroupResponse,
+		events.LambdaFunctionURLResponse, *events.LambdaFunctionURLResponse:
+	default:
+		return val, false
+	}
+	if isNilValue(metadata) || isNilValue(reflect.ValueOf(val)) {
+		return val, true
+	}
+	m := readResponseMetadata(metadata)
+	switch response := val.(type) {
+	case events.APIGatewayProxyResponse:
+		response.StatusCode, response.Headers, response.MultiValueHeaders = m.mergeMultiValue(response.StatusCode, response.Headers, response.MultiValueHeaders)
+		return response, true
+	case *events.APIGatewayProxyResponse:
+		copied := *response
+		copied.StatusCode, copied.Headers, copied.MultiValueHeaders = m.mergeMultiValue(copied.StatusCode, copied.Headers, copied.MultiValueHeaders)
+		return &copied, true
+	case events.ALBTargetGroupResponse:
+		response.StatusCode, response.Headers, response.MultiValueHeaders = m.mergeMultiValue(response.StatusCode, response.Headers, response.MultiValueHeaders)
+		return response, true
+	case *events.ALBTargetGroupResponse:
+		copied := *response
+		copied.StatusCode, copied.Headers, copied.MultiValueHeaders = m.mergeMultiValue(copied.StatusCode, copied.Headers, copied.MultiValueHeaders)
+		return &copied, true
+	case events.APIGatewayV2HTTPResponse:
+		response.StatusCode, response.Headers, response.Cookies = m.mergeCookies(response.StatusCode, response.Headers, response.Cookies)
+		return response, true
+	case *events.APIGatewayV2HTTPResponse:
+		copied := *response
+		copied.StatusCode, copied.Headers, copied.Cookies = m.mergeCookies(copied.StatusCode, copied.Headers, copied.Cookies)
+		return &copied, true
+	case events.LambdaFunctionURLResponse:
+		response.StatusCode, response.Headers, response.Cookies = m.mergeCookies(response.StatusCode, response.Headers, response.Cookies)
+		return response, true
+	case *events.LambdaFunctionURLResponse:
+		copied := *response
+		copied.StatusCode, copied.Headers, copied.Cookies = m.mergeCookies(copied.StatusCode, copied.Headers, copied.Cookies)
+		return &copied, true
+	}
+	return val, true
+}
+
+// mergeMultiValue merges the metadata into the fields of a REST API or ALB response, which sends Set-Cookie, like any header with several values, as a multi-value header
+func (m responseMetadata) mergeMultiValue(status int, headers map[string]string, multiValueHeaders map[string][]string) (int, map[string]string, map[string][]string) {
+	headers = m.mergeHeaders(headers, func(key string, values []string) bool {
+		if len(values) < 2 && !strings.EqualFold(key, "Set-Cookie") {
+			return false
+		}
+		copied := make(map[string][]string, len(multiValueHeaders)+1)
+		for k, v := range multiValueHeaders {
+			copied[k] = v
+		}
+		copied[key] = append(append([]string(nil), copied[key]...), values...)
+		multiValueHeaders = copied
+		return true
+	})
+	if m.status != 0 {
+		status = m.status
+	}
+	return status, headers, multiValueHeaders
+}
+
+// mergeCookies merges the metadata into the fields of an HTTP API or Function URL response, which sends Set-Cookie headers as cookies
+func (m responseMetadata) mergeCookies(status int, headers map[string]string, cookies []string) (int, map[string]string, []string) {
+	headers = m.mergeHeaders(headers, func(key string, values []string) bool {
+		if !strings.EqualFold(key, "Set-Cookie") {
+			return false
+		}
+		cookies = append(append([]string(nil), cookies...), values...)
+		return true
+	})
+	if m.status != 0 {
+		status = m.status
+	}
+	return status, headers, cookies
+}
+
+// mergeHeaders returns a copy of headers with the content type and headers of the metadata, leaving out those for which special returns true
+func (m responseMetadata) mergeHeaders(headers map[string]string, special func(key string, values []string) bool) map[string]string {
+	if m.contentType == "" && len(m.header) == 0 {
+		return headers
+	}
+	copied := make(map[string]string, len(headers)+len(m.header)+1)
+	for k, v := range headers {
+		copied[k] = v
+	}
+	if m.contentType != "" {
+		copied["Content-Type"] = m.contentType
+	}
+	for key, values := range m.header {
+		if len(values) == 0 || special(key, values) {
+			continue
+		}
+		copied[key] = strings.Join(values, ", ")
+	}
+	return copied
+}
+
+// contentTypeResponse is a handler's response, with the content type returned along with it
+type contentTypeResponse struct {
+	io.Reader
+	contentType string
+}
+
+func (r *contentTypeResponse) ContentType() string {
+	return r.contentType
+}
+
+// Close closes the response, if it needs to be closed
+func (r *contentTypeResponse) Close() error {
+	if closer, ok := r.Reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// metadataResponse is a handler's response, with the headers and status code returned along with it, and the content encoding of the response
+type metadataResponse struct {
+	io.Reader
+	contentType     string
+	contentEncoding string
+	header          http.Header
+	status          int
+}
+
+func (r *metadataResponse) ContentType() string {
+	if r.contentType == "" {
+		return contentTypeBytes
+	}
+	return r.contentType
+}
+
+func (r *metadataResponse) ContentEncoding() string {
+	return r.contentEncoding
+}
+
+func (r *metadataResponse) Headers() http.Header {
+	return r.header
+}
+
+func (r *metadataResponse) StatusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// Close closes the response, if it needs to be closed
+func (r *metadataResponse) Close() error {
+	if closer, ok := r.Reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// isNilValue reports whether v is a nil pointer, map, interface, slice, func, or chan
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
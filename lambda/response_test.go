@@ -0,0 +1,202 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheableResponse(t *testing.T) {
+	response, err := CacheableResponse("hello", 90*time.Second, "accept-encoding", " Origin ")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "hello", response.Body)
+	assert.Equal(t, map[string]string{
+		"Cache-Control":     "public, max-age=90",
+		"Surrogate-Control": "max-age=90",
+		"Vary":              "Accept-Encoding, Origin",
+	}, response.Headers)
+}
+
+func TestCacheableResponseNoVary(t *testing.T) {
+	response, err := CacheableResponse("hello", 1500*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, "public, max-age=1", response.Headers["Cache-Control"])
+	assert.NotContains(t, response.Headers, "Vary")
+}
+
+func TestCacheableResponseValidation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		maxAge   time.Duration
+		vary     []string
+		expected string
+	}{
+		{"negative max-age", -time.Second, nil, "max-age must not be negative, got -1s"},
+		{"empty vary", time.Second, []string{""}, `vary header name "" is not a valid HTTP header name`},
+		{"vary with separator", time.Second, []string{"Accept,Origin"}, `vary header name "Accept,Origin" is not a valid HTTP header name`},
+		{"vary with space", time.Second, []string{"Accept Encoding"}, `vary header name "Accept Encoding" is not a valid HTTP header name`},
+		{"vary star combined", time.Second, []string{"*", "Origin"}, `vary "*" must not be combined with other header names`},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := CacheableResponse("", testCase.maxAge, testCase.vary...)
+			assert.EqualError(t, err, testCase.expected)
+		})
+	}
+
+	response, err := CacheableResponse("", time.Second, "*")
+	require.NoError(t, err)
+	assert.Equal(t, "*", response.Headers["Vary"])
+}
+
+func TestRequestIDResponseHeader(t *testing.T) {
+	shared := map[string]string{"Content-Type": "text/plain"}
+	testCases := []struct {
+		name     string
+		header   string
+		handler  interface{}
+		expected string
+	}{
+		{
+			name:   "default header name on a proxy response",
+			header: "",
+			handler: func() (events.APIGatewayProxyResponse, error) {
+				return events.APIGatewayProxyResponse{StatusCode: 200, Headers: shared}, nil
+			},
+			expected: `{"statusCode":200,"headers":{"Content-Type":"text/plain","X-Amzn-RequestId":"dummyid"},"multiValueHeaders":null,"body":""}`,
+		},
+		{
+			name:   "custom header name on a Function URL response pointer",
+			header: "X-Request-Id",
+			handler: func() (*events.LambdaFunctionURLResponse, error) {
+				return &events.LambdaFunctionURLResponse{StatusCode: 200}, nil
+			},
+			expected: `{"statusCode":200,"headers":{"X-Request-Id":"dummyid"},"body":"","isBase64Encoded":false,"cookies":null}`,
+		},
+		{
+			name:   "http api response",
+			header: "",
+			handler: func() (events.APIGatewayV2HTTPResponse, error) {
+				return events.APIGatewayV2HTTPResponse{StatusCode: 204}, nil
+			},
+			expected: `{"statusCode":204,"headers":{"X-Amzn-RequestId":"dummyid"},"multiValueHeaders":null,"body":"","cookies":null}`,
+		},
+		{
+			name:   "other responses are unchanged",
+			header: "",
+			handler: func() (map[string]string, error) {
+				return shared, nil
+			},
+			expected: `{"Content-Type":"text/plain"}`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := NewHandlerWithOptions(testCase.handler, WithRequestIDResponseHeader(testCase.header))
+			ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "dummyid"})
+			response, err := handler.Invoke(ctx, []byte(`{}`))
+			require.NoError(t, err)
+			assert.JSONEq(t, testCase.expected, string(response))
+		})
+	}
+	assert.Equal(t, map[string]string{"Content-Type": "text/plain"}, shared)
+}
+
+func TestRequestIDResponseHeaderLeavesPointerResponsesUntouched(t *testing.T) {
+	returned := &events.ALBTargetGroupResponse{StatusCode: 200, Headers: map[string]string{"Content-Type": "text/plain"}}
+	handler := NewHandlerWithOptions(func() (*events.ALBTargetGroupResponse, error) {
+		return returned, nil
+	}, WithRequestIDResponseHeader(""))
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "dummyid"})
+	response, err := handler.Invoke(ctx, []byte(`{}`))
+	require.NoError(t, err)
+	assert.Contains(t, string(response), `"X-Amzn-RequestId":"dummyid"`)
+	assert.Equal(t, &events.ALBTargetGroupResponse{StatusCode: 200, Headers: map[string]string{"Content-Type": "text/plain"}}, returned)
+}
+
+func TestRedirect(t *testing.T) {
+	for _, location := range []string{"https://example.com/login?next=%2Fhome", "/login", "../up", "//example.com/"} {
+		response, err := Redirect(http.StatusFound, location)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusFound, response.StatusCode)
+		assert.Equal(t, map[string]string{"Location": location}, response.Headers)
+		assert.Empty(t, response.Body)
+	}
+
+	for _, status := range []int{301, 303, 307, 308} {
+		response, err := Redirect(status, "/")
+		require.NoError(t, err)
+		assert.Equal(t, status, response.StatusCode)
+	}
+}
+
+func TestRedirectValidation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   int
+		location string
+		expected string
+	}{
+		{"success status", http.StatusOK, "/", "status 200 is not a redirect status"},
+		{"not modified", http.StatusNotModified, "/", "status 304 is not a redirect status"},
+		{"empty location", http.StatusFound, "", "redirect location must not be empty"},
+		{"header injection", http.StatusFound, "/\r\nSet-Cookie: a=b", `redirect location "/\r\nSet-Cookie: a=b" must not contain line breaks`},
+		{"invalid url", http.StatusFound, "https://exa mple.com/", `redirect location "https://exa mple.com/" is not a valid URL: parse "https://exa mple.com/": invalid character " " in host name`},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := Redirect(testCase.status, testCase.location)
+			assert.EqualError(t, err, testCase.expected)
+		})
+	}
+}
+
+func TestObjectResponsePNG(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	lastModified := time.Date(2023, 5, 4, 3, 2, 1, 0, time.FixedZone("PDT", -7*60*60))
+	response := ObjectResponse(png, ObjectMetadata{
+		ETag:         `"d41d8cd98f00b204e9800998ecf8427e"`,
+		LastModified: lastModified,
+		MaxAge:       time.Hour,
+	})
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, map[string]string{
+		"Content-Type":   "image/png",
+		"Content-Length": "16",
+		"Cache-Control":  "public, max-age=3600",
+		"ETag":           `"d41d8cd98f00b204e9800998ecf8427e"`,
+		"Last-Modified":  "Thu, 04 May 2023 10:02:01 GMT",
+	}, response.Headers)
+	assert.True(t, response.IsBase64Encoded)
+	decoded, err := base64.StdEncoding.DecodeString(response.Body)
+	require.NoError(t, err)
+	assert.Equal(t, png, decoded)
+}
+
+func TestObjectResponseJSON(t *testing.T) {
+	body := []byte(`{"hello":"world"}`)
+	response := ObjectResponse(body, ObjectMetadata{ContentType: "application/json; charset=utf-8"})
+	assert.Equal(t, map[string]string{
+		"Content-Type":   "application/json; charset=utf-8",
+		"Content-Length": "17",
+		"Cache-Control":  "no-cache",
+	}, response.Headers)
+	assert.False(t, response.IsBase64Encoded)
+	assert.Equal(t, `{"hello":"world"}`, response.Body)
+}
+
+func TestObjectResponseInvalidUTF8Text(t *testing.T) {
+	response := ObjectResponse([]byte{'a', 0xff}, ObjectMetadata{ContentType: "text/plain"})
+	assert.True(t, response.IsBase64Encoded)
+	assert.Equal(t, "Yf8=", response.Body)
+}
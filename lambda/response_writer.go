@@ -0,0 +1,258 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda/messages"
+)
+
+// ResponseWriter is used by streaming handlers to write the response of a Function URL that uses the RESPONSE_STREAM invoke mode.
+// Writes are buffered, and sent to the caller as the buffer fills or when Flush is called.
+//
+// Usage:
+//
+//	lambda.Start(func(ctx context.Context, w lambda.ResponseWriter) error {
+//		w.SetHeader("Content-Type", "text/plain")
+//		for i := 0; i < 3; i++ {
+//			fmt.Fprintf(w, "tick %d\n", i)
+//			if err := w.Flush(); err != nil {
+//				return err
+//			}
+//			time.Sleep(time.Second)
+//		}
+//		return nil
+//	})
+type ResponseWriter interface {
+	// SetHeader sets a response header. Set-Cookie headers are sent as cookies, and may be set more than once.
+	// Headers set after the first call to Write or Flush are ignored.
+	SetHeader(key, value string)
+	// SetStatus sets the response status code, which defaults to 200.
+	// The status set after the first call to Write or Flush is ignored.
+	SetStatus(statusCode int)
+	// Write writes p to the response body. The status and headers are sent before the first write.
+	Write(p []byte) (int, error)
+	// Flush sends the buffered response to the caller.
+	Flush() error
+}
+
+var responseWriterType = reflect.TypeOf((*ResponseWriter)(nil)).Elem()
+
+var errWriteAfterReturn = errors.New("lambda: ResponseWriter used after the handler returned")
+
+type streamingResponseWriter struct {
+	mu       sync.Mutex
+	response *events.LambdaFunctionURLStreamingResponse
+	pipe     *io.PipeWriter
+	body     *bufio.Writer
+	started  chan struct{} // closed at the first Write or Flush
+	finished bool
+	failure  *messages.InvokeResponse_Error // failure is the error sent as the trailer of a started response
+}
+
+// streamingBody is the body of the response of a streaming handler
+type streamingBody struct {
+	*io.PipeReader
+	writer *streamingResponseWriter
+}
+
+// streamedFailure returns the error sent as the trailer of a streaming handler's response, once it has been sent
+func streamedFailure(response io.Reader) *messages.InvokeResponse_Error {
+	streaming, ok := response.(*events.LambdaFunctionURLStreamingResponse)
+	if !ok {
+		return nil
+	}
+	body, ok := streaming.Body.(*streamingBody)
+	if !ok {
+		return nil
+	}
+	body.writer.mu.Lock()
+	defer body.writer.mu.Unlock()
+	return body.writer.failure
+}
+
+func newStreamingResponseWriter() *streamingResponseWriter {
+	reader, writer := io.Pipe()
+	w := &streamingResponseWriter{
+		response: &events.LambdaFunctionURLStreamingResponse{
+			StatusCode: http.StatusOK,
+			Headers:    map[string]string{},
+		},
+		pipe:    writer,
+		body:    bufio.NewWriter(writer),
+		started: make(chan struct{}),
+	}
+	w.response.Body = &streamingBody{reader, w}
+	return w
+}
+
+func (w *streamingResponseWriter) isStarted() bool {
+	select {
+	case <-w.started:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *streamingResponseWriter) SetHeader(key, value string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.isStarted() {
+		return
+	}
+	if strings.EqualFold(key, "Set-Cookie") {
+		w.response.Cookies = append(w.response.Cookies, value)
+		return
+	}
+	w.response.Headers[key] = value
+}
+
+func (w *streamingResponseWriter) SetStatus(statusCode int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.isStarted() {
+		w.response.StatusCode = statusCode
+	}
+}
+
+func (w *streamingResponseWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.finished {
+		return 0, errWriteAfterReturn
+	}
+	w.start()
+	return w.body.Write(p)
+}
+
+func (w *streamingResponseWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.finished {
+		return errWriteAfterReturn
+	}
+	w.start()
+	return w.body.Flush()
+}
+
+func (w *streamingResponseWriter) start() {
+	if !w.isStarted() {
+		close(w.started)
+	}
+}
+
+// finish is called once the handler returns. It reports whether the response had already started streaming,
+// in which case the rest of the response is flushed, and err is sent to the caller as the stream's error trailer.
+func (w *streamingResponseWriter) finish(err error) (streaming bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.finished = true
+	if !w.isStarted() {
+		return false
+	}
+	if err == nil {
+		err = w.body.Flush()
+	}
+	if err != nil {
+		w.failure = lambdaErrorResponse(err)
+		_ = w.pipe.CloseWithError(err)
+	} else {
+		_ = w.pipe.Close()
+	}
+	return true
+}
+
+// streamingHandler adapts handlers of the form func(context.Context, ResponseWriter) error, or func(context.Context, TIn, ResponseWriter) error.
+// The handler runs in its own goroutine. The response is returned once the handler first writes,
+// so that the Runtime API receives the body while the handler continues writing it.
+func streamingHandler(handler reflect.Value, handlerType reflect.Type, h *handlerOptions) handlerFunc {
+	if err := validateStreamingHandler(handlerType); err != nil {
+		return errorHandler(err)
+	}
+	return func(ctx context.Context, payload []byte) (io.Reader, error) {
+		args := []reflect.Value{reflect.ValueOf(ctx)}
+		if handlerType.NumIn() == 3 {
+			decoder := json.NewDecoder(bytes.NewReader(payload))
+			if h.jsonRequestUseNumber {
+				decoder.UseNumber()
+			}
+			if h.jsonRequestDisallowUnknownFields {
+				decoder.DisallowUnknownFields()
+			}
+			eventType := handlerType.In(1)
+			event := reflect.New(eventType)
+			if h.unmarshaler == nil {
+				if err := decoder.Decode(event.Interface()); err != nil {
+					return nil, err
+				}
+			} else if eventType == bytesType || eventType == rawMessageType {
+				event.Elem().SetBytes(append([]byte(nil), payload...))
+			} else if err := h.unmarshaler(payload, event.Interface()); err != nil {
+				return nil, err
+			}
+			args = append(args, event.Elem())
+		}
+		w := newStreamingResponseWriter()
+		args = append(args, reflect.ValueOf(ResponseWriter(w)))
+
+		// the handler's error, or its panic as an error requiring the process to exit, when it returns before starting the response
+		done := make(chan error, 1)
+		go func() {
+			var err error
+			defer func() {
+				// the panic response is built here, so that its stack trace is the handler's
+				if p := recover(); p != nil {
+					panicErr := lambdaPanicResponse(p)
+					formatPanicMessage(panicErr, p, h.panicValueFormatter)
+					err = *panicErr
+				}
+				if !w.finish(err) {
+					done <- err
+				}
+			}()
+			if errVal, ok := handler.Call(args)[0].Interface().(error); ok && errVal != nil {
+				err = errVal
+			}
+		}()
+
+		select {
+		case <-w.started:
+			return w.response, nil
+		case err := <-done:
+			if err != nil {
+				return nil, err
+			}
+			// nothing was written, send the status and headers with an empty body
+			_ = w.pipe.Close()
+			return w.response, nil
+		}
+	}
+}
+
+func validateStreamingHandler(handlerType reflect.Type) error {
+	contextType := reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	if n := handlerType.NumIn(); n != 2 && n != 3 {
+		return fmt.Errorf("streaming handlers must take a context.Context, an optional event, and a lambda.ResponseWriter, but handler takes %d arguments", n)
+	}
+	if handlerType.In(0) != contextType {
+		return fmt.Errorf("streaming handlers must take a context.Context as the first argument, got %s", handlerType.In(0))
+	}
+	if handlerType.NumOut() != 1 || handlerType.Out(0) != errorType {
+		return fmt.Errorf("streaming handlers must return only an error")
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambda/messages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const streamingPreludeSeparator = "\x00\x00\x00\x00\x00\x00\x00\x00"
+
+// splitPrelude splits a streamed response around the separator that ends its prelude
+func splitPrelude(response string) (prelude, body string, found bool) {
+	i := strings.Index(response, streamingPreludeSeparator)
+	if i < 0 {
+		return response, "", false
+	}
+	return response[:i], response[i+len(streamingPreludeSeparator):], true
+}
+
+func TestResponseWriterStreamsPreludeAndBody(t *testing.T) {
+	ts, record := runtimeAPIServer(`{"name":"gopher"}`, 1)
+	defer ts.Close()
+	handler := NewHandler(func(ctx context.Context, event map[string]string, w ResponseWriter) error {
+		w.SetStatus(http.StatusCreated)
+		w.SetHeader("Content-Type", "text/plain")
+		w.SetHeader("Set-Cookie", "a=1")
+		w.SetHeader("Set-Cookie", "b=2")
+		if _, err := fmt.Fprintf(w, "hello %s", event["name"]); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		// headers and status are already sent
+		w.SetStatus(http.StatusTeapot)
+		w.SetHeader("X-Late", "ignored")
+		_, err := w.Write([]byte("!"))
+		return err
+	})
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 1, record.nPosts)
+
+	assert.Equal(t, contentTypeHTTPIntegrationResponse, record.contentTypes[0])
+	assert.Equal(t, responseModeStreaming, record.responseModes[0])
+	prelude, body, found := splitPrelude(string(record.responses[0]))
+	require.True(t, found)
+	assert.JSONEq(t, `{"statusCode":201,"headers":{"Content-Type":"text/plain"},"cookies":["a=1","b=2"]}`, prelude)
+	assert.Equal(t, "hello gopher!", body)
+	assert.Equal(t, "", record.errorTrailers[0])
+}
+
+func TestResponseWriterWithoutEvent(t *testing.T) {
+	ts, record := runtimeAPIServer(`not json`, 1)
+	defer ts.Close()
+	handler := NewHandler(func(ctx context.Context, w ResponseWriter) error {
+		_, err := w.Write([]byte("no event"))
+		return err
+	})
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 1, record.nPosts)
+	assert.Equal(t, `{"statusCode":200}`+streamingPreludeSeparator+"no event", string(record.responses[0]))
+}
+
+func TestResponseWriterWithUnmarshaler(t *testing.T) {
+	ts, record := runtimeAPIServer(`name=gopher`, 1)
+	defer ts.Close()
+	handler := NewHandlerWithOptions(func(ctx context.Context, event map[string]string, w ResponseWriter) error {
+		_, err := fmt.Fprintf(w, "hello %s", event["name"])
+		return err
+	}, WithUnmarshaler(func(data []byte, v interface{}) error {
+		pair := strings.SplitN(string(data), "=", 2)
+		*(v.(*map[string]string)) = map[string]string{pair[0]: pair[1]}
+		return nil
+	}))
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 1, record.nPosts)
+	assert.Equal(t, `{"statusCode":200}`+streamingPreludeSeparator+"hello gopher", string(record.responses[0]))
+}
+
+func TestResponseWriterNoWrites(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	handler := NewHandler(func(ctx context.Context, w ResponseWriter) error {
+		w.SetStatus(http.StatusNoContent)
+		return nil
+	})
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 1, record.nPosts)
+	assert.Equal(t, responseModeStreaming, record.responseModes[0])
+	assert.Equal(t, `{"statusCode":204}`+streamingPreludeSeparator, string(record.responses[0]))
+}
+
+func TestResponseWriterErrorBeforeWriting(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	handler := NewHandler(func(ctx context.Context, w ResponseWriter) error {
+		return errors.New("nothing to stream")
+	})
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 1, record.nPosts)
+	assert.Equal(t, contentTypeJSON, record.contentTypes[0])
+	assert.Equal(t, "", record.responseModes[0])
+	assert.JSONEq(t, `{"errorMessage":"nothing to stream","errorType":"errorString"}`, string(record.responses[0]))
+}
+
+func TestResponseWriterErrorAfterWriting(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	handler := NewHandler(func(ctx context.Context, w ResponseWriter) error {
+		_, _ = w.Write([]byte("partial"))
+		_ = w.Flush()
+		return errors.New("stream interrupted")
+	})
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 1, record.nPosts)
+	assert.Equal(t, `{"statusCode":200}`+streamingPreludeSeparator+"partial", string(record.responses[0]))
+	assert.Equal(t, "errorString", record.errorTrailers[0])
+}
+
+func panicBeforeStreaming(ctx context.Context, w ResponseWriter) error {
+	panic("oops")
+}
+
+func panicAfterStreaming(ctx context.Context, w ResponseWriter) error {
+	_, _ = w.Write([]byte("partial"))
+	_ = w.Flush()
+	panic("oops")
+}
+
+func stackTraceLabels(invokeErr *messages.InvokeResponse_Error) []string {
+	var labels []string
+	for _, frame := range invokeErr.StackTrace {
+		labels = append(labels, frame.Label)
+	}
+	return labels
+}
+
+func TestResponseWriterPanicBeforeWriting(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	handler := NewHandler(panicBeforeStreaming)
+	err := startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.EqualError(t, err, "calling the handler function resulted in a panic, the process should exit")
+	require.Equal(t, 1, record.nPosts)
+	var invokeErr messages.InvokeResponse_Error
+	require.NoError(t, json.Unmarshal(record.responses[0], &invokeErr))
+	assert.Equal(t, "oops", invokeErr.Message)
+	assert.Contains(t, stackTraceLabels(&invokeErr), "panicBeforeStreaming")
+}
+
+func TestResponseWriterPanicAfterWriting(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 2)
+	defer ts.Close()
+	var crashes []*messages.InvokeResponse_Error
+	var infos []InvokeInfo
+	handler := NewHandlerWithOptions(panicAfterStreaming, WithCrashReporter(func(ctx context.Context, invokeErr *messages.InvokeResponse_Error) {
+		crashes = append(crashes, invokeErr)
+	}), WithInvokeObserver(func(info InvokeInfo) {
+		infos = append(infos, info)
+	}))
+	err := startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.EqualError(t, err, "calling the handler function resulted in a panic, the process should exit")
+	require.Equal(t, 1, record.nPosts, "the process should exit instead of serving the next invoke")
+	assert.Equal(t, `{"statusCode":200}`+streamingPreludeSeparator+"partial", string(record.responses[0]))
+	assert.Equal(t, "string", record.errorTrailers[0])
+	require.Len(t, crashes, 1)
+	assert.Equal(t, "oops", crashes[0].Message)
+	assert.Contains(t, stackTraceLabels(crashes[0]), "panicAfterStreaming")
+	require.Len(t, infos, 1)
+	assert.True(t, infos[0].Failed)
+	assert.True(t, infos[0].Panicked)
+}
+
+func TestResponseWriterWriteAfterReturn(t *testing.T) {
+	var leaked ResponseWriter
+	handler := NewHandler(func(ctx context.Context, w ResponseWriter) error {
+		leaked = w
+		_, err := w.Write([]byte("done"))
+		return err
+	})
+	response, err := handler.Invoke(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"statusCode":200}`+streamingPreludeSeparator+"done", string(response))
+
+	_, err = leaked.Write([]byte("late"))
+	assert.Equal(t, errWriteAfterReturn, err)
+	assert.Equal(t, errWriteAfterReturn, leaked.Flush())
+}
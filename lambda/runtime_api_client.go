@@ -6,36 +6,55 @@ package lambda
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil" //nolint: staticcheck
 	"log"
 	"net/http"
 	"runtime"
+	"time"
 )
 
 const (
-	headerAWSRequestID       = "Lambda-Runtime-Aws-Request-Id"
-	headerDeadlineMS         = "Lambda-Runtime-Deadline-Ms"
-	headerTraceID            = "Lambda-Runtime-Trace-Id"
-	headerCognitoIdentity    = "Lambda-Runtime-Cognito-Identity"
-	headerClientContext      = "Lambda-Runtime-Client-Context"
-	headerInvokedFunctionARN = "Lambda-Runtime-Invoked-Function-Arn"
-	headerXRayErrorCause     = "Lambda-Runtime-Function-Xray-Error-Cause"
-	trailerLambdaErrorType   = "Lambda-Runtime-Function-Error-Type"
-	trailerLambdaErrorBody   = "Lambda-Runtime-Function-Error-Body"
-	contentTypeJSON          = "application/json"
-	contentTypeBytes         = "application/octet-stream"
-	apiVersion               = "2018-06-01"
-	xrayErrorCauseMaxSize    = 1024 * 1024
+	headerAWSRequestID                 = "Lambda-Runtime-Aws-Request-Id"
+	headerDeadlineMS                   = "Lambda-Runtime-Deadline-Ms"
+	headerTraceID                      = "Lambda-Runtime-Trace-Id"
+	headerCognitoIdentity              = "Lambda-Runtime-Cognito-Identity"
+	headerClientContext                = "Lambda-Runtime-Client-Context"
+	headerInvokedFunctionARN           = "Lambda-Runtime-Invoked-Function-Arn"
+	headerXRayErrorCause               = "Lambda-Runtime-Function-Xray-Error-Cause"
+	headerResponseMode                 = "Lambda-Runtime-Function-Response-Mode"
+	headerFunctionErrorType            = "Lambda-Runtime-Function-Error-Type"
+	trailerLambdaErrorType             = "Lambda-Runtime-Function-Error-Type"
+	trailerLambdaErrorBody             = "Lambda-Runtime-Function-Error-Body"
+	contentTypeJSON                    = "application/json"
+	contentTypeBytes                   = "application/octet-stream"
+	contentTypeHTTPIntegrationResponse = "application/vnd.awslambda.http-integration-response"
+	responseModeStreaming              = "streaming"
+	apiVersion                         = "2018-06-01"
+	xrayErrorCauseMaxSize              = 1024 * 1024
 )
 
+// errRuntimeReset is returned by next when the response to /next has the status set by WithRuntimeResetStatus
+var errRuntimeReset = errors.New("the Runtime API is resetting the execution environment")
+
 type runtimeAPIClient struct {
-	baseURL    string
-	userAgent  string
-	httpClient *http.Client
-	buffer     *bytes.Buffer
+	baseURL      string
+	initErrorURL string
+	userAgent    string
+	httpClient   *http.Client
+	buffer       *bytes.Buffer
+	nextRetry    retryPolicy
+	resetStatus  int // the status of a response to /next signaling a reset, or 0
+}
+
+// retryPolicy is the number of times a failed request is retried, and the delay before the first retry, which doubles after each one
+type retryPolicy struct {
+	retries int
+	backoff time.Duration
 }
 
 func newRuntimeAPIClient(address string) *runtimeAPIClient {
@@ -43,8 +62,9 @@ func newRuntimeAPIClient(address string) *runtimeAPIClient {
 		Timeout: 0, // connections to the runtime API are never expected to time out
 	}
 	endpoint := "http://" + address + "/" + apiVersion + "/runtime/invocation/"
+	initErrorURL := "http://" + address + "/" + apiVersion + "/runtime/init/error"
 	userAgent := "aws-lambda-go/" + runtime.Version()
-	return &runtimeAPIClient{endpoint, userAgent, client, bytes.NewBuffer(nil)}
+	return &runtimeAPIClient{endpoint, initErrorURL, userAgent, client, bytes.NewBuffer(nil), retryPolicy{}, 0}
 }
 
 type invoke struct {
@@ -59,7 +79,16 @@ type invoke struct {
 //   - An invoke is not complete until next() is called again!
 func (i *invoke) success(body io.Reader, contentType string) error {
 	url := i.client.baseURL + i.id + "/response"
-	return i.client.post(url, body, contentType, nil)
+	return i.client.post(url, body, contentType, nil, "")
+}
+
+// streamingSuccess sends the response payload for an in-progress invocation as a stream.
+// The body is sent with chunked transfer encoding, so the response reaches the caller as it is read.
+// Notes:
+//   - The function must be configured with the RESPONSE_STREAM invoke mode for the caller to receive the response as a stream
+func (i *invoke) streamingSuccess(body io.Reader, contentType string) error {
+	url := i.client.baseURL + i.id + "/response"
+	return i.client.post(url, body, contentType, nil, responseModeStreaming)
 }
 
 // failure sends the payload to the Runtime API. This marks the function's invoke as a failure.
@@ -69,7 +98,34 @@ func (i *invoke) success(body io.Reader, contentType string) error {
 //     If the error is fatal (panic, unrecoverable state), exit the process immediately after calling failure()
 func (i *invoke) failure(body io.Reader, contentType string, causeForXRay []byte) error {
 	url := i.client.baseURL + i.id + "/error"
-	return i.client.post(url, body, contentType, causeForXRay)
+	return i.client.post(url, body, contentType, causeForXRay, "")
+}
+
+// initError sends the payload to the Runtime API, reporting that the function failed to initialize.
+// Notes:
+//   - The Runtime API does not send any invoke to a function that reported an init error, the process is expected to exit
+func (c *runtimeAPIClient) initError(body io.Reader, contentType string, errorType string) error {
+	req, err := http.NewRequest(http.MethodPost, c.initErrorURL, body)
+	if err != nil {
+		return fmt.Errorf("failed to construct POST request to %s: %v", c.initErrorURL, err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(headerFunctionErrorType, errorType)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to POST to %s: %v", c.initErrorURL, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("runtime API client failed to close %s response body: %v", c.initErrorURL, err)
+		}
+	}()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to POST to %s: got unexpected status code: %d", c.initErrorURL, resp.StatusCode)
+	}
+	return nil
 }
 
 // next connects to the Runtime API and waits for a new invoke Request to be available.
@@ -83,8 +139,15 @@ func (c *runtimeAPIClient) next() (*invoke, error) {
 	req.Header.Set("User-Agent", c.userAgent)
 
 	resp, err := c.httpClient.Do(req)
+	delay := c.nextRetry.backoff
+	for retry := 0; err != nil && retry < c.nextRetry.retries && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded); retry++ {
+		log.Printf("failed to get the next invoke, retrying in %s: %v", delay, err)
+		time.Sleep(delay)
+		delay *= 2
+		resp, err = c.httpClient.Do(req)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get the next invoke: %v", err)
+		return nil, fmt.Errorf("failed to get the next invoke: %w", err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -92,6 +155,9 @@ func (c *runtimeAPIClient) next() (*invoke, error) {
 		}
 	}()
 
+	if c.resetStatus != 0 && resp.StatusCode == c.resetStatus {
+		return nil, fmt.Errorf("failed to GET %s: %w", url, errRuntimeReset)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("failed to GET %s: got unexpected status code: %d", url, resp.StatusCode)
 	}
@@ -110,7 +176,7 @@ func (c *runtimeAPIClient) next() (*invoke, error) {
 	}, nil
 }
 
-func (c *runtimeAPIClient) post(url string, body io.Reader, contentType string, xrayErrorCause []byte) error {
+func (c *runtimeAPIClient) post(url string, body io.Reader, contentType string, xrayErrorCause []byte, responseMode string) error {
 	b := newErrorCapturingReader(body)
 	req, err := http.NewRequest(http.MethodPost, url, b)
 	if err != nil {
@@ -119,6 +185,9 @@ func (c *runtimeAPIClient) post(url string, body io.Reader, contentType string,
 	req.Trailer = b.Trailer
 	req.Header.Set("User-Agent", c.userAgent)
 	req.Header.Set("Content-Type", contentType)
+	if responseMode != "" {
+		req.Header.Set(headerResponseMode, responseMode)
+	}
 
 	if xrayErrorCause != nil && len(xrayErrorCause) < xrayErrorCauseMaxSize {
 		req.Header.Set(headerXRayErrorCause, string(xrayErrorCause))
@@ -0,0 +1,72 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+var readerType = reflect.TypeOf((*io.Reader)(nil)).Elem()
+
+// validateSerializable makes a best-effort check that values of type t can be encoded by encoding/json.
+// Interfaces, types that implement json.Marshaler, encoding.TextMarshaler, or io.Reader are assumed to be valid,
+// since the values they hold, or the way they are serialized, can only be known at invoke time.
+func validateSerializable(t reflect.Type) error {
+	if path, kind, ok := findUnsupportedKind(t, "", map[reflect.Type]bool{}); ok {
+		if path == "" {
+			return fmt.Errorf("handler returns type %s, which cannot be serialized to JSON: %s is not supported", t, kind)
+		}
+		return fmt.Errorf("handler returns type %s, which cannot be serialized to JSON: %s has unsupported kind %s", t, path, kind)
+	}
+	return nil
+}
+
+func findUnsupportedKind(t reflect.Type, path string, visited map[reflect.Type]bool) (string, reflect.Kind, bool) {
+	if visited[t] {
+		return "", reflect.Invalid, false
+	}
+	visited[t] = true
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) || t.Implements(readerType) {
+		return "", reflect.Invalid, false
+	}
+	if t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface {
+		// methods with pointer receivers are still used by encoding/json when the value is addressable
+		if ptr := reflect.PtrTo(t); ptr.Implements(jsonMarshalerType) || ptr.Implements(textMarshalerType) {
+			return "", reflect.Invalid, false
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return path, t.Kind(), true
+	case reflect.Ptr:
+		return findUnsupportedKind(t.Elem(), path, visited)
+	case reflect.Slice, reflect.Array:
+		return findUnsupportedKind(t.Elem(), path+"[]", visited)
+	case reflect.Map:
+		switch key := t.Key(); {
+		case key.Kind() == reflect.String, key.Implements(textMarshalerType):
+		case key.Kind() >= reflect.Int && key.Kind() <= reflect.Uintptr:
+		default:
+			return strings.TrimPrefix(path+" key", " "), key.Kind(), true
+		}
+		return findUnsupportedKind(t.Elem(), path+"[]", visited)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			if name := strings.Split(field.Tag.Get("json"), ",")[0]; name == "-" {
+				continue
+			}
+			if fieldPath, kind, ok := findUnsupportedKind(field.Type, strings.TrimPrefix(path+"."+field.Name, "."), visited); ok {
+				return fieldPath, kind, true
+			}
+		}
+	}
+	return "", reflect.Invalid, false
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// SSEWriter writes Server-Sent Events to a streaming response. See SSEStream.
+type SSEWriter struct {
+	w   ResponseWriter
+	buf bytes.Buffer
+}
+
+// SSEStream prepares w for sending Server-Sent Events, by setting the Content-Type header to text/event-stream,
+// and disabling caching. It must be called before anything is written to w.
+//
+// Usage:
+//
+//	lambda.Start(func(ctx context.Context, w lambda.ResponseWriter) error {
+//		stream := lambda.SSEStream(w)
+//		for i := 0; i < 3; i++ {
+//			if err := stream.SendEvent("tick", strconv.Itoa(i)); err != nil {
+//				return err
+//			}
+//			time.Sleep(time.Second)
+//		}
+//		return nil
+//	})
+func SSEStream(w ResponseWriter) *SSEWriter {
+	w.SetHeader("Content-Type", "text/event-stream")
+	w.SetHeader("Cache-Control", "no-cache")
+	return &SSEWriter{w: w}
+}
+
+// SendEvent sends one event, and flushes it to the caller.
+// The "event:" line is omitted when event is empty, so that clients dispatch it as a message event.
+// Each line of data is sent on its own "data:" line, and the event is terminated by a blank line.
+// An error is returned when event contains a line break.
+func (s *SSEWriter) SendEvent(event, data string) error {
+	if strings.ContainsAny(event, "\r\n") {
+		return fmt.Errorf("event name %q must not contain line breaks", event)
+	}
+	s.buf.Reset()
+	if event != "" {
+		s.buf.WriteString("event: ")
+		s.buf.WriteString(event)
+		s.buf.WriteByte('\n')
+	}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		s.buf.WriteString("data: ")
+		s.buf.WriteString(line)
+		s.buf.WriteByte('\n')
+	}
+	s.buf.WriteByte('\n')
+	if _, err := s.w.Write(s.buf.Bytes()); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSEStream(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	handler := NewHandler(func(ctx context.Context, w ResponseWriter) error {
+		stream := SSEStream(w)
+		if err := stream.SendEvent("greeting", "hello"); err != nil {
+			return err
+		}
+		if err := stream.SendEvent("", "line one\nline two\r\nline three"); err != nil {
+			return err
+		}
+		return stream.SendEvent("done", "")
+	})
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	require.Equal(t, 1, record.nPosts)
+
+	prelude, body, found := splitPrelude(string(record.responses[0]))
+	require.True(t, found)
+	assert.JSONEq(t, `{"statusCode":200,"headers":{"Content-Type":"text/event-stream","Cache-Control":"no-cache"}}`, prelude)
+	assert.Equal(t, "event: greeting\ndata: hello\n\n"+
+		"data: line one\ndata: line two\ndata: line three\n\n"+
+		"event: done\ndata: \n\n", body)
+}
+
+func TestSSEStreamInvalidEventName(t *testing.T) {
+	ts, record := runtimeAPIServer(``, 1)
+	defer ts.Close()
+	var sendErr error
+	handler := NewHandler(func(ctx context.Context, w ResponseWriter) error {
+		sendErr = SSEStream(w).SendEvent("bad\nname", "data")
+		return nil
+	})
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+	assert.EqualError(t, sendErr, `event name "bad\nname" must not contain line breaks`)
+	assert.Equal(t, `{"statusCode":200,"headers":{"Cache-Control":"no-cache","Content-Type":"text/event-stream"}}`+streamingPreludeSeparator, string(record.responses[0]))
+}
@@ -0,0 +1,369 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// streamingEncodeBufferSize is the size of the buffer between the encoder and the pipe, so that small values still make a single write
+const streamingEncodeBufferSize = 32 * 1024
+
+// streamingEncodeMaxLevel is the number of nested values past which values are encoded whole, so that json.Encoder reports cycles
+const streamingEncodeMaxLevel = 100
+
+// jsonPipeReader is the response of a handler using WithStreamingEncode, read as the JSON encoder writes it
+type jsonPipeReader struct {
+	*io.PipeReader
+	contentType string
+	done        <-chan struct{} // done is closed once the encoding goroutine has returned
+}
+
+func (r *jsonPipeReader) ContentType() string {
+	return r.contentType
+}
+
+// streamJSON encodes v in a new goroutine, writing the JSON to the returned reader as it is read, rather than to a buffer.
+// It waits for the first write, so that encoding errors found before anything is written are returned instead of read.
+// Closing the reader stops the goroutine.
+func streamJSON(v interface{}, h *handlerOptions, contentType string) (io.Reader, error) {
+	r, w := io.Pipe()
+	started := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		out := &firstWriteWriter{w: w, started: started}
+		buffered := bufio.NewWriterSize(out, streamingEncodeBufferSize)
+		encoder := &streamingEncoder{
+			w:          buffered,
+			escapeHTML: h.jsonResponseEscapeHTML,
+			prefix:     h.jsonResponseIndentPrefix,
+			indent:     h.jsonResponseIndentValue,
+		}
+		err := encoder.encode(reflect.ValueOf(v), 0)
+		// back-compat, the encoder's trailing newline is only kept when WithSetIndent was used
+		if err == nil && encoder.indenting() {
+			_, err = buffered.WriteString("\n")
+		}
+		if err == nil {
+			err = buffered.Flush()
+		}
+		if !out.wrote {
+			started <- err
+		}
+		_ = w.CloseWithError(err)
+	}()
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	return &jsonPipeReader{r, contentType, done}, nil
+}
+
+// streamingEncoder writes the elements of slices and arrays, and the fields of structs holding them, one at a time,
+// so that only the value being written is held in memory. Other values are encoded whole with json.Encoder.
+type streamingEncoder struct {
+	w          io.Writer
+	escapeHTML bool
+	prefix     string
+	indent     string
+	level      int
+	buf        bytes.Buffer
+	encoder    *json.Encoder
+	streamable map[reflect.Type]bool
+	fields     map[reflect.Type][]streamableField
+}
+
+type streamableField struct {
+	index     int
+	key       []byte // key is the encoded name of the field, followed by a colon
+	omitEmpty bool
+}
+
+func (e *streamingEncoder) indenting() bool {
+	return e.prefix != "" || e.indent != ""
+}
+
+// encode writes v, indented for an array or object nested depth times
+func (e *streamingEncoder) encode(v reflect.Value, depth int) error {
+	if !v.IsValid() {
+		_, err := io.WriteString(e.w, "null")
+		return err
+	}
+	e.level++
+	defer func() { e.level-- }()
+	if e.level > streamingEncodeMaxLevel || !e.isStreamable(v.Type()) || hasJSONMarshaler(v) {
+		return e.encodeWhole(v, depth)
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			_, err := io.WriteString(e.w, "null")
+			return err
+		}
+		return e.encode(v.Elem(), depth)
+	case reflect.Slice:
+		if v.IsNil() {
+			_, err := io.WriteString(e.w, "null")
+			return err
+		}
+		return e.encodeArray(v, depth)
+	case reflect.Array:
+		return e.encodeArray(v, depth)
+	case reflect.Struct:
+		return e.encodeStruct(v, depth)
+	}
+	return e.encodeWhole(v, depth)
+}
+
+func (e *streamingEncoder) encodeArray(v reflect.Value, depth int) error {
+	if v.Len() == 0 {
+		_, err := io.WriteString(e.w, "[]")
+		return err
+	}
+	if _, err := io.WriteString(e.w, "["); err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := e.writeSeparator(i, depth); err != nil {
+			return err
+		}
+		if err := e.encode(v.Index(i), depth+1); err != nil {
+			return err
+		}
+	}
+	return e.writeClose("]", depth)
+}
+
+func (e *streamingEncoder) encodeStruct(v reflect.Value, depth int) error {
+	written := 0
+	for _, field := range e.fields[v.Type()] {
+		value := v.Field(field.index)
+		if field.omitEmpty && isEmptyJSONValue(value) {
+			continue
+		}
+		if written == 0 {
+			if _, err := io.WriteString(e.w, "{"); err != nil {
+				return err
+			}
+		}
+		if err := e.writeSeparator(written, depth); err != nil {
+			return err
+		}
+		written++
+		if _, err := e.w.Write(field.key); err != nil {
+			return err
+		}
+		if err := e.encode(value, depth+1); err != nil {
+			return err
+		}
+	}
+	if written == 0 {
+		_, err := io.WriteString(e.w, "{}")
+		return err
+	}
+	return e.writeClose("}", depth)
+}
+
+// writeSeparator writes what comes before the i-th element of an array or object, as json.Indent would
+func (e *streamingEncoder) writeSeparator(i int, depth int) error {
+	separator := ""
+	if i > 0 {
+		separator = ","
+	}
+	if e.indenting() {
+		separator += "\n" + e.prefix + strings.Repeat(e.indent, depth+1)
+	}
+	_, err := io.WriteString(e.w, separator)
+	return err
+}
+
+func (e *streamingEncoder) writeClose(closing string, depth int) error {
+	if e.indenting() {
+		closing = "\n" + e.prefix + strings.Repeat(e.indent, depth) + closing
+	}
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
+
+func (e *streamingEncoder) encodeWhole(v reflect.Value, depth int) error {
+	value := v.Interface()
+	// as with json.Marshal, the pointer receiver methods of addressable values are used
+	if v.CanAddr() {
+		value = v.Addr().Interface()
+	}
+	b, err := e.marshal(value, depth)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(b)
+	return err
+}
+
+// marshal encodes value as json.Encoder would when nested depth times, without the trailing newline.
+// The returned slice is only valid until the next call.
+func (e *streamingEncoder) marshal(value interface{}, depth int) ([]byte, error) {
+	if e.encoder == nil {
+		e.encoder = json.NewEncoder(&e.buf)
+		e.encoder.SetEscapeHTML(e.escapeHTML)
+	}
+	if e.indenting() {
+		e.encoder.SetIndent(e.prefix+strings.Repeat(e.indent, depth), e.indent)
+	}
+	e.buf.Reset()
+	if err := e.encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(e.buf.Bytes(), []byte("\n")), nil
+}
+
+// isStreamable reports whether values of t hold slices or arrays that are worth writing an element at a time,
+// caching the fields of the structs that do
+func (e *streamingEncoder) isStreamable(t reflect.Type) bool {
+	if streamable, ok := e.streamable[t]; ok {
+		return streamable
+	}
+	if e.streamable == nil {
+		e.streamable = map[reflect.Type]bool{}
+		e.fields = map[reflect.Type][]streamableField{}
+	}
+	// recursive types are streamed, and bounded by streamingEncodeMaxLevel
+	e.streamable[t] = true
+	streamable := false
+	switch t.Kind() {
+	case reflect.Slice:
+		// byte slices are encoded as base64 strings
+		streamable = t.Elem().Kind() != reflect.Uint8
+	case reflect.Array:
+		streamable = t.Len() > 0
+	case reflect.Interface:
+		streamable = true
+	case reflect.Ptr:
+		streamable = e.isStreamable(t.Elem())
+	case reflect.Struct:
+		if fields, ok := e.streamableFields(t); ok {
+			for _, field := range fields {
+				if e.isStreamable(t.Field(field.index).Type) {
+					streamable = true
+				}
+			}
+			e.fields[t] = fields
+		}
+	}
+	e.streamable[t] = streamable
+	return streamable
+}
+
+// streamableFields returns the fields encoding/json writes for t, in order,
+// or false if t has embedded fields, ",string" options or duplicate names, which are left to json.Encoder
+func (e *streamingEncoder) streamableFields(t reflect.Type) ([]streamableField, bool) {
+	var fields []streamableField
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			return nil, false
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		options := strings.Split(tag, ",")
+		name := options[0]
+		if !isValidJSONTag(name) {
+			name = field.Name
+		}
+		omitEmpty := false
+		for _, option := range options[1:] {
+			switch option {
+			case "omitempty":
+				omitEmpty = true
+			case "string":
+				return nil, false
+			}
+		}
+		if names[name] {
+			return nil, false
+		}
+		names[name] = true
+		key, err := e.marshal(name, 0)
+		if err != nil {
+			return nil, false
+		}
+		key = append(append([]byte(nil), key...), ':')
+		if e.indenting() {
+			key = append(key, ' ')
+		}
+		fields = append(fields, streamableField{index: i, key: key, omitEmpty: omitEmpty})
+	}
+	return fields, true
+}
+
+func hasJSONMarshaler(v reflect.Value) bool {
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return true
+	}
+	if v.CanAddr() {
+		t = reflect.PtrTo(t)
+		return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+	}
+	return false
+}
+
+// isValidJSONTag reports whether s is used by encoding/json as a field name
+func isValidJSONTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
+// isEmptyJSONValue reports whether v is left out of the JSON of a field with the omitempty option
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// firstWriteWriter signals started before the first write to w, as writes to a pipe block until they are read
+type firstWriteWriter struct {
+	w       io.Writer
+	started chan<- error
+	wrote   bool
+}
+
+func (f *firstWriteWriter) Write(p []byte) (int, error) {
+	if !f.wrote {
+		f.wrote = true
+		f.started <- nil
+	}
+	return f.w.Write(p)
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil" //nolint: staticcheck
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type largeReport struct {
+	Rows []largeReportRow `json:"rows"`
+}
+
+type largeReportRow struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Notes string `json:"notes"`
+}
+
+func newLargeReport(rows int) *largeReport {
+	report := &largeReport{Rows: make([]largeReportRow, rows)}
+	for i := range report.Rows {
+		report.Rows[i] = largeReportRow{ID: i, Name: "row", Notes: strings.Repeat("x", 100)}
+	}
+	return report
+}
+
+func TestStreamingEncodeLoop(t *testing.T) {
+	ts, record := runtimeAPIServer(`{}`, 2)
+	defer ts.Close()
+	report := newLargeReport(1000)
+	handler := NewHandlerWithOptions(func() (*largeReport, error) {
+		return report, nil
+	}, WithStreamingEncode())
+	_ = startRuntimeAPILoop(serverAddress(ts), handler)
+
+	require.Equal(t, 2, record.nPosts)
+	expected, err := json.Marshal(report)
+	require.NoError(t, err)
+	for i := range record.responses {
+		assert.Equal(t, contentTypeJSON, record.contentTypes[i])
+		assert.Equal(t, string(expected), string(record.responses[i]))
+	}
+}
+
+func TestStreamingEncodeTaggedContentType(t *testing.T) {
+	type page struct {
+		ContentType string `json:"-" lambda:"contentType"`
+		Title       string `json:"title"`
+	}
+	handler := newHandler(func() (page, error) {
+		return page{ContentType: "application/hal+json", Title: "home"}, nil
+	}, WithStreamingEncode())
+	response, err := handler.handlerFunc(context.Background(), []byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, "application/hal+json", response.(interface{ ContentType() string }).ContentType())
+	body, err := ioutil.ReadAll(response)
+	require.NoError(t, err)
+	assert.Equal(t, `{"title":"home"}`, string(body))
+}
+
+func TestStreamingEncodeCloseStopsEncoding(t *testing.T) {
+	handler := newHandler(func() (*largeReport, error) {
+		return newLargeReport(100000), nil
+	}, WithStreamingEncode())
+	response, err := handler.handlerFunc(context.Background(), []byte(`{}`))
+	require.NoError(t, err)
+	_, err = response.Read(make([]byte, 16))
+	require.NoError(t, err)
+	require.NoError(t, response.(io.Closer).Close())
+	select {
+	case <-response.(*jsonPipeReader).done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the encoding goroutine did not return after the response was closed")
+	}
+}
+
+type streamingEncodeValues struct {
+	Name      string `json:"name"`
+	Skipped   string `json:"-"`
+	Dash      string `json:"-,"`
+	Omitted   []int  `json:"omitted,omitempty"`
+	Untagged  int
+	HTML      string                 `json:"<html>"`
+	Bytes     []byte                 `json:"bytes"`
+	Nested    [][]int                `json:"nested"`
+	Empty     []string               `json:"empty"`
+	Nil       []string               `json:"nil"`
+	Map       map[string]interface{} `json:"map"`
+	Pointer   *largeReportRow        `json:"pointer"`
+	Marshaler pointerMarshaler       `json:"marshaler"`
+	Time      time.Time              `json:"time"`
+	Any       interface{}            `json:"any"`
+	Array     [2]largeReportRow      `json:"array"`
+	WithEmbed struct {
+		largeReportRow
+		Extra string `json:"extra"`
+	} `json:"withEmbed"`
+	unexported string
+}
+
+func TestStreamingEncodeMatchesEncoder(t *testing.T) {
+	values := []interface{}{
+		&streamingEncodeValues{
+			Name:      "<gopher>",
+			Dash:      "dash",
+			HTML:      "&",
+			Bytes:     []byte("bytes"),
+			Nested:    [][]int{{1, 2}, {}, nil},
+			Empty:     []string{},
+			Map:       map[string]interface{}{"b": []int{1}, "a": nil},
+			Pointer:   &largeReportRow{ID: 1},
+			Marshaler: pointerMarshaler{Name: "gopher"},
+			Time:      time.Date(2023, 5, 4, 3, 2, 1, 0, time.UTC),
+			Any:       []interface{}{"a", 1.5, true, nil, map[string]int{"n": 1}},
+		},
+		[]streamingEncodeValues{{}, {Omitted: []int{1}}},
+		newLargeReport(3),
+		[]int{},
+		struct{}{},
+		"plain",
+		nil,
+	}
+	for _, indent := range [][2]string{{"", ""}, {"", "  "}, {"> ", "\t"}} {
+		for _, escapeHTML := range []bool{false, true} {
+			h := &handlerOptions{jsonResponseEscapeHTML: escapeHTML, jsonResponseIndentPrefix: indent[0], jsonResponseIndentValue: indent[1]}
+			for _, value := range values {
+				var expected bytes.Buffer
+				encoder := json.NewEncoder(&expected)
+				encoder.SetEscapeHTML(escapeHTML)
+				encoder.SetIndent(indent[0], indent[1])
+				require.NoError(t, encoder.Encode(value))
+				if indent == [2]string{"", ""} {
+					expected.Truncate(expected.Len() - 1)
+				}
+
+				response, err := streamJSON(value, h, contentTypeJSON)
+				require.NoError(t, err)
+				body, err := ioutil.ReadAll(response)
+				require.NoError(t, err)
+				assert.Equal(t, expected.String(), string(body))
+			}
+		}
+	}
+}
+
+func TestStreamingEncodeErrorAfterFirstWrite(t *testing.T) {
+	rows := make([]interface{}, streamingEncodeBufferSize)
+	for i := range rows {
+		rows[i] = "row"
+	}
+	rows = append(rows, make(chan int))
+	response, err := streamJSON(rows, &handlerOptions{}, contentTypeJSON)
+	require.NoError(t, err, "the error is found after the first write, so it can only be read")
+	_, err = ioutil.ReadAll(response)
+	assert.EqualError(t, err, "json: unsupported type: chan int")
+}
+
+// BenchmarkLargeResponse compares the memory allocated to respond with a large struct, by the first invoke of a handler.
+func BenchmarkLargeResponse(b *testing.B) {
+	report := newLargeReport(50000)
+	for _, benchmark := range []struct {
+		name    string
+		options []Option
+	}{
+		{"buffered", nil},
+		{"streaming", []Option{WithStreamingEncode()}},
+	} {
+		b.Run(benchmark.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				handler := newHandler(func() (*largeReport, error) {
+					return report, nil
+				}, benchmark.options...)
+				response, err := handler.handlerFunc(context.Background(), []byte(`{}`))
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := io.Copy(ioutil.Discard, response); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambda
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// withStreamingPrelude wraps a response implementing StatusCode() int, Headers() http.Header, ContentEncoding() string, or any of them,
+// so that it is streamed with the prelude carrying the status and headers expected for Function URL responses.
+// When the response has a content type of its own, other than the default, it is sent as the Content-Type header unless the headers have one,
+// and a non-empty content encoding is sent as the Content-Encoding header in the same way.
+// The second return value is false, and the response is returned unchanged, when it implements none of the methods, or returns an empty content encoding.
+func withStreamingPrelude(response io.Reader, contentType string) (io.Reader, bool) {
+	type StatusCode interface{ StatusCode() int }
+	type Headers interface{ Headers() http.Header }
+	type ContentEncoding interface{ ContentEncoding() string }
+	withStatus, hasStatus := response.(StatusCode)
+	withHeaders, hasHeaders := response.(Headers)
+	var contentEncoding string
+	if withEncoding, ok := response.(ContentEncoding); ok {
+		contentEncoding = withEncoding.ContentEncoding()
+	}
+	if !hasStatus && !hasHeaders && contentEncoding == "" {
+		return response, false
+	}
+	streaming := &events.LambdaFunctionURLStreamingResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{},
+		Body:       response,
+	}
+	if hasStatus {
+		streaming.StatusCode = withStatus.StatusCode()
+	}
+	if hasHeaders {
+		for key, values := range withHeaders.Headers() {
+			if strings.EqualFold(key, "Set-Cookie") {
+				streaming.Cookies = append(streaming.Cookies, values...)
+				continue
+			}
+			streaming.Headers[key] = strings.Join(values, ", ")
+		}
+	}
+	if _, ok := streaming.Headers["Content-Type"]; !ok && contentType != contentTypeBytes {
+		streaming.Headers["Content-Type"] = contentType
+	}
+	if _, ok := streaming.Headers["Content-Encoding"]; !ok && contentEncoding != "" {
+		streaming.Headers["Content-Encoding"] = contentEncoding
+	}
+	return streaming, true
+}
@@ -0,0 +1,42 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+type parsedARN struct {
+	arn       string
+	region    string
+	accountID string
+}
+
+// lastParsedARN caches the most recently parsed InvokedFunctionArn, which rarely changes within an execution environment.
+var lastParsedARN atomic.Value
+
+// AccountID returns the AWS account ID from InvokedFunctionArn, or an empty string if the ARN is malformed.
+func (lc *LambdaContext) AccountID() string {
+	return parseFunctionARN(lc.InvokedFunctionArn).accountID
+}
+
+// Region returns the AWS region from InvokedFunctionArn, or an empty string if the ARN is malformed.
+func (lc *LambdaContext) Region() string {
+	return parseFunctionARN(lc.InvokedFunctionArn).region
+}
+
+// parseFunctionARN splits a function ARN of the form arn:partition:lambda:region:account-id:function:name[:qualifier].
+func parseFunctionARN(arn string) parsedARN {
+	if cached, ok := lastParsedARN.Load().(parsedARN); ok && cached.arn == arn {
+		return cached
+	}
+	parsed := parsedARN{arn: arn}
+	parts := strings.SplitN(arn, ":", 7)
+	if len(parts) == 7 && parts[0] == "arn" && parts[5] == "function" {
+		parsed.region = parts[3]
+		parsed.accountID = parts[4]
+	}
+	lastParsedARN.Store(parsed)
+	return parsed
+}
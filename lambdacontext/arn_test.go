@@ -0,0 +1,35 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountIDAndRegion(t *testing.T) {
+	testCases := []struct {
+		name      string
+		arn       string
+		region    string
+		accountID string
+	}{
+		{"function", "arn:aws:lambda:us-west-2:123456789012:function:my-function", "us-west-2", "123456789012"},
+		{"alias qualified", "arn:aws:lambda:eu-central-1:210987654321:function:my-function:live", "eu-central-1", "210987654321"},
+		{"other partition", "arn:aws-cn:lambda:cn-north-1:123456789012:function:my-function:7", "cn-north-1", "123456789012"},
+		{"empty", "", "", ""},
+		{"too few segments", "arn:aws:lambda:us-west-2:123456789012", "", ""},
+		{"not an arn", "dummyarn", "", ""},
+		{"not a function", "arn:aws:lambda:us-west-2:123456789012:layer:my-layer", "", ""},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			lc := &LambdaContext{InvokedFunctionArn: testCase.arn}
+			assert.Equal(t, testCase.region, lc.Region())
+			assert.Equal(t, testCase.accountID, lc.AccountID())
+			// the second call is served from the cache
+			assert.Equal(t, testCase.accountID, lc.AccountID())
+		})
+	}
+}
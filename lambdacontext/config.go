@@ -0,0 +1,27 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import "context"
+
+type configKey struct{}
+
+// NewConfigContext returns a new Context that carries config, such as the struct populated from the environment by lambda.WithEnvConfig.
+func NewConfigContext(parent context.Context, config interface{}) context.Context {
+	return context.WithValue(parent, configKey{}, config)
+}
+
+// Config returns the config stored in ctx by NewConfigContext, if any.
+// It is the pointer passed to lambda.WithEnvConfig, and can be asserted back to its type.
+//
+// Usage:
+//
+//	cfg, ok := lambdacontext.Config(ctx)
+//	if !ok {
+//		return errors.New("no config")
+//	}
+//	table := cfg.(*Config).TableName
+func Config(ctx context.Context) (interface{}, bool) {
+	config := ctx.Value(configKey{})
+	return config, config != nil
+}
@@ -0,0 +1,23 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig(t *testing.T) {
+	type appConfig struct {
+		TableName string
+	}
+	_, ok := Config(context.Background())
+	assert.False(t, ok)
+
+	cfg := &appConfig{TableName: "orders"}
+	config, ok := Config(NewConfigContext(context.Background(), cfg))
+	assert.True(t, ok)
+	assert.Same(t, cfg, config)
+}
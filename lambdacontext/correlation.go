@@ -0,0 +1,78 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"net/http"
+)
+
+// DefaultCorrelationHeader is the header CorrelationRoundTripper sends the correlation id in, when none was set with lambda.WithCorrelationHeader.
+const DefaultCorrelationHeader = "X-Correlation-Id"
+
+type correlationKey struct{}
+
+type correlation struct {
+	header string
+	id     string
+}
+
+// NewCorrelationIDContext returns a new Context that carries the correlation id read from the request header named header.
+// The lambda package calls it for every invoke when lambda.WithCorrelationHeader is used, so users of this package should only need it in tests.
+func NewCorrelationIDContext(parent context.Context, header, id string) context.Context {
+	return context.WithValue(parent, correlationKey{}, correlation{header: header, id: id})
+}
+
+// CorrelationID returns the id correlating the invoke with the requests that caused it, for tracing without X-Ray.
+// It is read from the request header set with lambda.WithCorrelationHeader, falling back to the AwsRequestID of the invoke.
+// It returns the empty string when ctx carries neither.
+func CorrelationID(ctx context.Context) string {
+	if c, ok := ctx.Value(correlationKey{}).(correlation); ok && c.id != "" {
+		return c.id
+	}
+	if lc, ok := FromContext(ctx); ok {
+		return lc.AwsRequestID
+	}
+	return ""
+}
+
+// correlationHeader returns the name of the header the correlation id of ctx was read from
+func correlationHeader(ctx context.Context) string {
+	if c, ok := ctx.Value(correlationKey{}).(correlation); ok && c.header != "" {
+		return c.header
+	}
+	return DefaultCorrelationHeader
+}
+
+type correlationRoundTripper struct {
+	base http.RoundTripper
+}
+
+// CorrelationRoundTripper returns an http.RoundTripper setting the correlation id of each request's context on the request,
+// in the header it was read from, so that downstream services can continue the correlation.
+// Requests already carrying the header, or whose context has no correlation id, are sent unchanged. A nil base uses http.DefaultTransport.
+//
+// Usage:
+//
+//	client := &http.Client{Transport: lambdacontext.CorrelationRoundTripper(nil)}
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com", nil)
+//	resp, err := client.Do(req)
+func CorrelationRoundTripper(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &correlationRoundTripper{base: base}
+}
+
+func (t *correlationRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	header := correlationHeader(ctx)
+	id := CorrelationID(ctx)
+	if id == "" || req.Header.Get(header) != "" {
+		return t.base.RoundTrip(req)
+	}
+	// a RoundTripper must not modify the request it was given
+	req = req.Clone(ctx)
+	req.Header.Set(header, id)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,41 @@
+//go:build go1.21
+// +build go1.21
+
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"log/slog"
+)
+
+type correlationLogHandler struct {
+	slog.Handler
+}
+
+// CorrelationLogHandler wraps h to add a "correlationId" attribute, holding the CorrelationID of the context, to each record logged with a context.
+//
+// Usage:
+//
+//	slog.SetDefault(slog.New(lambdacontext.CorrelationLogHandler(slog.NewJSONHandler(os.Stdout, nil))))
+//	slog.InfoContext(ctx, "order created")
+func CorrelationLogHandler(h slog.Handler) slog.Handler {
+	return &correlationLogHandler{h}
+}
+
+func (h *correlationLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := CorrelationID(ctx); id != "" {
+		record = record.Clone()
+		record.AddAttrs(slog.String("correlationId", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *correlationLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &correlationLogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *correlationLogHandler) WithGroup(name string) slog.Handler {
+	return &correlationLogHandler{h.Handler.WithGroup(name)}
+}
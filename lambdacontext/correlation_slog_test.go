@@ -0,0 +1,36 @@
+//go:build go1.21
+// +build go1.21
+
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrelationLogHandler(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(CorrelationLogHandler(slog.NewJSONHandler(&logs, nil))).With("service", "orders")
+	ctx := NewCorrelationIDContext(NewContext(context.Background(), &LambdaContext{AwsRequestID: "abc-123"}), "X-Request-Id", "from-header")
+
+	logger.InfoContext(ctx, "order created")
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &record))
+	assert.Equal(t, "order created", record["msg"])
+	assert.Equal(t, "orders", record["service"])
+	assert.Equal(t, "from-header", record["correlationId"])
+
+	logs.Reset()
+	logger.Info("no context")
+	record = nil
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &record))
+	assert.NotContains(t, record, "correlationId")
+}
@@ -0,0 +1,54 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrelationID(t *testing.T) {
+	assert.Equal(t, "", CorrelationID(context.Background()))
+
+	ctx := NewContext(context.Background(), &LambdaContext{AwsRequestID: "abc-123"})
+	assert.Equal(t, "abc-123", CorrelationID(ctx))
+	assert.Equal(t, "abc-123", CorrelationID(NewCorrelationIDContext(ctx, "X-Request-Id", "")))
+	assert.Equal(t, "from-header", CorrelationID(NewCorrelationIDContext(ctx, "X-Request-Id", "from-header")))
+}
+
+func TestCorrelationRoundTripper(t *testing.T) {
+	var received []http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Clone())
+	}))
+	defer ts.Close()
+	client := &http.Client{Transport: CorrelationRoundTripper(nil)}
+	send := func(ctx context.Context, header http.Header) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, header, req.Header, "the request must not be modified")
+	}
+
+	lc := NewContext(context.Background(), &LambdaContext{AwsRequestID: "abc-123"})
+	send(NewCorrelationIDContext(lc, "X-Request-Id", "from-header"), http.Header{})
+	send(lc, http.Header{})
+	send(NewCorrelationIDContext(lc, "X-Request-Id", "from-header"), http.Header{"X-Request-Id": {"already-set"}})
+	send(context.Background(), http.Header{})
+
+	require.Len(t, received, 4)
+	assert.Equal(t, "from-header", received[0].Get("X-Request-Id"))
+	assert.Equal(t, "abc-123", received[1].Get(DefaultCorrelationHeader))
+	assert.Equal(t, "already-set", received[2].Get("X-Request-Id"))
+	assert.Empty(t, received[3].Get(DefaultCorrelationHeader))
+}
@@ -0,0 +1,36 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// grpc-timeout values are limited to 8 digits
+const maxGRPCTimeoutValue = 99999999
+
+// GRPCTimeout returns the time remaining until the deadline of ctx in the format of the grpc-timeout metadata header,
+// in milliseconds, such as "4500m". Remaining times too large for milliseconds are returned in seconds.
+// It returns an empty string when ctx has no deadline, and "1n" when the deadline has already passed,
+// so that downstream calls fail immediately.
+//
+// Usage:
+//
+//	ctx = metadata.AppendToOutgoingContext(ctx, "grpc-timeout", lambdacontext.GRPCTimeout(ctx))
+func GRPCTimeout(ctx context.Context) string {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ""
+	}
+	remaining := time.Until(deadline)
+	ms := int64(remaining / time.Millisecond)
+	switch {
+	case ms < 1:
+		return "1n"
+	case ms > maxGRPCTimeoutValue:
+		return strconv.FormatInt(int64(remaining/time.Second), 10) + "S"
+	}
+	return strconv.FormatInt(ms, 10) + "m"
+}
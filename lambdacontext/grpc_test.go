@@ -0,0 +1,37 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGRPCTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 4500*time.Millisecond)
+	defer cancel()
+	timeout := GRPCTimeout(ctx)
+	require.True(t, strings.HasSuffix(timeout, "m"), timeout)
+	ms, err := strconv.Atoi(strings.TrimSuffix(timeout, "m"))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, ms, 4500)
+	assert.Greater(t, ms, 4000)
+}
+
+func TestGRPCTimeoutEdgeCases(t *testing.T) {
+	assert.Equal(t, "", GRPCTimeout(context.Background()))
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	assert.Equal(t, "1n", GRPCTimeout(expired))
+
+	long, cancel := context.WithTimeout(context.Background(), 48*time.Hour)
+	defer cancel()
+	assert.Regexp(t, `^1727(99|98)S$`, GRPCTimeout(long))
+}
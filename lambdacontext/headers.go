@@ -0,0 +1,34 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"net/http"
+)
+
+type headersKey struct{}
+
+// NewHeadersContext returns a new Context that carries the headers of the Runtime API's next invocation response.
+// The lambda package calls it for every invoke, so users of this package should only need it in tests.
+func NewHeadersContext(parent context.Context, headers http.Header) context.Context {
+	return context.WithValue(parent, headersKey{}, headers)
+}
+
+// HeadersFromContext returns the headers the Runtime API sent with the invoke, such as Lambda-Runtime-Invoked-Function-Arn,
+// including any the LambdaContext does not model.
+// The headers are copied, so changes made by the caller are not seen by other readers.
+//
+// Example:
+//
+//	headers, ok := lambdacontext.HeadersFromContext(ctx)
+//	if ok {
+//		log.Printf("deadline: %s", headers.Get("Lambda-Runtime-Deadline-Ms"))
+//	}
+func HeadersFromContext(ctx context.Context) (http.Header, bool) {
+	headers, ok := ctx.Value(headersKey{}).(http.Header)
+	if !ok {
+		return nil, false
+	}
+	return headers.Clone(), true
+}
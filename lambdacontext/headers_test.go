@@ -0,0 +1,35 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadersFromContext(t *testing.T) {
+	headers := http.Header{"Lambda-Runtime-Aws-Request-Id": {"abc-123"}}
+	ctx := NewHeadersContext(context.Background(), headers)
+
+	read, ok := HeadersFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "abc-123", read.Get("Lambda-Runtime-Aws-Request-Id"))
+
+	read.Set("Lambda-Runtime-Aws-Request-Id", "changed")
+	read.Add("X-Extra", "1")
+	again, _ := HeadersFromContext(ctx)
+	assert.Equal(t, http.Header{"Lambda-Runtime-Aws-Request-Id": {"abc-123"}}, again)
+}
+
+func TestHeadersFromContextMissing(t *testing.T) {
+	headers, ok := HeadersFromContext(context.Background())
+	assert.False(t, ok)
+	assert.Nil(t, headers)
+
+	_, ok = HeadersFromContext(NewContext(context.Background(), &LambdaContext{}))
+	assert.False(t, ok)
+}
@@ -0,0 +1,28 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// DefaultHTTPClientTimeout is the timeout of clients returned by HTTPClient for contexts without a deadline.
+const DefaultHTTPClientTimeout = 30 * time.Second
+
+// HTTPClient returns an *http.Client whose timeout is the time remaining until the deadline of ctx,
+// so that requests made with it do not outlive the invoke.
+// When ctx has no deadline, the client's timeout is DefaultHTTPClientTimeout.
+// The client uses http.DefaultTransport, so connections are shared with other clients.
+func HTTPClient(ctx context.Context) *http.Client {
+	timeout := DefaultHTTPClientTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+		// a zero timeout means no timeout, so requests made after the deadline must still time out immediately
+		if timeout <= 0 {
+			timeout = time.Nanosecond
+		}
+	}
+	return &http.Client{Timeout: timeout}
+}
@@ -0,0 +1,42 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClientTimeoutTracksDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := HTTPClient(ctx)
+	assert.LessOrEqual(t, client.Timeout, 5*time.Second)
+	assert.Greater(t, client.Timeout, 4*time.Second)
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.LessOrEqual(t, HTTPClient(ctx).Timeout, time.Second)
+}
+
+func TestHTTPClientNoDeadline(t *testing.T) {
+	assert.Equal(t, DefaultHTTPClientTimeout, HTTPClient(context.Background()).Timeout)
+}
+
+func TestHTTPClientExpiredDeadline(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	client := HTTPClient(ctx)
+	require.Greater(t, client.Timeout, time.Duration(0))
+	_, err := client.Get(ts.URL) // nolint:noctx
+	assert.Error(t, err)
+}
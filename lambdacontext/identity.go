@@ -0,0 +1,31 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import "context"
+
+// PoolID returns the id of the Cognito identity pool of the caller, or "" when the invoke carries no Cognito identity.
+func (ci CognitoIdentity) PoolID() string {
+	return ci.CognitoIdentityPoolID
+}
+
+// IsAnonymous reports whether the caller has no Cognito identity id, as for invokes not made with Cognito credentials.
+func (ci CognitoIdentity) IsAnonymous() bool {
+	return ci.CognitoIdentityID == ""
+}
+
+// IdentityFromContext returns the Cognito identity of the invoke in ctx.
+// The second return value is false when ctx carries no LambdaContext, or the invoke carries no Cognito identity.
+//
+// Usage:
+//
+//	if identity, ok := lambdacontext.IdentityFromContext(ctx); !ok || identity.IsAnonymous() {
+//		return nil, errors.New("unauthenticated")
+//	}
+func IdentityFromContext(ctx context.Context) (CognitoIdentity, bool) {
+	lc, ok := FromContext(ctx)
+	if !ok || lc.Identity == (CognitoIdentity{}) {
+		return CognitoIdentity{}, false
+	}
+	return lc.Identity, true
+}
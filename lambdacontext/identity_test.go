@@ -0,0 +1,51 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentityFromContext(t *testing.T) {
+	testCases := []struct {
+		name      string
+		identity  CognitoIdentity
+		found     bool
+		poolID    string
+		anonymous bool
+	}{
+		{
+			name:     "authenticated",
+			identity: CognitoIdentity{CognitoIdentityID: "us-east-1:1234", CognitoIdentityPoolID: "us-east-1:pool"},
+			found:    true,
+			poolID:   "us-east-1:pool",
+		},
+		{
+			name:      "anonymous",
+			identity:  CognitoIdentity{CognitoIdentityPoolID: "us-east-1:pool"},
+			found:     true,
+			poolID:    "us-east-1:pool",
+			anonymous: true,
+		},
+		{
+			name:      "missing",
+			identity:  CognitoIdentity{},
+			anonymous: true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx := NewContext(context.Background(), &LambdaContext{AwsRequestID: "abc-123", Identity: testCase.identity})
+			identity, found := IdentityFromContext(ctx)
+			assert.Equal(t, testCase.found, found)
+			assert.Equal(t, testCase.poolID, identity.PoolID())
+			assert.Equal(t, testCase.anonymous, identity.IsAnonymous())
+		})
+	}
+
+	_, found := IdentityFromContext(context.Background())
+	assert.False(t, found)
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"os"
+	"sync"
+)
+
+// Initialization types reported by InitializationType.
+const (
+	InitializationTypeOnDemand               = "on-demand"
+	InitializationTypeProvisionedConcurrency = "provisioned-concurrency"
+	InitializationTypeSnapStart              = "snap-start"
+)
+
+var (
+	initializationTypeOnce sync.Once
+	initializationType     string
+)
+
+// InitializationType returns how the execution environment was initialized, read once from the AWS_LAMBDA_INITIALIZATION_TYPE environment variable.
+// It is InitializationTypeOnDemand when the variable is not set, such as when running outside of Lambda.
+//
+// Usage:
+//
+//	if lambdacontext.InitializationType() != lambdacontext.InitializationTypeProvisionedConcurrency {
+//		warmUpCaches()
+//	}
+func InitializationType() string {
+	initializationTypeOnce.Do(func() {
+		initializationType = os.Getenv("AWS_LAMBDA_INITIALIZATION_TYPE")
+		if initializationType == "" {
+			initializationType = InitializationTypeOnDemand
+		}
+	})
+	return initializationType
+}
@@ -0,0 +1,33 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func resetInitializationType() {
+	initializationTypeOnce = sync.Once{}
+}
+
+func TestInitializationType(t *testing.T) {
+	resetInitializationType()
+	defer resetInitializationType()
+	os.Setenv("AWS_LAMBDA_INITIALIZATION_TYPE", "provisioned-concurrency")
+	defer os.Unsetenv("AWS_LAMBDA_INITIALIZATION_TYPE")
+	assert.Equal(t, InitializationTypeProvisionedConcurrency, InitializationType())
+
+	os.Setenv("AWS_LAMBDA_INITIALIZATION_TYPE", "on-demand")
+	assert.Equal(t, InitializationTypeProvisionedConcurrency, InitializationType(), "the variable is only read once")
+}
+
+func TestInitializationTypeUnset(t *testing.T) {
+	resetInitializationType()
+	defer resetInitializationType()
+	os.Unsetenv("AWS_LAMBDA_INITIALIZATION_TYPE")
+	assert.Equal(t, InitializationTypeOnDemand, InitializationType())
+}
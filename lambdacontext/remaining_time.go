@@ -0,0 +1,28 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"time"
+)
+
+// RemainingTime returns the time left until the deadline of the invoke, and 0 once the deadline has passed.
+// The lambda package sets the deadline with a monotonic clock reading, so changes to the wall clock during the invoke do not skew it.
+// It also returns 0 when ctx has no deadline, as outside of an invoke.
+//
+// Usage:
+//
+//	if lambdacontext.RemainingTime(ctx) < 5*time.Second {
+//		return enqueueForLater(ctx, event)
+//	}
+func RemainingTime(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	if remaining := time.Until(deadline); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
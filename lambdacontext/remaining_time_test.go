@@ -0,0 +1,28 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemainingTime(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	remaining := RemainingTime(ctx)
+	assert.True(t, remaining > 59*time.Second && remaining <= time.Minute, remaining)
+}
+
+func TestRemainingTimePastDeadline(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	assert.Equal(t, time.Duration(0), RemainingTime(ctx))
+}
+
+func TestRemainingTimeWithoutDeadline(t *testing.T) {
+	assert.Equal(t, time.Duration(0), RemainingTime(context.Background()))
+}
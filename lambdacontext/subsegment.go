@@ -0,0 +1,117 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDaemonAddress = "127.0.0.1:2000"
+	daemonHeader         = `{"format": "json", "version": 1}` + "\n"
+)
+
+type subsegment struct {
+	Name      string             `json:"name"`
+	ID        string             `json:"id"`
+	TraceID   string             `json:"trace_id"`
+	ParentID  string             `json:"parent_id,omitempty"`
+	Type      string             `json:"type"`
+	StartTime float64            `json:"start_time"`
+	EndTime   float64            `json:"end_time"`
+	Fault     bool               `json:"fault,omitempty"`
+	Cause     *subsegmentFailure `json:"cause,omitempty"`
+}
+
+type subsegmentFailure struct {
+	Exceptions []subsegmentException `json:"exceptions"`
+}
+
+type subsegmentException struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// NewSubsegment starts an X-Ray subsegment named name under the invoke's segment, or under the subsegment already started in ctx.
+// The returned context carries a trace header whose Parent is the new subsegment, so that subsegments started from it,
+// and requests traced with InjectTraceIntoRequest, are nested below it.
+// Calling the returned function ends the subsegment and sends it to the X-Ray daemon over UDP, marking it as a fault when err is not nil.
+// Only the first call sends the subsegment. When the trace is not sampled, ctx is returned unchanged and nothing is sent.
+//
+// Usage:
+//
+//	for _, id := range ids {
+//		go func(id string) {
+//			ctx, end := lambdacontext.NewSubsegment(ctx, "fetch")
+//			err := fetch(ctx, id)
+//			end(err)
+//		}(id)
+//	}
+func NewSubsegment(ctx context.Context, name string) (context.Context, func(err error)) {
+	trace, ok := TraceFromContext(ctx)
+	if !ok || !trace.Sampled {
+		return ctx, func(error) {}
+	}
+	segment := subsegment{
+		Name:      name,
+		ID:        newSpanID(),
+		TraceID:   trace.Root,
+		ParentID:  trace.Parent,
+		Type:      "subsegment",
+		StartTime: epochSeconds(time.Now()),
+	}
+	trace.Parent = segment.ID
+	// nolint:staticcheck
+	ctx = context.WithValue(ctx, "x-amzn-trace-id", trace.String())
+
+	var once sync.Once
+	return ctx, func(err error) {
+		once.Do(func() {
+			segment.EndTime = epochSeconds(time.Now())
+			if err != nil {
+				segment.Fault = true
+				segment.Cause = &subsegmentFailure{Exceptions: []subsegmentException{{ID: newSpanID(), Message: err.Error()}}}
+			}
+			sendToDaemon(segment)
+		})
+	}
+}
+
+// sendToDaemon sends a segment document to the X-Ray daemon. Failures are ignored, as tracing must not fail the invoke.
+func sendToDaemon(segment subsegment) {
+	document, err := json.Marshal(segment)
+	if err != nil {
+		return
+	}
+	conn, err := net.Dial("udp", daemonAddress())
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	_, _ = conn.Write(append([]byte(daemonHeader), document...))
+}
+
+// daemonAddress returns the UDP address of the X-Ray daemon from AWS_XRAY_DAEMON_ADDRESS,
+// which is either host:port, or separate addresses like "tcp:127.0.0.1:2000 udp:127.0.0.1:2001"
+func daemonAddress() string {
+	address := os.Getenv("AWS_XRAY_DAEMON_ADDRESS")
+	if address == "" {
+		return defaultDaemonAddress
+	}
+	for _, part := range strings.Fields(address) {
+		if strings.HasPrefix(part, "udp:") {
+			return strings.TrimPrefix(part, "udp:")
+		}
+	}
+	return address
+}
+
+func epochSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
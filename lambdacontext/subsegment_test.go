@@ -0,0 +1,104 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listenDaemon(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	os.Setenv("AWS_XRAY_DAEMON_ADDRESS", conn.LocalAddr().String())
+	return conn
+}
+
+func readSubsegment(t *testing.T, conn net.PacketConn) subsegment {
+	buf := make([]byte, 64*1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	parts := strings.SplitN(string(buf[:n]), "\n", 2)
+	require.Len(t, parts, 2)
+	assert.Equal(t, `{"format": "json", "version": 1}`, parts[0])
+	var segment subsegment
+	require.NoError(t, json.Unmarshal([]byte(parts[1]), &segment))
+	return segment
+}
+
+func TestNewSubsegmentNesting(t *testing.T) {
+	os.Setenv("_X_AMZN_TRACE_ID", "")
+	defer os.Unsetenv("_X_AMZN_TRACE_ID")
+	daemon := listenDaemon(t)
+	defer os.Unsetenv("AWS_XRAY_DAEMON_ADDRESS")
+	defer daemon.Close()
+	ctx := traceContext("Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+
+	outerCtx, endOuter := NewSubsegment(ctx, "outer")
+	innerCtx, endInner := NewSubsegment(outerCtx, "inner")
+	endInner(errors.New("table not found"))
+	endInner(nil)
+	endOuter(nil)
+
+	inner := readSubsegment(t, daemon)
+	outer := readSubsegment(t, daemon)
+
+	assert.Equal(t, "outer", outer.Name)
+	assert.Equal(t, "subsegment", outer.Type)
+	assert.Equal(t, "1-5759e988-bd862e3fe1be46a994272793", outer.TraceID)
+	assert.Equal(t, "53995c3f42cd8ad8", outer.ParentID)
+	assert.Regexp(t, "^[0-9a-f]{16}$", outer.ID)
+	assert.False(t, outer.Fault)
+	assert.Nil(t, outer.Cause)
+	assert.LessOrEqual(t, outer.StartTime, outer.EndTime)
+
+	assert.Equal(t, "inner", inner.Name)
+	assert.Equal(t, outer.TraceID, inner.TraceID)
+	assert.Equal(t, outer.ID, inner.ParentID)
+	assert.True(t, inner.Fault)
+	require.NotNil(t, inner.Cause)
+	assert.Equal(t, "table not found", inner.Cause.Exceptions[0].Message)
+
+	assert.Equal(t, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent="+outer.ID+";Sampled=1", traceHeaderFromContext(outerCtx))
+	assert.Equal(t, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent="+inner.ID+";Sampled=1", traceHeaderFromContext(innerCtx))
+
+	require.NoError(t, daemon.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, _, err := daemon.ReadFrom(make([]byte, 1024))
+	assert.Error(t, err, "each subsegment is sent once")
+}
+
+func TestNewSubsegmentNotSampled(t *testing.T) {
+	os.Setenv("_X_AMZN_TRACE_ID", "")
+	defer os.Unsetenv("_X_AMZN_TRACE_ID")
+	daemon := listenDaemon(t)
+	defer os.Unsetenv("AWS_XRAY_DAEMON_ADDRESS")
+	defer daemon.Close()
+	ctx := traceContext("Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=0")
+
+	subsegmentCtx, end := NewSubsegment(ctx, "skipped")
+	end(nil)
+	assert.Equal(t, ctx, subsegmentCtx)
+
+	require.NoError(t, daemon.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, _, err := daemon.ReadFrom(make([]byte, 1024))
+	assert.Error(t, err)
+}
+
+func TestDaemonAddress(t *testing.T) {
+	os.Setenv("AWS_XRAY_DAEMON_ADDRESS", "")
+	defer os.Unsetenv("AWS_XRAY_DAEMON_ADDRESS")
+	assert.Equal(t, "127.0.0.1:2000", daemonAddress())
+	os.Setenv("AWS_XRAY_DAEMON_ADDRESS", "169.254.79.129:2000")
+	assert.Equal(t, "169.254.79.129:2000", daemonAddress())
+	os.Setenv("AWS_XRAY_DAEMON_ADDRESS", "tcp:127.0.0.1:2000 udp:127.0.0.2:2001")
+	assert.Equal(t, "127.0.0.2:2001", daemonAddress())
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const traceHeaderName = "X-Amzn-Trace-Id"
+
+// TraceHeader is the X-Ray trace header of an invoke, such as Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1
+type TraceHeader struct {
+	Root    string // Root is the trace id
+	Parent  string // Parent is the id of the parent segment, and is empty when the header has none
+	Sampled bool   // Sampled is true when the header has Sampled=1
+}
+
+// String formats the trace header as it is sent in the X-Amzn-Trace-Id header.
+func (h TraceHeader) String() string {
+	header := "Root=" + h.Root
+	if h.Parent != "" {
+		header += ";Parent=" + h.Parent
+	}
+	if h.Sampled {
+		return header + ";Sampled=1"
+	}
+	return header + ";Sampled=0"
+}
+
+// TraceFromContext returns the X-Ray trace header of the invoke, falling back to the _X_AMZN_TRACE_ID environment variable.
+// The second return value is false when there is no trace header, or when it has no Root.
+func TraceFromContext(ctx context.Context) (TraceHeader, bool) {
+	fields := parseTraceHeader(traceHeaderFromContext(ctx))
+	header := TraceHeader{
+		Root:    fields["Root"],
+		Parent:  fields["Parent"],
+		Sampled: fields["Sampled"] == "1",
+	}
+	return header, header.Root != ""
+}
+
+// InjectTraceIntoRequest sets the X-Amzn-Trace-Id header of req to continue the invoke's X-Ray trace, when the trace is sampled.
+// The header keeps the trace's Root, and sets Parent to a freshly generated span id.
+// It returns false, leaving req unchanged, when ctx carries no trace or the trace is not sampled.
+//
+// Usage:
+//
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com", nil)
+//	lambdacontext.InjectTraceIntoRequest(ctx, req)
+//	resp, err := http.DefaultClient.Do(req)
+func InjectTraceIntoRequest(ctx context.Context, req *http.Request) bool {
+	trace, ok := TraceFromContext(ctx)
+	if !ok || !trace.Sampled {
+		return false
+	}
+	trace.Parent = newSpanID()
+	req.Header.Set(traceHeaderName, trace.String())
+	return true
+}
+
+// traceHeaderFromContext returns the trace header of the invoke, falling back to the _X_AMZN_TRACE_ID environment variable
+func traceHeaderFromContext(ctx context.Context) string {
+	// nolint:staticcheck
+	if header, ok := ctx.Value("x-amzn-trace-id").(string); ok && header != "" {
+		return header
+	}
+	return os.Getenv("_X_AMZN_TRACE_ID")
+}
+
+// parseTraceHeader splits a trace header like Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1 into its fields
+func parseTraceHeader(header string) map[string]string {
+	fields := map[string]string{}
+	for _, part := range strings.Split(header, ";") {
+		if field := strings.SplitN(strings.TrimSpace(part), "=", 2); len(field) == 2 {
+			fields[field[0]] = field[1]
+		}
+	}
+	return fields
+}
+
+// newSpanID returns a random 64-bit span id as 16 hex digits
+func newSpanID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
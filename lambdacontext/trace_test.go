@@ -0,0 +1,121 @@
+// Copyright 2023 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+
+package lambdacontext
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func traceContext(header string) context.Context {
+	// nolint:staticcheck
+	return context.WithValue(context.Background(), "x-amzn-trace-id", header)
+}
+
+func TestInjectTraceIntoRequest(t *testing.T) {
+	os.Setenv("_X_AMZN_TRACE_ID", "")
+	defer os.Unsetenv("_X_AMZN_TRACE_ID")
+	ctx := traceContext("Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+	req, err := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	require.NoError(t, err)
+
+	require.True(t, InjectTraceIntoRequest(ctx, req))
+	header := req.Header.Get("X-Amzn-Trace-Id")
+	assert.Regexp(t, regexp.MustCompile(`^Root=1-5759e988-bd862e3fe1be46a994272793;Parent=[0-9a-f]{16};Sampled=1$`), header)
+	assert.NotContains(t, header, "53995c3f42cd8ad8")
+
+	require.True(t, InjectTraceIntoRequest(ctx, req))
+	assert.NotEqual(t, header, req.Header.Get("X-Amzn-Trace-Id"), "each request gets a new span id")
+}
+
+func TestInjectTraceIntoRequestNotSampled(t *testing.T) {
+	os.Setenv("_X_AMZN_TRACE_ID", "")
+	defer os.Unsetenv("_X_AMZN_TRACE_ID")
+	for _, header := range []string{
+		"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=0",
+		"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8",
+		"Parent=53995c3f42cd8ad8;Sampled=1",
+		"",
+	} {
+		req, err := http.NewRequest(http.MethodGet, "https://example.com", nil)
+		require.NoError(t, err)
+		assert.False(t, InjectTraceIntoRequest(traceContext(header), req), header)
+		assert.Empty(t, req.Header.Get("X-Amzn-Trace-Id"))
+	}
+}
+
+func TestInjectTraceIntoRequestFromEnvironment(t *testing.T) {
+	os.Setenv("_X_AMZN_TRACE_ID", "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1")
+	defer os.Unsetenv("_X_AMZN_TRACE_ID")
+	req, err := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	require.NoError(t, err)
+	require.True(t, InjectTraceIntoRequest(context.Background(), req))
+	assert.Regexp(t, `^Root=1-5759e988-bd862e3fe1be46a994272793;Parent=[0-9a-f]{16};Sampled=1$`, req.Header.Get("X-Amzn-Trace-Id"))
+}
+
+func TestTraceFromContext(t *testing.T) {
+	os.Setenv("_X_AMZN_TRACE_ID", "")
+	defer os.Unsetenv("_X_AMZN_TRACE_ID")
+	testCases := []struct {
+		header   string
+		expected TraceHeader
+		ok       bool
+	}{
+		{
+			header:   "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+			expected: TraceHeader{Root: "1-5759e988-bd862e3fe1be46a994272793", Parent: "53995c3f42cd8ad8", Sampled: true},
+			ok:       true,
+		},
+		{
+			header:   "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=0",
+			expected: TraceHeader{Root: "1-5759e988-bd862e3fe1be46a994272793", Parent: "53995c3f42cd8ad8"},
+			ok:       true,
+		},
+		{
+			header:   "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1",
+			expected: TraceHeader{Root: "1-5759e988-bd862e3fe1be46a994272793", Sampled: true},
+			ok:       true,
+		},
+		{
+			header:   "Root=1-5759e988-bd862e3fe1be46a994272793; Parent=53995c3f42cd8ad8; Sampled=?; Lineage=a87bd80c:1",
+			expected: TraceHeader{Root: "1-5759e988-bd862e3fe1be46a994272793", Parent: "53995c3f42cd8ad8"},
+			ok:       true,
+		},
+		{
+			header:   "Parent=53995c3f42cd8ad8;Sampled=1",
+			expected: TraceHeader{Parent: "53995c3f42cd8ad8", Sampled: true},
+			ok:       false,
+		},
+		{
+			header: "",
+			ok:     false,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.header, func(t *testing.T) {
+			trace, ok := TraceFromContext(traceContext(testCase.header))
+			assert.Equal(t, testCase.ok, ok)
+			assert.Equal(t, testCase.expected, trace)
+		})
+	}
+}
+
+func TestTraceFromContextEnvironment(t *testing.T) {
+	os.Setenv("_X_AMZN_TRACE_ID", "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=0")
+	defer os.Unsetenv("_X_AMZN_TRACE_ID")
+	trace, ok := TraceFromContext(context.Background())
+	require.True(t, ok)
+	assert.Equal(t, TraceHeader{Root: "1-5759e988-bd862e3fe1be46a994272793"}, trace)
+}
+
+func TestTraceHeaderString(t *testing.T) {
+	assert.Equal(t, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+		TraceHeader{Root: "1-5759e988-bd862e3fe1be46a994272793", Parent: "53995c3f42cd8ad8", Sampled: true}.String())
+	assert.Equal(t, "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=0", TraceHeader{Root: "1-5759e988-bd862e3fe1be46a994272793"}.String())
+}